@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/impact"
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/policy"
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/scan"
+)
+
+// analyzeImpact compares two policy files over exported cluster objects. It returns the process exit
+// code: 0 when the new policy denies nothing the old one admits, 1 when it does, 2 on bad usage or
+// unreadable input.
+func analyzeImpact(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("impact", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	format := flags.String("format", "markdown", "output format: markdown or json")
+	namespace := flags.String("namespace", "default", "namespace of objects that do not set one")
+	groups := flags.String("groups", "", "comma separated check groups to run (default all)")
+	verbose := flags.Bool("v", false, "also print the log of the checks")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: dynpodsec impact [flags] <old-policy> <new-policy> [file|dir|- ...]")
+		fmt.Fprintln(stderr, "Evaluates exported objects (e.g. kubectl get -A -o yaml) against both policies; reads stdin without objects or for -.")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() < 2 {
+		flags.Usage()
+		return 2
+	}
+	if !*verbose {
+		log.SetOutput(io.Discard)
+		defer log.SetOutput(os.Stderr)
+	}
+	write, ok := impact.Writers[*format]
+	if !ok {
+		fmt.Fprintf(stderr, "unknown format %q\n", *format)
+		return 2
+	}
+
+	oldPath, newPath := flags.Arg(0), flags.Arg(1)
+	oldPolicies, err := policy.Load(oldPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", oldPath, err)
+		return 2
+	}
+	newPolicies, err := policy.Load(newPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", newPath, err)
+		return 2
+	}
+	var selected []string
+	if *groups != "" {
+		selected = strings.Split(*groups, ",")
+	}
+
+	paths := flags.Args()[2:]
+	if len(paths) == 0 {
+		paths = []string{"-"}
+	}
+	files, err := manifestFiles(paths)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	var objects []*scan.Object
+	for _, file := range files {
+		found, err := readManifests(file, stdin)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 2
+		}
+		objects = append(objects, found...)
+	}
+
+	report, err := impact.Analyze(context.Background(), objects, *namespace, oldPolicies, newPolicies, selected)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	report.OldPolicy, report.NewPolicy = oldPath, newPath
+	if err := write(stdout, report); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	if report.Summary.NewlyDenied > 0 {
+		return 1
+	}
+	return 0
+}
+
+func init() {
+	commands["impact"] = func(args []string) int { return analyzeImpact(args, os.Stdin, os.Stdout, os.Stderr) }
+}
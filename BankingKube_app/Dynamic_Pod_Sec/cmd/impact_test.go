@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestImpactExitCode(t *testing.T) {
+	shipped, err := os.ReadFile("../configs/security-policies.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	relaxed := filepath.Join(t.TempDir(), "relaxed.yaml")
+	if err := os.WriteFile(relaxed, bytes.Replace(shipped, []byte("readOnlyRootFilesystem: true"), []byte("readOnlyRootFilesystem: false"), 1), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	manifest := "../policytests/manifests/compliant-pod.yaml"
+
+	var stdout, stderr bytes.Buffer
+	if code := analyzeImpact([]string{"-groups", "context", "-format", "json", relaxed, "../configs/security-policies.yaml", manifest}, nil, &stdout, &stderr); code != 0 {
+		t.Errorf("relaxing to the shipped policy: exit code %d\n%s%s", code, stdout.String(), stderr.String())
+	}
+	if !strings.Contains(stdout.String(), `"newlyAllowed": 1`) {
+		t.Errorf("unexpected report:\n%s", stdout.String())
+	}
+
+	stdout.Reset()
+	if code := analyzeImpact([]string{"-groups", "context", "../configs/security-policies.yaml", relaxed, manifest}, nil, &stdout, &stderr); code != 1 {
+		t.Errorf("tightening: exit code %d, want 1\n%s", code, stdout.String())
+	}
+	if !strings.Contains(stdout.String(), "| Pod payments-api |") {
+		t.Errorf("newly denied pod missing from the report:\n%s", stdout.String())
+	}
+
+	if code := analyzeImpact([]string{relaxed}, nil, &stdout, &stderr); code != 2 {
+		t.Errorf("missing new policy: exit code %d, want 2", code)
+	}
+}
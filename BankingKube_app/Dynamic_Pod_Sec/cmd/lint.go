@@ -0,0 +1,111 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/checks"
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/policy"
+)
+
+// lint validates policy files the way the webhook loads them and additionally reports check
+// names that neither a registered check nor a custom rule has, which the webhook would silently
+// never apply.
+// It returns the process exit code: 0 when every file is valid, 1 otherwise, 2 on bad usage.
+func lint(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("lint", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: dynpodsec lint [policy-file ...]")
+		fmt.Fprintln(stderr, "Validates policy files; defaults to SECURITY_POLICIES_PATH or "+policy.DefaultPath+".")
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	paths := flags.Args()
+	if len(paths) == 0 {
+		paths = []string{policy.Path()}
+	}
+
+	known := make(map[string]bool)
+	for _, name := range checks.Default.Names() {
+		known[name] = true
+	}
+
+	exitCode := 0
+	for _, path := range paths {
+		problems := lintFile(path, known)
+		if len(problems) == 0 {
+			fmt.Fprintf(stdout, "%s: ok\n", path)
+			continue
+		}
+		exitCode = 1
+		for _, problem := range problems {
+			fmt.Fprintf(stdout, "%s: %s\n", path, problem)
+		}
+	}
+	return exitCode
+}
+
+// lintFile returns every problem found in one policy file
+func lintFile(path string, known map[string]bool) []string {
+	policies, err := policy.Load(path)
+	if err != nil {
+		return strings.Split(err.Error(), "\n")
+	}
+
+	var problems []string
+	rules := make(map[string]bool)
+	for _, rule := range policies.CustomRules {
+		rules[rule.Name] = true
+	}
+	for _, ref := range referencedChecks(policies) {
+		if !known[ref.name] && !rules[ref.name] {
+			problems = append(problems, fmt.Sprintf("%s: unknown check %q", ref.field, ref.name))
+		}
+	}
+	return problems
+}
+
+type checkRef struct {
+	field string
+	name  string
+}
+
+// referencedChecks lists every check name the policy file refers to, with where it does
+func referencedChecks(policies *policy.SecurityPolicies) []checkRef {
+	var refs []checkRef
+	for _, name := range sortedKeys(policies.Enforcement.Checks) {
+		refs = append(refs, checkRef{"enforcement.checks", name})
+	}
+	for _, name := range sortedKeys(policies.Execution.Checks) {
+		refs = append(refs, checkRef{"execution.checks", name})
+	}
+	for _, name := range sortedKeys(policies.Updates.Checks) {
+		refs = append(refs, checkRef{"updates.checks", name})
+	}
+	for i, exemption := range policies.Exemptions {
+		for _, name := range exemption.Checks {
+			refs = append(refs, checkRef{fmt.Sprintf("exemptions[%d].checks", i), name})
+		}
+	}
+	return refs
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func init() {
+	commands["lint"] = func(args []string) int { return lint(args, os.Stdout, os.Stderr) }
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	dir := t.TempDir()
+	bad := filepath.Join(dir, "bad.yaml")
+	content := "policies:\n  enforcement:\n    checks:\n      image_tag: warn\n  resourceLimits:\n    cpuLimits:\n      min: 2000m\n      max: 1000m\n"
+	if err := os.WriteFile(bad, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := lint([]string{"../configs/security-policies.yaml"}, &stdout, &stderr); code != 0 {
+		t.Fatalf("shipped policies: exit code %d\n%s", code, stdout.String())
+	}
+
+	stdout.Reset()
+	if code := lint([]string{bad}, &stdout, &stderr); code != 1 {
+		t.Errorf("exit code %d, want 1", code)
+	}
+	if want := "min 2000m is greater than max 1000m"; !strings.Contains(stdout.String(), want) {
+		t.Errorf("output does not report %q:\n%s", want, stdout.String())
+	}
+
+	// Unknown check names only show once the file is otherwise valid
+	if err := os.WriteFile(bad, []byte("policies:\n  enforcement:\n    checks:\n      image_tag: warn\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	stdout.Reset()
+	if code := lint([]string{bad}, &stdout, &stderr); code != 1 || !strings.Contains(stdout.String(), `enforcement.checks: unknown check "image_tag"`) {
+		t.Errorf("exit code %d, output:\n%s\nwant the unknown check reported", code, stdout.String())
+	}
+}
@@ -1,28 +1,160 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
 	"log"
 	"net/http"
+	"os"
+	"time"
 
 	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission"
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/checks"
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/rego_policies"
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/wasm_plugins"
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/policy"
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/policy/crd"
 	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/server"
+
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
 )
 
+// commands maps subcommand names to their entry points; each returns the process exit code.
+// Without a subcommand the binary runs the webhook server.
+var commands = map[string]func(args []string) int{}
+
 func main() {
+	if len(os.Args) > 1 {
+		if command, ok := commands[os.Args[1]]; ok {
+			os.Exit(command(os.Args[2:]))
+		}
+		if os.Args[1] != "serve" {
+			log.Fatalf("Unknown command %q", os.Args[1])
+		}
+	}
+	serve()
+}
+
+// watchPolicyResources serves the policies of the security policy resources, merged over the policy file
+func watchPolicyResources(ctx context.Context, store *policy.Store) error {
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		return err
+	}
+	client, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+
+	source := crd.NewSource(client, store.Load, 10*time.Minute)
+	if err := source.Start(ctx, 30*time.Second); err != nil {
+		return err
+	}
+	admission.UsePolicySource(source.Policies)
+	log.Println("Watching ClusterSecurityPolicy and SecurityPolicy resources")
+	return nil
+}
+
+// watchNamespaces makes the labels of namespaces available to custom rules
+func watchNamespaces(ctx context.Context) error {
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		return err
+	}
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+
+	factory := informers.NewSharedInformerFactory(client, 10*time.Minute)
+	lister := factory.Core().V1().Namespaces().Lister()
+	factory.Start(ctx.Done())
+
+	syncCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	for _, synced := range factory.WaitForCacheSync(syncCtx.Done()) {
+		if !synced {
+			return fmt.Errorf("namespaces did not sync")
+		}
+	}
+	admission.UseNamespaceLookup(func(name string) (map[string]string, error) {
+		namespace, err := lister.Get(name)
+		if err != nil {
+			return nil, err
+		}
+		return namespace.Labels, nil
+	})
+	return nil
+}
+
+// serve runs the webhook server
+func serve() {
 	log.Println("Starting Webhook Server...")
 
 	certFile := "/tls/tls.crt"
 	keyFile := "/tls/tls.key"
 
-	// Register the admission handlers
-	http.HandleFunc("/validate/context", admission.HandleAdmissionRequest)
-	http.HandleFunc("/validate/volumes", admission.HandleAdmissionRequest)
-	http.HandleFunc("/validate/network", admission.HandleAdmissionRequest)
-	http.HandleFunc("/validate/api", admission.HandleAdmissionRequest)
-	http.HandleFunc("/validate/image", admission.HandleAdmissionRequest)
-	http.HandleFunc("/validate/rbac", admission.HandleAdmissionRequest)
-	http.HandleFunc("/validate/resources", admission.HandleAdmissionRequest)
-	http.HandleFunc("/mutate/pod", admission.HandleAdmissionRequest)
+	// Refuse to start on an invalid policy file; a missing one is reported per request until it appears
+	store := policy.DefaultStore()
+	if err := store.Reload(); err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			log.Fatal(err)
+		}
+		log.Println(err)
+	}
+
+	// Likewise for the Rego bundle, when REGO_BUNDLE_PATH configures one
+	regoStore := rego_policies.DefaultStore()
+	if regoStore != nil {
+		if _, err := regoStore.Load(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			log.Fatal(err)
+		}
+	}
+
+	// And for the WebAssembly plugins, when WASM_PLUGIN_DIR configures them
+	if _, err := wasm_plugins.DefaultHost(); err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			log.Fatal(err)
+		}
+		log.Println(err)
+	}
+
+	// Register one validation route per check group (e.g. /validate/context)
+	for _, group := range checks.Groups() {
+		http.HandleFunc(admission.ValidatePathPrefix+group, admission.HandleAdmissionRequest)
+	}
+	http.HandleFunc(admission.MutatePodPath, admission.HandleAdmissionRequest)
+
+	// Reload the policies whenever the ConfigMap changes
+	go func() {
+		if err := store.Watch(context.Background()); err != nil {
+			log.Println("Policy hot reload disabled:", err)
+		}
+	}()
+	if regoStore != nil {
+		go func() {
+			if err := regoStore.Watch(context.Background()); err != nil {
+				log.Println("Rego hot reload disabled:", err)
+			}
+		}()
+	}
+
+	// Layer the ClusterSecurityPolicy and SecurityPolicy resources over the policy file
+	if os.Getenv("ENABLE_POLICY_RESOURCES") == "true" {
+		if err := watchPolicyResources(context.Background(), store); err != nil {
+			log.Fatal("Failed to watch security policy resources: ", err)
+		}
+	}
+
+	// Custom rules that read namespace labels fail with an internal error without them
+	if err := watchNamespaces(context.Background()); err != nil {
+		log.Println("Namespace labels are not available to custom rules:", err)
+	}
 
 	// Create and start the server
 	srv := server.NewServer(certFile, keyFile)
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/policytest"
+)
+
+// runPolicyTests runs the test cases of every directory through the webhook handlers and prints a table.
+// It returns the process exit code: 0 when every case passes, 1 when any fails, 2 on bad usage.
+func runPolicyTests(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("policytest", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: dynpodsec policytest dir ...")
+		fmt.Fprintln(stderr, "Runs every "+policytest.CaseSuffix+" test case under the directories against its policy file.")
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+
+	var results []*policytest.Result
+	for _, dir := range flags.Args() {
+		dirResults, err := policytest.RunDir(context.Background(), dir)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 2
+		}
+		results = append(results, dirResults...)
+	}
+	if policytest.PrintTable(stdout, results) > 0 {
+		return 1
+	}
+	return 0
+}
+
+func init() {
+	commands["policytest"] = func(args []string) int { return runPolicyTests(args, os.Stdout, os.Stderr) }
+}
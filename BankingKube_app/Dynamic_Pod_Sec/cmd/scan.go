@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/offline"
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/policy"
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/scan"
+)
+
+// scanManifests checks manifests against a policy file the way the webhook would on CREATE.
+// It returns the process exit code: 0 when every object would be admitted, 1 when any would be
+// denied, 2 on bad usage or unreadable input.
+func scanManifests(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("scan", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	policyPath := flags.String("policy", policy.Path(), "policy file to check against")
+	format := flags.String("format", "text", "output format: text, json, junit or sarif")
+	namespace := flags.String("namespace", "default", "namespace of objects that do not set one")
+	groups := flags.String("groups", "", "comma separated check groups to run (default all)")
+	verbose := flags.Bool("v", false, "also print the log of the checks")
+	fix := flags.Bool("fix", false, "write the defaults of the mutating webhook into the YAML files before checking them")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: dynpodsec scan [flags] [file|dir|- ...]")
+		fmt.Fprintln(stderr, "Checks YAML or JSON manifests, including multi-document streams; reads stdin without arguments or for -.")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if !*verbose {
+		log.SetOutput(io.Discard)
+		defer log.SetOutput(os.Stderr)
+	}
+	write, ok := scan.Writers[*format]
+	if !ok {
+		fmt.Fprintf(stderr, "unknown format %q\n", *format)
+		return 2
+	}
+
+	policies, err := policy.Load(*policyPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", *policyPath, err)
+		return 2
+	}
+	options := offline.Options{Policies: policies}
+	if *groups != "" {
+		options.Groups = strings.Split(*groups, ",")
+	}
+
+	paths := flags.Args()
+	if len(paths) == 0 {
+		paths = []string{"-"}
+	}
+	files, err := manifestFiles(paths)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	var objects []*scan.Object
+	for _, file := range files {
+		if *fix && file == "-" {
+			fmt.Fprintln(stderr, "-fix rewrites files and cannot read stdin")
+			return 2
+		}
+		if *fix && filepath.Ext(file) != ".json" {
+			if err := fixManifests(file, stderr); err != nil {
+				fmt.Fprintln(stderr, err)
+				return 2
+			}
+		}
+		found, err := readManifests(file, stdin)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 2
+		}
+		objects = append(objects, found...)
+	}
+
+	results, err := scan.Scan(context.Background(), objects, *namespace, options)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	if err := write(stdout, results); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	if scan.Denied(results) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// manifestFiles expands directories into the .yaml, .yml and .json files under them, sorted
+func manifestFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		if path == "-" {
+			files = append(files, path)
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		var found []string
+		err = filepath.WalkDir(path, func(file string, entry fs.DirEntry, err error) error {
+			if err != nil || entry.IsDir() {
+				return err
+			}
+			switch filepath.Ext(file) {
+			case ".yaml", ".yml", ".json":
+				found = append(found, file)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(found)
+		files = append(files, found...)
+	}
+	return files, nil
+}
+
+// readManifests reads the objects of a file, or of stdin for -
+func readManifests(file string, stdin io.Reader) ([]*scan.Object, error) {
+	if file == "-" {
+		return scan.Read(file, stdin)
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return scan.Read(file, f)
+}
+
+// fixManifests rewrites a file with the defaults of the mutating webhook when they change it
+func fixManifests(file string, stderr io.Writer) error {
+	info, err := os.Stat(file)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	fixed, objects, err := scan.Fix(file, bytes.NewReader(data))
+	if err != nil || fixed == nil {
+		return err
+	}
+	if err := os.WriteFile(file, fixed, info.Mode().Perm()); err != nil {
+		return err
+	}
+	fmt.Fprintf(stderr, "%s: fixed %d objects\n", file, objects)
+	return nil
+}
+
+func init() {
+	commands["scan"] = func(args []string) int { return scanManifests(args, os.Stdin, os.Stdout, os.Stderr) }
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestScanExitCode(t *testing.T) {
+	args := []string{"-policy", "../configs/security-policies.yaml", "-groups", "context"}
+	var stdout, stderr bytes.Buffer
+	if code := scanManifests(append(args, "../policytests/manifests/compliant-pod.yaml"), nil, &stdout, &stderr); code != 0 {
+		t.Errorf("compliant pod: exit code %d\n%s%s", code, stdout.String(), stderr.String())
+	}
+
+	stdout.Reset()
+	stdin := strings.NewReader("apiVersion: v1\nkind: Pod\nmetadata:\n  name: root\nspec:\n  containers:\n    - name: app\n      image: nginx\n")
+	if code := scanManifests(append(args, "-format", "json"), stdin, &stdout, &stderr); code != 1 {
+		t.Errorf("pod from stdin: exit code %d, want 1\n%s", code, stdout.String())
+	}
+	if !strings.Contains(stdout.String(), `"file": "-"`) {
+		t.Errorf("output does not name stdin:\n%s", stdout.String())
+	}
+
+	if code := scanManifests(append(args, "-format", "xml"), nil, &stdout, &stderr); code != 2 {
+		t.Errorf("unknown format: exit code %d, want 2", code)
+	}
+}
+
+func TestScanFix(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "pod.yaml")
+	manifest := "apiVersion: v1\nkind: Pod\nmetadata:\n  name: web # frontend\nspec:\n  containers:\n    - name: app\n      image: nginx\n"
+	if err := os.WriteFile(file, []byte(manifest), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"-policy", "../configs/security-policies.yaml", "-groups", "context", "-fix"}
+	if code := scanManifests(append(args, file), nil, &stdout, &stderr); code != 0 {
+		t.Errorf("exit code %d after fixing\n%s%s", code, stdout.String(), stderr.String())
+	}
+	fixed, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(fixed), "name: web # frontend") || !strings.Contains(string(fixed), "readOnlyRootFilesystem: true") {
+		t.Errorf("unexpected fixed manifest:\n%s", fixed)
+	}
+
+	if code := scanManifests(args, strings.NewReader(manifest), &stdout, &stderr); code != 2 {
+		t.Errorf("-fix on stdin: exit code %d, want 2", code)
+	}
+}
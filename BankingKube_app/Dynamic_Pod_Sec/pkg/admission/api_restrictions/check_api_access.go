@@ -2,19 +2,15 @@ package api_restrictions
 
 import (
 	"context"
-	"encoding/json"
+	"fmt"
 	"log"
-	"os"
 	"strings"
 
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/checks"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/metric"
 	"go.opentelemetry.io/otel/trace"
-
-	"gopkg.in/yaml.v2"
-	admissionv1 "k8s.io/api/admission/v1"
-	corev1 "k8s.io/api/core/v1"
 )
 
 var (
@@ -36,29 +32,16 @@ func init() {
 	}
 }
 
-// APIRestrictions defines a structure for restricted API paths
-type APIRestrictions struct {
-	RestrictedAPIPaths []string `yaml:"restrictedAPIPaths"`
-}
-
-// SecurityPolicies represents the structure of the security-policies.yaml file
-type SecurityPolicies struct {
-	Policies struct {
-		APIRestrictions APIRestrictions `yaml:"apiRestrictions"`
-	} `yaml:"policies"`
-}
-
 // CheckAPIAccess validates if a pod is trying to access restricted Kubernetes API paths
-func CheckAPIAccess(ctx context.Context, request *admissionv1.AdmissionRequest) bool {
+func CheckAPIAccess(ctx context.Context, eval *checks.Evaluation) []checks.Violation {
 	ctx, span := tracer.Start(ctx, "CheckAPIAccess", trace.WithAttributes(
-		attribute.String("operation", string(request.Operation)),
-		attribute.String("resource", request.Resource.Resource),
+		attribute.String("operation", string(eval.Request.Operation)),
+		attribute.String("resource", eval.Request.Resource.Resource),
 	))
 	defer span.End()
 
-	// Parse the Pod object from the request
-	pod := &corev1.Pod{}
-	err := json.Unmarshal(request.Object.Raw, pod)
+	// Extract the pod, or the pod template of a workload, from the request
+	template, err := eval.PodTemplate()
 	if err != nil {
 		log.Println("Failed to parse pod object:", err)
 		span.SetAttributes(
@@ -66,8 +49,9 @@ func CheckAPIAccess(ctx context.Context, request *admissionv1.AdmissionRequest)
 			attribute.String("result", "denied"),
 		)
 		span.RecordError(err)
-		return false // Fails the validation if the pod can't be parsed
+		return []checks.Violation{checks.ErrorViolation("failed_to_parse_pod", err)} // Fails the validation if the pod can't be parsed
 	}
+	pod := template.Pod
 
 	span.SetAttributes(
 		attribute.String("pod", pod.Name),
@@ -75,7 +59,7 @@ func CheckAPIAccess(ctx context.Context, request *admissionv1.AdmissionRequest)
 	)
 
 	// Retrieve the API restrictions
-	apiRestrictions, err := getAPIRestrictions()
+	policies, err := eval.Policies()
 	if err != nil {
 		log.Println("Failed to load API restrictions:", err)
 		span.SetAttributes(
@@ -83,11 +67,13 @@ func CheckAPIAccess(ctx context.Context, request *admissionv1.AdmissionRequest)
 			attribute.String("result", "denied"),
 		)
 		span.RecordError(err)
-		return false
+		return []checks.Violation{checks.ErrorViolation("failed_to_load_restrictions", err)}
 	}
+	apiRestrictions := &policies.APIRestrictions
 
 	// Check if the pod is trying to access restricted API paths
-	requestPath := "/" + request.Resource.Group + "/" + request.Resource.Version + "/" + request.Resource.Resource + "/" + request.Name
+	var violations []checks.Violation
+	requestPath := "/" + eval.Request.Resource.Group + "/" + eval.Request.Resource.Version + "/" + eval.Request.Resource.Resource + "/" + eval.Request.Name
 	for _, restrictedPath := range apiRestrictions.RestrictedAPIPaths {
 		// Convert wildcards to proper regex patterns for matching
 		pattern := strings.ReplaceAll(restrictedPath, "*", ".*")
@@ -102,16 +88,29 @@ func CheckAPIAccess(ctx context.Context, request *admissionv1.AdmissionRequest)
 				attribute.String("request_path", requestPath),
 			))
 
-			span.SetAttributes(
+			span.AddEvent("Restricted API path", trace.WithAttributes(
 				attribute.String("restricted_path", restrictedPath),
 				attribute.String("request_path", requestPath),
-				attribute.String("result", "denied"),
-			)
+			))
 
-			return false
+			violations = append(violations, checks.Violation{
+				Reason:   "restricted_api_path",
+				Value:    requestPath,
+				Expected: "a path outside " + restrictedPath,
+				Message:  fmt.Sprintf("request targets restricted API path %s", restrictedPath),
+			})
 		}
 	}
 
+	if len(violations) > 0 {
+		span.SetAttributes(
+			attribute.String("request_path", requestPath),
+			attribute.String("result", "denied"),
+			attribute.Int("violation_count", len(violations)),
+		)
+		return violations
+	}
+
 	// Request is allowed
 	apiAllowed.Add(ctx, 1, metric.WithAttributes(
 		attribute.String("pod", pod.Name),
@@ -120,26 +119,5 @@ func CheckAPIAccess(ctx context.Context, request *admissionv1.AdmissionRequest)
 	))
 
 	span.SetAttributes(attribute.String("result", "allowed"))
-	return true
-}
-
-// getAPIRestrictions loads the API restrictions from the configuration file
-func getAPIRestrictions() (*APIRestrictions, error) {
-	configPath := os.Getenv("SECURITY_POLICIES_PATH")
-	if configPath == "" {
-		configPath = "configs/security-policies.yaml" // Default path
-	}
-
-	data, err := os.ReadFile(configPath)
-	if err != nil {
-		return nil, err
-	}
-
-	var policies SecurityPolicies
-	err = yaml.Unmarshal(data, &policies)
-	if err != nil {
-		return nil, err
-	}
-
-	return &policies.Policies.APIRestrictions, nil
+	return nil
 }
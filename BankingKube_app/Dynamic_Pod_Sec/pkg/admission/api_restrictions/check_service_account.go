@@ -2,18 +2,15 @@ package api_restrictions
 
 import (
 	"context"
-	"encoding/json"
+	"fmt"
 	"log"
-	"os"
+	"strings"
 
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/checks"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/metric"
 	"go.opentelemetry.io/otel/trace"
-
-	"gopkg.in/yaml.v2"
-	admissionv1 "k8s.io/api/admission/v1"
-	corev1 "k8s.io/api/core/v1"
 )
 
 var (
@@ -35,29 +32,16 @@ func init() {
 	}
 }
 
-// ServiceAccountRestrictions defines a structure for restricted service accounts
-type ServiceAccountRestrictions struct {
-	RestrictedServiceAccounts []string `yaml:"restrictedServiceAccounts"`
-}
-
-// SecurityPoliciesForSA represents the structure of the security-policies.yaml file
-type SecurityPoliciesForSA struct {
-	Policies struct {
-		ServiceAccountRestrictions ServiceAccountRestrictions `yaml:"serviceAccountRestrictions"`
-	} `yaml:"policies"`
-}
-
 // CheckServiceAccount ensures that pods do not use restricted service accounts
-func CheckServiceAccount(ctx context.Context, request *admissionv1.AdmissionRequest) bool {
+func CheckServiceAccount(ctx context.Context, eval *checks.Evaluation) []checks.Violation {
 	ctx, span := saTracer.Start(ctx, "CheckServiceAccount", trace.WithAttributes(
-		attribute.String("operation", string(request.Operation)),
-		attribute.String("resource", request.Resource.Resource),
+		attribute.String("operation", string(eval.Request.Operation)),
+		attribute.String("resource", eval.Request.Resource.Resource),
 	))
 	defer span.End()
 
-	// Parse the Pod object from the request
-	pod := &corev1.Pod{}
-	err := json.Unmarshal(request.Object.Raw, pod)
+	// Extract the pod, or the pod template of a workload, from the request
+	template, err := eval.PodTemplate()
 	if err != nil {
 		log.Println("Failed to parse pod object:", err)
 		span.SetAttributes(
@@ -65,8 +49,9 @@ func CheckServiceAccount(ctx context.Context, request *admissionv1.AdmissionRequ
 			attribute.String("result", "denied"),
 		)
 		span.RecordError(err)
-		return false // Fails the validation if the pod can't be parsed
+		return []checks.Violation{checks.ErrorViolation("failed_to_parse_pod", err)} // Fails the validation if the pod can't be parsed
 	}
+	pod := template.Pod
 
 	span.SetAttributes(
 		attribute.String("pod", pod.Name),
@@ -75,7 +60,7 @@ func CheckServiceAccount(ctx context.Context, request *admissionv1.AdmissionRequ
 	)
 
 	// Retrieve the security policies for service account usage
-	serviceAccountRestrictions, err := getServiceAccountRestrictions()
+	policies, err := eval.Policies()
 	if err != nil {
 		log.Println("Failed to load service account restrictions:", err)
 		span.SetAttributes(
@@ -83,8 +68,9 @@ func CheckServiceAccount(ctx context.Context, request *admissionv1.AdmissionRequ
 			attribute.String("result", "denied"),
 		)
 		span.RecordError(err)
-		return false
+		return []checks.Violation{checks.ErrorViolation("failed_to_load_restrictions", err)}
 	}
+	serviceAccountRestrictions := &policies.ServiceAccountRestrictions
 
 	// Check if the pod is using a restricted service account
 	for _, restrictedAccount := range serviceAccountRestrictions.RestrictedServiceAccounts {
@@ -104,7 +90,13 @@ func CheckServiceAccount(ctx context.Context, request *admissionv1.AdmissionRequ
 				attribute.String("result", "denied"),
 			)
 
-			return false
+			return []checks.Violation{{
+				Reason:   "restricted_service_account",
+				Field:    template.SpecPath + ".serviceAccountName",
+				Value:    pod.Spec.ServiceAccountName,
+				Expected: "a service account other than " + strings.Join(serviceAccountRestrictions.RestrictedServiceAccounts, ", "),
+				Message:  fmt.Sprintf("pod uses restricted service account %q", restrictedAccount),
+			}}
 		}
 	}
 
@@ -116,26 +108,5 @@ func CheckServiceAccount(ctx context.Context, request *admissionv1.AdmissionRequ
 	))
 
 	span.SetAttributes(attribute.String("result", "allowed"))
-	return true
-}
-
-// getServiceAccountRestrictions loads the service account restrictions from the configuration file
-func getServiceAccountRestrictions() (*ServiceAccountRestrictions, error) {
-	configPath := os.Getenv("SECURITY_POLICIES_PATH")
-	if configPath == "" {
-		configPath = "configs/security-policies.yaml" // Default path
-	}
-
-	data, err := os.ReadFile(configPath)
-	if err != nil {
-		return nil, err
-	}
-
-	var policies SecurityPoliciesForSA
-	err = yaml.Unmarshal(data, &policies)
-	if err != nil {
-		return nil, err
-	}
-
-	return &policies.Policies.ServiceAccountRestrictions, nil
+	return nil
 }
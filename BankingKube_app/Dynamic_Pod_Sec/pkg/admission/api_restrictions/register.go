@@ -0,0 +1,14 @@
+package api_restrictions
+
+import (
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/checks"
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/workload"
+)
+
+// Group is the check group served on /validate/api
+const Group = "api"
+
+func init() {
+	checks.Register(Group, checks.NewCheck("api_access", workload.Kinds, CheckAPIAccess))
+	checks.Register(Group, checks.NewCheck("service_account", workload.Kinds, CheckServiceAccount))
+}
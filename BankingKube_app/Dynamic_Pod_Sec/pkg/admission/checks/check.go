@@ -0,0 +1,134 @@
+package checks
+
+import (
+	"context"
+
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/policy"
+)
+
+// Check is a single admission rule that the webhook dispatches through a Registry
+type Check interface {
+	// Name returns the unique identifier of the check (e.g. "capabilities")
+	Name() string
+	// Kinds returns the resource kinds the check applies to; an empty list matches every kind
+	Kinds() []string
+	// Validate evaluates the admission request and reports the outcome
+	Validate(ctx context.Context, eval *Evaluation) Result
+}
+
+// ErrorPolicyDeclarer is implemented by checks that declare how their internal errors are handled.
+// Checks that do not implement it defer to the global default error policy.
+type ErrorPolicyDeclarer interface {
+	ErrorPolicy() policy.ErrorPolicy
+}
+
+// DeclaredErrorPolicy returns the error policy a check declares
+func DeclaredErrorPolicy(check Check) policy.ErrorPolicy {
+	if d, ok := check.(ErrorPolicyDeclarer); ok {
+		return d.ErrorPolicy()
+	}
+	return policy.ErrorPolicyDefault
+}
+
+// ModeDeclarer is implemented by checks that declare their own enforcement mode, such as custom rules.
+// The enforcement section of the policy file can still override it.
+type ModeDeclarer interface {
+	Mode() policy.Mode
+}
+
+// DeclaredMode returns the enforcement mode a check declares, or "" when it defers to the policy file
+func DeclaredMode(check Check) policy.Mode {
+	if d, ok := check.(ModeDeclarer); ok {
+		return d.Mode()
+	}
+	return ""
+}
+
+// Result is the structured outcome of a single check
+type Result struct {
+	Violations []Violation
+	// Errors are the internal errors that kept the check from being evaluated (e.g. an unreadable policy file)
+	Errors []Violation
+}
+
+// Allowed reports whether the check passed
+func (r Result) Allowed() bool {
+	return len(r.Violations) == 0 && len(r.Errors) == 0
+}
+
+// CheckFunc is the signature of the check functions exposed by the admission packages.
+// It returns every violation found in the request, or nil when the request complies.
+type CheckFunc func(ctx context.Context, eval *Evaluation) []Violation
+
+// WithoutContext adapts a check function that does not take a context into a CheckFunc
+func WithoutContext(fn func(eval *Evaluation) []Violation) CheckFunc {
+	return func(_ context.Context, eval *Evaluation) []Violation {
+		return fn(eval)
+	}
+}
+
+// funcCheck adapts a CheckFunc into a Check
+type funcCheck struct {
+	name        string
+	kinds       []string
+	fn          CheckFunc
+	errorPolicy policy.ErrorPolicy
+	mode        policy.Mode
+}
+
+// Option configures a check built by NewCheck
+type Option func(c *funcCheck)
+
+// OnError declares the error policy of the check; the policy file can still override it
+func OnError(p policy.ErrorPolicy) Option {
+	return func(c *funcCheck) { c.errorPolicy = p }
+}
+
+// InMode declares the enforcement mode of the check; the policy file can still override it
+func InMode(mode policy.Mode) Option {
+	return func(c *funcCheck) { c.mode = mode }
+}
+
+// NewCheck wraps a check function into a Check
+func NewCheck(name string, kinds []string, fn CheckFunc, opts ...Option) Check {
+	c := &funcCheck{name: name, kinds: kinds, fn: fn}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *funcCheck) Name() string                    { return c.name }
+func (c *funcCheck) Kinds() []string                 { return c.kinds }
+func (c *funcCheck) ErrorPolicy() policy.ErrorPolicy { return c.errorPolicy }
+func (c *funcCheck) Mode() policy.Mode               { return c.mode }
+
+// Validate runs the check function and separates internal errors from violations
+func (c *funcCheck) Validate(ctx context.Context, eval *Evaluation) Result {
+	var result Result
+	for _, v := range c.fn(ctx, eval) {
+		if v.CheckID == "" {
+			v.CheckID = c.name
+		}
+		if v.Internal {
+			result.Errors = append(result.Errors, v)
+		} else {
+			result.Violations = append(result.Violations, v)
+		}
+	}
+	return result
+}
+
+// AppliesTo reports whether the check handles objects of the given kind
+func AppliesTo(check Check, kind string) bool {
+	kinds := check.Kinds()
+	if len(kinds) == 0 {
+		return true
+	}
+	for _, k := range kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
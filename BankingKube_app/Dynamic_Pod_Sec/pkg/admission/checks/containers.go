@@ -0,0 +1,50 @@
+package checks
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Container types, matching the container_type attribute used in traces and metrics
+const (
+	ContainerTypeRegular   = "regular"
+	ContainerTypeInit      = "init"
+	ContainerTypeEphemeral = "ephemeral"
+)
+
+// ContainerRef points at one container of a pod spec together with its field path
+type ContainerRef struct {
+	Container *corev1.Container
+	Type      string
+	Path      string // e.g. spec.initContainers[1]
+}
+
+// PodContainers returns every regular, init and ephemeral container of a pod spec.
+// specPath is the field path of the spec inside the admitted object (e.g. "spec").
+func PodContainers(spec *corev1.PodSpec, specPath string) []ContainerRef {
+	refs := make([]ContainerRef, 0, len(spec.Containers)+len(spec.InitContainers)+len(spec.EphemeralContainers))
+	for i := range spec.Containers {
+		refs = append(refs, ContainerRef{
+			Container: &spec.Containers[i],
+			Type:      ContainerTypeRegular,
+			Path:      fmt.Sprintf("%s.containers[%d]", specPath, i),
+		})
+	}
+	for i := range spec.InitContainers {
+		refs = append(refs, ContainerRef{
+			Container: &spec.InitContainers[i],
+			Type:      ContainerTypeInit,
+			Path:      fmt.Sprintf("%s.initContainers[%d]", specPath, i),
+		})
+	}
+	for i := range spec.EphemeralContainers {
+		// EphemeralContainerCommon has the same layout as Container
+		refs = append(refs, ContainerRef{
+			Container: (*corev1.Container)(&spec.EphemeralContainers[i].EphemeralContainerCommon),
+			Type:      ContainerTypeEphemeral,
+			Path:      fmt.Sprintf("%s.ephemeralContainers[%d]", specPath, i),
+		})
+	}
+	return refs
+}
@@ -0,0 +1,143 @@
+package checks
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/workload"
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/policy"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+)
+
+// PolicyLoader returns the policies a request is evaluated against
+type PolicyLoader func() (*policy.SecurityPolicies, error)
+
+// NamespaceLookup returns the labels of a namespace
+type NamespaceLookup func(name string) (map[string]string, error)
+
+// Evaluation is the request-scoped state shared by every check run for one admission request.
+// The admitted object is decoded and the policies are loaded at most once, however many checks ask for them.
+type Evaluation struct {
+	Request *admissionv1.AdmissionRequest
+	// Namespaces looks up the labels of the request's namespace; nil when the webhook does not watch namespaces
+	Namespaces NamespaceLookup
+
+	loadPolicies PolicyLoader
+	policiesOnce sync.Once
+	policies     *policy.SecurityPolicies
+	policiesErr  error
+
+	templateOnce sync.Once
+	template     *workload.PodTemplate
+	templateErr  error
+
+	objectOnce sync.Once
+	object     interface{}
+	objectErr  error
+
+	changesOnce sync.Once
+	changes     []string
+	changesErr  error
+
+	unstructuredOnce sync.Once
+	unstructured     map[string]interface{}
+	oldUnstructured  map[string]interface{}
+	unstructuredErr  error
+
+	labelsOnce sync.Once
+	labels     map[string]string
+	labelsErr  error
+}
+
+// NewEvaluation creates the evaluation of a request against the policies returned by loadPolicies
+func NewEvaluation(request *admissionv1.AdmissionRequest, loadPolicies PolicyLoader) *Evaluation {
+	return &Evaluation{Request: request, loadPolicies: loadPolicies}
+}
+
+// NewEvaluationWithPolicies creates the evaluation of a request against an already loaded policy snapshot
+func NewEvaluationWithPolicies(request *admissionv1.AdmissionRequest, policies *policy.SecurityPolicies) *Evaluation {
+	return NewEvaluation(request, func() (*policy.SecurityPolicies, error) { return policies, nil })
+}
+
+// Policies returns the policy snapshot of the request
+func (e *Evaluation) Policies() (*policy.SecurityPolicies, error) {
+	e.policiesOnce.Do(func() {
+		if e.loadPolicies == nil {
+			e.policiesErr = fmt.Errorf("no policies configured")
+			return
+		}
+		e.policies, e.policiesErr = e.loadPolicies()
+	})
+	return e.policies, e.policiesErr
+}
+
+// PodTemplate returns the admitted pod, or the pod template of the admitted workload
+func (e *Evaluation) PodTemplate() (*workload.PodTemplate, error) {
+	e.templateOnce.Do(func() {
+		e.template, e.templateErr = workload.Extract(e.Request)
+	})
+	return e.template, e.templateErr
+}
+
+// Object returns the admitted RBAC object (Role, ClusterRole, RoleBinding or ClusterRoleBinding)
+// decoded into its typed form. Pods and workloads are read through PodTemplate.
+func (e *Evaluation) Object() (interface{}, error) {
+	e.objectOnce.Do(func() {
+		var object interface{}
+		switch e.Request.Kind.Kind {
+		case "Role":
+			object = &rbacv1.Role{}
+		case "ClusterRole":
+			object = &rbacv1.ClusterRole{}
+		case "RoleBinding":
+			object = &rbacv1.RoleBinding{}
+		case "ClusterRoleBinding":
+			object = &rbacv1.ClusterRoleBinding{}
+		default:
+			e.objectErr = fmt.Errorf("unsupported kind %q", e.Request.Kind.Kind)
+			return
+		}
+		if err := json.Unmarshal(e.Request.Object.Raw, object); err != nil {
+			e.objectErr = err
+			return
+		}
+		e.object = object
+	})
+	return e.object, e.objectErr
+}
+
+// Unstructured returns the admitted object and the object before an UPDATE as generic JSON values,
+// for checks written against the raw object rather than a typed one. oldObject is nil when the
+// request carries none.
+func (e *Evaluation) Unstructured() (object, oldObject map[string]interface{}, err error) {
+	e.unstructuredOnce.Do(func() {
+		if err := json.Unmarshal(e.Request.Object.Raw, &e.unstructured); err != nil {
+			e.unstructuredErr = fmt.Errorf("decoding object: %w", err)
+			return
+		}
+		if len(e.Request.OldObject.Raw) > 0 {
+			if err := json.Unmarshal(e.Request.OldObject.Raw, &e.oldUnstructured); err != nil {
+				e.unstructuredErr = fmt.Errorf("decoding oldObject: %w", err)
+			}
+		}
+	})
+	return e.unstructured, e.oldUnstructured, e.unstructuredErr
+}
+
+// NamespaceLabels returns the labels of the request's namespace. Cluster-scoped objects have none.
+func (e *Evaluation) NamespaceLabels() (map[string]string, error) {
+	e.labelsOnce.Do(func() {
+		switch {
+		case e.Request.Namespace == "":
+			e.labels = map[string]string{}
+		case e.Namespaces == nil:
+			e.labelsErr = fmt.Errorf("labels of namespace %s are not available: namespaces are not watched", e.Request.Namespace)
+		default:
+			e.labels, e.labelsErr = e.Namespaces(e.Request.Namespace)
+		}
+	})
+	return e.labels, e.labelsErr
+}
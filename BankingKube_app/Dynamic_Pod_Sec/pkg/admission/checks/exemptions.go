@@ -0,0 +1,99 @@
+package checks
+
+import (
+	"time"
+
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/policy"
+)
+
+// Exemption returns the exemption that lifts a violation of the request. An exemption that has
+// expired at now is only returned when no other exemption matches, so the caller can report it.
+func (e *Evaluation) Exemption(v Violation, exemptions []policy.Exemption, now time.Time) *policy.Exemption {
+	var expired *policy.Exemption
+	for i := range exemptions {
+		exemption := &exemptions[i]
+		if !exemption.Covers(v.CheckID) || !e.matches(exemption, v) {
+			continue
+		}
+		if !exemption.Expired(now) {
+			return exemption
+		}
+		if expired == nil {
+			expired = exemption
+		}
+	}
+	return expired
+}
+
+// matches reports whether every selector the exemption sets matches the request
+func (e *Evaluation) matches(exemption *policy.Exemption, v Violation) bool {
+	request := e.Request
+	if len(exemption.Namespaces) > 0 && !contains(exemption.Namespaces, request.Namespace) {
+		return false
+	}
+	if len(exemption.Users) > 0 && !contains(exemption.Users, request.UserInfo.Username) {
+		return false
+	}
+	if len(exemption.Groups) > 0 && !containsAny(exemption.Groups, request.UserInfo.Groups) {
+		return false
+	}
+	if len(exemption.Labels) == 0 && len(exemption.ServiceAccounts) == 0 && len(exemption.Images) == 0 {
+		return true
+	}
+
+	// The remaining selectors look at the pod, so they never match other kinds
+	template, err := e.PodTemplate()
+	if err != nil {
+		return false
+	}
+	pod := template.Pod
+	for key, value := range exemption.Labels {
+		if actual, ok := pod.Labels[key]; !ok || actual != value {
+			return false
+		}
+	}
+	if len(exemption.ServiceAccounts) > 0 {
+		serviceAccount := pod.Spec.ServiceAccountName
+		if serviceAccount == "" {
+			serviceAccount = "default"
+		}
+		if !contains(exemption.ServiceAccounts, serviceAccount) {
+			return false
+		}
+	}
+	if len(exemption.Images) > 0 {
+		// A container violation matches on the container's image, a pod violation when every image matches
+		matched := false
+		for _, ref := range PodContainers(&pod.Spec, template.SpecPath) {
+			if v.Container != "" && ref.Container.Name != v.Container {
+				continue
+			}
+			if !policy.MatchesAny(exemption.Images, ref.Container.Image) {
+				return false
+			}
+			matched = true
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAny(list []string, values []string) bool {
+	for _, value := range values {
+		if contains(list, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+package checks
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/policy"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestExemption(t *testing.T) {
+	const daemonSet = `{"metadata":{"name":"aws-node","namespace":"kube-system"},"spec":{"template":{"metadata":{"labels":{"k8s-app":"aws-node"}},` +
+		`"spec":{"serviceAccountName":"aws-node","hostNetwork":true,"containers":[` +
+		`{"name":"aws-node","image":"602401143452.dkr.ecr.eu-west-1.amazonaws.com/amazon-k8s-cni:v1.18.0"},` +
+		`{"name":"sidecar","image":"busybox:1.36"}]}}}}`
+	eval := NewEvaluation(&admissionv1.AdmissionRequest{
+		Kind:      metav1.GroupVersionKind{Group: "apps", Version: "v1", Kind: "DaemonSet"},
+		Namespace: "kube-system",
+		Operation: admissionv1.Create,
+		UserInfo:  authenticationv1.UserInfo{Username: "system:serviceaccount:kube-system:argocd", Groups: []string{"system:serviceaccounts", "platform-admins"}},
+		Object:    runtime.RawExtension{Raw: []byte(daemonSet)},
+	}, nil)
+
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	expires := policy.Date{Time: time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC)}
+	hostNetwork := Violation{CheckID: "host_network", Field: "spec.template.spec.hostNetwork"}
+	cniImage := Violation{CheckID: "image_tags", Container: "aws-node"}
+	sidecarImage := Violation{CheckID: "image_tags", Container: "sidecar"}
+
+	tests := []struct {
+		name      string
+		exemption policy.Exemption
+		violation Violation
+		want      bool
+	}{
+		{"namespace", policy.Exemption{Checks: []string{"host_network"}, Namespaces: []string{"kube-system"}}, hostNetwork, true},
+		{"other check", policy.Exemption{Checks: []string{"host_path"}, Namespaces: []string{"kube-system"}}, hostNetwork, false},
+		{"other namespace", policy.Exemption{Checks: []string{"host_network"}, Namespaces: []string{"payments"}}, hostNetwork, false},
+		{"labels", policy.Exemption{Checks: []string{"host_network"}, Labels: map[string]string{"k8s-app": "aws-node"}}, hostNetwork, true},
+		{"labels mismatch", policy.Exemption{Checks: []string{"host_network"}, Labels: map[string]string{"k8s-app": "calico"}}, hostNetwork, false},
+		{"service account", policy.Exemption{Checks: []string{"host_network"}, ServiceAccounts: []string{"aws-node"}}, hostNetwork, true},
+		{"user", policy.Exemption{Checks: []string{"host_network"}, Users: []string{"system:serviceaccount:kube-system:argocd"}}, hostNetwork, true},
+		{"group", policy.Exemption{Checks: []string{"host_network"}, Groups: []string{"platform-admins"}}, hostNetwork, true},
+		{"group mismatch", policy.Exemption{Checks: []string{"host_network"}, Groups: []string{"developers"}}, hostNetwork, false},
+		{"image of the container", policy.Exemption{Checks: []string{"image_tags"}, Images: []string{"602401143452.dkr.ecr.*"}}, cniImage, true},
+		{"image of another container", policy.Exemption{Checks: []string{"image_tags"}, Images: []string{"602401143452.dkr.ecr.*"}}, sidecarImage, false},
+		{"pod violation needs every image", policy.Exemption{Checks: []string{"host_network"}, Images: []string{"602401143452.dkr.ecr.*"}}, hostNetwork, false},
+		{
+			"every selector must match",
+			policy.Exemption{Checks: []string{"host_network"}, Namespaces: []string{"kube-system"}, ServiceAccounts: []string{"calico-node"}},
+			hostNetwork,
+			false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.exemption.Name = tt.name
+			tt.exemption.Expires = expires
+			got := eval.Exemption(tt.violation, []policy.Exemption{tt.exemption}, now) != nil
+			if got != tt.want {
+				t.Errorf("exempted = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("expired exemption is only a fallback", func(t *testing.T) {
+		exemptions := []policy.Exemption{
+			{Name: "old", Checks: []string{"host_network"}, Namespaces: []string{"kube-system"}, Expires: policy.Date{Time: time.Date(2026, 2, 28, 0, 0, 0, 0, time.UTC)}},
+			{Name: "current", Checks: []string{"host_network"}, Namespaces: []string{"kube-system"}, Expires: expires},
+		}
+		if got := eval.Exemption(hostNetwork, exemptions, now); got == nil || got.Name != "current" {
+			t.Errorf("got %+v, want the current exemption", got)
+		}
+		if got := eval.Exemption(hostNetwork, exemptions[:1], now); got == nil || !got.Expired(now) {
+			t.Errorf("got %+v, want the expired exemption", got)
+		}
+	})
+}
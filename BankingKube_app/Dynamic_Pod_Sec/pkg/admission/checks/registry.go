@@ -0,0 +1,148 @@
+package checks
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/policy"
+)
+
+// Registry holds checks organised into named groups. Each group is served by the
+// webhook on its own route (e.g. group "image" is served on /validate/image).
+type Registry struct {
+	mu        sync.RWMutex
+	groups    map[string][]Check
+	providers map[string][]Provider
+}
+
+// Provider returns checks that are defined by the policies of a request rather than in code,
+// such as the custom rules of the policy file
+type Provider func(eval *Evaluation) []Check
+
+// NewRegistry returns an empty registry
+func NewRegistry() *Registry {
+	return &Registry{groups: make(map[string][]Check), providers: make(map[string][]Provider)}
+}
+
+// Register adds a check to a group. It panics if a check with the same name is
+// already registered in that group, as this is a programming error in an init function.
+func (r *Registry) Register(group string, check Check) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.groups[group] {
+		if existing.Name() == check.Name() {
+			panic(fmt.Sprintf("checks: check %q registered twice in group %q", check.Name(), group))
+		}
+	}
+	r.groups[group] = append(r.groups[group], check)
+}
+
+// RegisterProvider adds a provider of checks to a group
+func (r *Registry) RegisterProvider(group string, provider Provider) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.providers[group] = append(r.providers[group], provider)
+}
+
+// Groups returns the names of all registered groups in sorted order
+func (r *Registry) Groups() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	groups := make([]string, 0, len(r.groups)+len(r.providers))
+	for group := range r.groups {
+		groups = append(groups, group)
+	}
+	for group := range r.providers {
+		if _, ok := r.groups[group]; !ok {
+			groups = append(groups, group)
+		}
+	}
+	sort.Strings(groups)
+	return groups
+}
+
+// Names returns the names of all registered checks across groups in sorted order
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	seen := make(map[string]bool)
+	var names []string
+	for _, list := range r.groups {
+		for _, check := range list {
+			if !seen[check.Name()] {
+				seen[check.Name()] = true
+				names = append(names, check.Name())
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// HasGroup reports whether any checks are registered under the group
+func (r *Registry) HasGroup(group string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	_, ok := r.groups[group]
+	_, provided := r.providers[group]
+	return ok || provided
+}
+
+// Checks returns the checks of a group that apply to the given kind, in registration order
+func (r *Registry) Checks(group, kind string) []Check {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var applicable []Check
+	for _, check := range r.groups[group] {
+		if AppliesTo(check, kind) {
+			applicable = append(applicable, check)
+		}
+	}
+	return applicable
+}
+
+// Resolve returns the checks of a group that apply to a request: the registered checks
+// in registration order, followed by those of the group's providers
+func (r *Registry) Resolve(group string, eval *Evaluation) []Check {
+	applicable := r.Checks(group, eval.Request.Kind.Kind)
+
+	r.mu.RLock()
+	providers := r.providers[group]
+	r.mu.RUnlock()
+
+	for _, provider := range providers {
+		for _, check := range provider(eval) {
+			if AppliesTo(check, eval.Request.Kind.Kind) {
+				applicable = append(applicable, check)
+			}
+		}
+	}
+	return applicable
+}
+
+// Default is the registry used by the webhook and populated by the built-in admission packages
+var Default = NewRegistry()
+
+// Register adds a check to a group of the Default registry and reserves its name,
+// so that no custom rule can take it
+func Register(group string, check Check) {
+	Default.Register(group, check)
+	policy.ReserveCheckName(check.Name())
+}
+
+// RegisterProvider adds a provider of checks to a group of the Default registry
+func RegisterProvider(group string, provider Provider) {
+	Default.RegisterProvider(group, provider)
+}
+
+// Groups returns the group names of the Default registry
+func Groups() []string {
+	return Default.Groups()
+}
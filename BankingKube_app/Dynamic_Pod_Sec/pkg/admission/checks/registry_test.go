@@ -0,0 +1,65 @@
+package checks
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+// passing returns a check that never reports anything
+func passing(name string, kinds ...string) Check {
+	return NewCheck(name, kinds, func(context.Context, *Evaluation) []Violation { return nil })
+}
+
+// names returns the names of checks in order
+func names(list []Check) []string {
+	var out []string
+	for _, check := range list {
+		out = append(out, check.Name())
+	}
+	return out
+}
+
+func TestRegistryFiltersByKind(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register("workloads", passing("pods_only", "Pod"))
+	registry.Register("workloads", passing("any_kind"))
+	registry.Register("workloads", passing("deployments_only", "Deployment"))
+
+	if got, want := names(registry.Checks("workloads", "Pod")), []string{"pods_only", "any_kind"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Checks(Pod) = %v, want %v", got, want)
+	}
+	if got, want := names(registry.Checks("workloads", "Deployment")), []string{"any_kind", "deployments_only"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Checks(Deployment) = %v, want %v", got, want)
+	}
+	if got := registry.Checks("other", "Pod"); len(got) != 0 {
+		t.Errorf("Checks of an unknown group = %v", names(got))
+	}
+}
+
+func TestRegistryGroups(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register("network", passing("egress"))
+	registry.Register("image", passing("image_tags"))
+	// The same name may be registered in another group
+	registry.Register("network", passing("image_tags"))
+
+	if got, want := registry.Groups(), []string{"image", "network"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Groups() = %v, want %v", got, want)
+	}
+	if !registry.HasGroup("image") || registry.HasGroup("rbac") {
+		t.Errorf("HasGroup(image) = %v, HasGroup(rbac) = %v", registry.HasGroup("image"), registry.HasGroup("rbac"))
+	}
+}
+
+func TestRegistryPanicsOnDuplicate(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register("image", passing("image_tags"))
+
+	defer func() {
+		if recover() == nil {
+			t.Error("registering a check twice in a group did not panic")
+		}
+	}()
+	registry.Register("image", passing("image_tags", "Pod"))
+}
@@ -0,0 +1,68 @@
+package checks
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Outcome is what running one check produced
+type Outcome struct {
+	Check    Check
+	Result   Result
+	Duration time.Duration
+	// Unfinished is set when the check did not return within its budget or the request deadline,
+	// or panicked. Err tells which; Result is empty.
+	Unfinished bool
+	Err        error
+}
+
+// Budget returns the time a check may run for
+type Budget func(check Check) time.Duration
+
+// Run evaluates independent checks concurrently. Each check runs under a context whose deadline is
+// its own budget, capped by the deadline of ctx. A check that has not returned by then is reported as
+// unfinished and abandoned: its goroutine is left to exit on its own once it notices the cancelled context.
+// Outcomes are returned in the order of the checks.
+func Run(ctx context.Context, eval *Evaluation, list []Check, budget Budget) []Outcome {
+	outcomes := make([]Outcome, len(list))
+	done := make(chan int, len(list))
+
+	for i, check := range list {
+		checkCtx, cancel := context.WithTimeout(ctx, budget(check))
+		defer cancel()
+
+		outcomes[i].Check = check
+		results := make(chan Outcome, 1) // Buffered so an abandoned check never blocks
+		go func() {
+			start := time.Now()
+			defer func() {
+				if r := recover(); r != nil {
+					results <- Outcome{Unfinished: true, Err: fmt.Errorf("check panicked: %v", r), Duration: time.Since(start)}
+				}
+			}()
+			result := check.Validate(checkCtx, eval)
+			results <- Outcome{Result: result, Duration: time.Since(start)}
+		}()
+
+		go func(i int, start time.Time) {
+			select {
+			case outcome := <-results:
+				outcomes[i].Result = outcome.Result
+				outcomes[i].Duration = outcome.Duration
+				outcomes[i].Unfinished = outcome.Unfinished
+				outcomes[i].Err = outcome.Err
+			case <-checkCtx.Done():
+				outcomes[i].Unfinished = true
+				outcomes[i].Err = checkCtx.Err()
+				outcomes[i].Duration = time.Since(start)
+			}
+			done <- i
+		}(i, time.Now())
+	}
+
+	for range list {
+		<-done
+	}
+	return outcomes
+}
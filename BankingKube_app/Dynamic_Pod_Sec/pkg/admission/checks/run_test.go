@@ -0,0 +1,83 @@
+package checks
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// sleeping returns a check that reports one violation after d, or gives up when its context is cancelled
+func sleeping(name string, d time.Duration) Check {
+	return NewCheck(name, nil, func(ctx context.Context, _ *Evaluation) []Violation {
+		select {
+		case <-time.After(d):
+			return []Violation{{Reason: "slow", Message: name}}
+		case <-ctx.Done():
+			return nil
+		}
+	})
+}
+
+func fixedBudget(d time.Duration) Budget {
+	return func(Check) time.Duration { return d }
+}
+
+func TestRunIsConcurrent(t *testing.T) {
+	list := []Check{sleeping("a", 100*time.Millisecond), sleeping("b", 100*time.Millisecond), sleeping("c", 100*time.Millisecond)}
+
+	start := time.Now()
+	outcomes := Run(context.Background(), NewEvaluation(nil, nil), list, fixedBudget(time.Second))
+	if elapsed := time.Since(start); elapsed > 250*time.Millisecond {
+		t.Errorf("three 100ms checks took %s, want them to run concurrently", elapsed)
+	}
+
+	for i, outcome := range outcomes {
+		if outcome.Check != list[i] {
+			t.Errorf("outcome %d belongs to %s, want %s", i, outcome.Check.Name(), list[i].Name())
+		}
+		if outcome.Unfinished || len(outcome.Result.Violations) != 1 {
+			t.Errorf("outcome of %s = %+v, want one violation", outcome.Check.Name(), outcome)
+		}
+	}
+}
+
+func TestRunReportsUnfinishedChecks(t *testing.T) {
+	list := []Check{sleeping("fast", 0), sleeping("slow", time.Second)}
+	budget := func(check Check) time.Duration {
+		if check.Name() == "slow" {
+			return 20 * time.Millisecond
+		}
+		return time.Second
+	}
+
+	outcomes := Run(context.Background(), NewEvaluation(nil, nil), list, budget)
+	if outcomes[0].Unfinished {
+		t.Errorf("fast check reported unfinished: %v", outcomes[0].Err)
+	}
+	if !outcomes[1].Unfinished || outcomes[1].Err != context.DeadlineExceeded {
+		t.Errorf("slow check outcome = %+v, want unfinished with deadline exceeded", outcomes[1])
+	}
+}
+
+func TestRunCapsBudgetsByRequestDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	outcomes := Run(ctx, NewEvaluation(nil, nil), []Check{sleeping("slow", time.Second)}, fixedBudget(time.Minute))
+	if !outcomes[0].Unfinished {
+		t.Fatal("check outliving the request deadline was reported as finished")
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Run returned after %s, want it to stop at the request deadline", elapsed)
+	}
+}
+
+func TestRunRecoversPanics(t *testing.T) {
+	panicking := NewCheck("panicking", nil, func(context.Context, *Evaluation) []Violation { panic("boom") })
+
+	outcomes := Run(context.Background(), NewEvaluation(nil, nil), []Check{panicking}, fixedBudget(time.Second))
+	if !outcomes[0].Unfinished || outcomes[0].Err == nil {
+		t.Errorf("panicking check outcome = %+v, want unfinished with an error", outcomes[0])
+	}
+}
@@ -0,0 +1,111 @@
+package checks
+
+import (
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Levels a value of the effective security context can come from
+const (
+	LevelContainer = "container"
+	LevelPod       = "pod"
+	LevelDefault   = "default" // Set by neither, the runtime default applies
+)
+
+// Setting is one value of a container's effective security context
+type Setting[T any] struct {
+	Value *T     // nil when neither the container nor the pod sets it
+	Level string // LevelContainer, LevelPod or LevelDefault
+	Field string // Field path of the value; the container's field when the value is unset
+
+	// Fields are the paths the value is resolved from: the container's field and, for values
+	// inherited from the pod, the pod's field. A change to any of them can change the value.
+	Fields []string
+}
+
+// Related returns the fields the value is resolved from other than Field
+func (s Setting[T]) Related() []string {
+	var related []string
+	for _, field := range s.Fields {
+		if field != s.Field {
+			related = append(related, field)
+		}
+	}
+	return related
+}
+
+// Origin describes where the value was set, for violation messages
+func (s Setting[T]) Origin() string {
+	switch s.Level {
+	case LevelContainer:
+		return "on the container"
+	case LevelPod:
+		return "on the pod"
+	default:
+		return "by default"
+	}
+}
+
+// SecurityContext is the security context a container runs with
+type SecurityContext struct {
+	// Inherited from the pod securityContext unless the container sets them
+	RunAsUser       Setting[int64]
+	RunAsGroup      Setting[int64]
+	RunAsNonRoot    Setting[bool]
+	SELinuxOptions  Setting[corev1.SELinuxOptions]
+	SeccompProfile  Setting[corev1.SeccompProfile]
+	AppArmorProfile Setting[corev1.AppArmorProfile]
+
+	// Only set on the container
+	Privileged               Setting[bool]
+	AllowPrivilegeEscalation Setting[bool]
+	ReadOnlyRootFilesystem   Setting[bool]
+	Capabilities             Setting[corev1.Capabilities]
+	ProcMount                Setting[corev1.ProcMountType]
+}
+
+// EffectiveSecurityContext computes the security context of a container the way the kubelet does:
+// a value set on the container overrides the one set on the pod.
+// specPath is the field path of the spec inside the admitted object (e.g. "spec").
+func EffectiveSecurityContext(spec *corev1.PodSpec, specPath string, ref ContainerRef) SecurityContext {
+	pod := spec.SecurityContext
+	if pod == nil {
+		pod = &corev1.PodSecurityContext{}
+	}
+	container := ref.Container.SecurityContext
+	if container == nil {
+		container = &corev1.SecurityContext{}
+	}
+	podPath := specPath + ".securityContext."
+	containerPath := ref.Path + ".securityContext."
+
+	return SecurityContext{
+		RunAsUser:       inherit(container.RunAsUser, pod.RunAsUser, containerPath+"runAsUser", podPath+"runAsUser"),
+		RunAsGroup:      inherit(container.RunAsGroup, pod.RunAsGroup, containerPath+"runAsGroup", podPath+"runAsGroup"),
+		RunAsNonRoot:    inherit(container.RunAsNonRoot, pod.RunAsNonRoot, containerPath+"runAsNonRoot", podPath+"runAsNonRoot"),
+		SELinuxOptions:  inherit(container.SELinuxOptions, pod.SELinuxOptions, containerPath+"seLinuxOptions", podPath+"seLinuxOptions"),
+		SeccompProfile:  inherit(container.SeccompProfile, pod.SeccompProfile, containerPath+"seccompProfile", podPath+"seccompProfile"),
+		AppArmorProfile: inherit(container.AppArmorProfile, pod.AppArmorProfile, containerPath+"appArmorProfile", podPath+"appArmorProfile"),
+
+		Privileged:               inherit(container.Privileged, nil, containerPath+"privileged", ""),
+		AllowPrivilegeEscalation: inherit(container.AllowPrivilegeEscalation, nil, containerPath+"allowPrivilegeEscalation", ""),
+		ReadOnlyRootFilesystem:   inherit(container.ReadOnlyRootFilesystem, nil, containerPath+"readOnlyRootFilesystem", ""),
+		Capabilities:             inherit(container.Capabilities, nil, containerPath+"capabilities", ""),
+		ProcMount:                inherit(container.ProcMount, nil, containerPath+"procMount", ""),
+	}
+}
+
+// inherit picks the container's value over the pod's
+func inherit[T any](container, pod *T, containerField, podField string) Setting[T] {
+	fields := []string{containerField}
+	if podField != "" {
+		fields = append(fields, podField)
+	}
+	switch {
+	case container != nil:
+		return Setting[T]{Value: container, Level: LevelContainer, Field: containerField, Fields: fields}
+	case pod != nil:
+		return Setting[T]{Value: pod, Level: LevelPod, Field: podField, Fields: fields}
+	default:
+		return Setting[T]{Level: LevelDefault, Field: containerField, Fields: fields}
+	}
+}
@@ -0,0 +1,39 @@
+package checks
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestEffectiveSecurityContext(t *testing.T) {
+	root, user, yes, no := int64(0), int64(1000), true, false
+	spec := &corev1.PodSpec{
+		SecurityContext: &corev1.PodSecurityContext{RunAsUser: &root, RunAsNonRoot: &yes},
+		Containers: []corev1.Container{
+			{Name: "app", SecurityContext: &corev1.SecurityContext{RunAsUser: &user, ReadOnlyRootFilesystem: &no}},
+			{Name: "sidecar"},
+		},
+	}
+	refs := PodContainers(spec, "spec.template.spec")
+
+	app := EffectiveSecurityContext(spec, "spec.template.spec", refs[0])
+	if *app.RunAsUser.Value != 1000 || app.RunAsUser.Level != LevelContainer || app.RunAsUser.Field != "spec.template.spec.containers[0].securityContext.runAsUser" {
+		t.Errorf("app runAsUser = %+v, want 1000 from the container", app.RunAsUser)
+	}
+	if !*app.RunAsNonRoot.Value || app.RunAsNonRoot.Level != LevelPod || app.RunAsNonRoot.Field != "spec.template.spec.securityContext.runAsNonRoot" {
+		t.Errorf("app runAsNonRoot = %+v, want true from the pod", app.RunAsNonRoot)
+	}
+	if *app.ReadOnlyRootFilesystem.Value || app.ReadOnlyRootFilesystem.Level != LevelContainer {
+		t.Errorf("app readOnlyRootFilesystem = %+v, want false from the container", app.ReadOnlyRootFilesystem)
+	}
+
+	// A container without a securityContext inherits the pod's values
+	sidecar := EffectiveSecurityContext(spec, "spec.template.spec", refs[1])
+	if *sidecar.RunAsUser.Value != 0 || sidecar.RunAsUser.Level != LevelPod || sidecar.RunAsUser.Origin() != "on the pod" {
+		t.Errorf("sidecar runAsUser = %+v, want 0 from the pod", sidecar.RunAsUser)
+	}
+	if p := sidecar.AllowPrivilegeEscalation; p.Value != nil || p.Level != LevelDefault || p.Field != "spec.template.spec.containers[1].securityContext.allowPrivilegeEscalation" {
+		t.Errorf("sidecar allowPrivilegeEscalation = %+v, want unset on the container", p)
+	}
+}
@@ -0,0 +1,103 @@
+package checks
+
+import (
+	"strings"
+
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/jsonpatch"
+
+	admissionv1 "k8s.io/api/admission/v1"
+)
+
+// Skipped reports whether the operation of a request carries no object to evaluate.
+// DELETE sends only the old object and CONNECT sends connection options.
+func Skipped(request *admissionv1.AdmissionRequest) bool {
+	return request.Operation == admissionv1.Delete || request.Operation == admissionv1.Connect
+}
+
+// Changes returns the JSON pointers of the fields an UPDATE changes relative to OldObject.
+// It returns nil for any other operation.
+func (e *Evaluation) Changes() ([]string, error) {
+	e.changesOnce.Do(func() {
+		if e.Request.Operation != admissionv1.Update || len(e.Request.OldObject.Raw) == 0 {
+			return
+		}
+		ops, err := jsonpatch.Diff(e.Request.OldObject.Raw, e.Request.Object.Raw)
+		if err != nil {
+			e.changesErr = err
+			return
+		}
+		e.changes = make([]string, 0, len(ops))
+		for _, op := range ops {
+			e.changes = append(e.changes, op.Path)
+		}
+	})
+	return e.changes, e.changesErr
+}
+
+// Introduced reports whether an UPDATE introduces a violation, i.e. whether the field it
+// concerns or one of its related fields, anything inside them or anything containing them
+// changed. A violation of the object as a whole is introduced by any change outside its metadata.
+// On any other operation every violation is introduced.
+func (e *Evaluation) Introduced(v Violation) bool {
+	changes, err := e.Changes()
+	if err != nil || changes == nil {
+		return true
+	}
+	if v.Field == "" {
+		for _, change := range changes {
+			if change != "/metadata" && !strings.HasPrefix(change, "/metadata/") {
+				return true
+			}
+		}
+		return false
+	}
+	for _, field := range append([]string{v.Field}, v.Related...) {
+		pointer := FieldPointer(field)
+		for _, change := range changes {
+			if within(change, pointer) || within(pointer, change) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// within reports whether pointer equals parent or points inside it
+func within(pointer, parent string) bool {
+	return pointer == parent || parent == "" || strings.HasPrefix(pointer, parent+"/")
+}
+
+// FieldPointer converts a violation field path such as spec.containers[0].image or
+// metadata.annotations[k8s.v1.cni.cncf.io/networks] into a JSON pointer
+func FieldPointer(field string) string {
+	var b strings.Builder
+	var token strings.Builder
+	flush := func() {
+		if token.Len() > 0 {
+			b.WriteString("/")
+			b.WriteString(jsonpatch.EscapePointer(token.String()))
+			token.Reset()
+		}
+	}
+	for i := 0; i < len(field); i++ {
+		switch field[i] {
+		case '.':
+			flush()
+		case '[':
+			flush()
+			end := strings.IndexByte(field[i:], ']')
+			if end < 0 {
+				token.WriteString(field[i+1:])
+				i = len(field)
+				continue
+			}
+			token.WriteString(field[i+1 : i+end])
+			flush()
+			i += end
+		default:
+			token.WriteByte(field[i])
+		}
+	}
+	flush()
+	return b.String()
+}
@@ -0,0 +1,75 @@
+package checks
+
+import (
+	"testing"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestFieldPointer(t *testing.T) {
+	tests := map[string]string{
+		"spec.containers[0].image":                                    "/spec/containers/0/image",
+		"spec.template.spec.volumes[2].hostPath.path":                 "/spec/template/spec/volumes/2/hostPath/path",
+		"metadata.annotations[k8s.v1.cni.cncf.io/networks]":           "/metadata/annotations/k8s.v1.cni.cncf.io~1networks",
+		"rules[1].verbs[0]":                                           "/rules/1/verbs/0",
+		"spec.containers[0].securityContext.capabilities.add[1]":      "/spec/containers/0/securityContext/capabilities/add/1",
+		"spec.jobTemplate.spec.template.spec.containers[0].resources": "/spec/jobTemplate/spec/template/spec/containers/0/resources",
+	}
+	for field, want := range tests {
+		if got := FieldPointer(field); got != want {
+			t.Errorf("FieldPointer(%q) = %q, want %q", field, got, want)
+		}
+	}
+}
+
+func TestIntroduced(t *testing.T) {
+	const old = `{"metadata":{"name":"web","labels":{"app":"web"}},"spec":{"containers":[{"name":"app","image":"nginx:1.25","securityContext":{"privileged":true}}]}}`
+	update := func(object string) *Evaluation {
+		return NewEvaluation(&admissionv1.AdmissionRequest{
+			Operation: admissionv1.Update,
+			Object:    runtime.RawExtension{Raw: []byte(object)},
+			OldObject: runtime.RawExtension{Raw: []byte(old)},
+		}, nil)
+	}
+	image := Violation{Field: "spec.containers[0].image"}
+	privileged := Violation{Field: "spec.containers[0].securityContext.privileged"}
+	containers := Violation{Field: "spec.containers"}
+	object := Violation{}
+
+	relabelled := update(`{"metadata":{"name":"web","labels":{"app":"web","team":"payments"}},"spec":{"containers":[{"name":"app","image":"nginx:1.25","securityContext":{"privileged":true}}]}}`)
+	for _, v := range []Violation{image, privileged, containers, object} {
+		if relabelled.Introduced(v) {
+			t.Errorf("label-only update introduced %+v", v)
+		}
+	}
+
+	retagged := update(`{"metadata":{"name":"web","labels":{"app":"web"}},"spec":{"containers":[{"name":"app","image":"nginx:latest","securityContext":{"privileged":true}}]}}`)
+	if !retagged.Introduced(image) {
+		t.Error("image update did not introduce the image violation")
+	}
+	if retagged.Introduced(privileged) {
+		t.Error("image update introduced the unchanged privileged violation")
+	}
+	if !retagged.Introduced(containers) {
+		t.Error("image update did not introduce a violation of the enclosing containers")
+	}
+	if !retagged.Introduced(object) {
+		t.Error("spec update did not introduce an object-level violation")
+	}
+
+	// A container inheriting a value from the pod is affected by a change of the pod's field
+	inherited := Violation{Field: "spec.containers[0].securityContext.runAsNonRoot", Related: []string{"spec.securityContext.runAsNonRoot"}}
+	podLevel := update(`{"metadata":{"name":"web","labels":{"app":"web"}},"spec":{"securityContext":{"runAsNonRoot":false},"containers":[{"name":"app","image":"nginx:1.25","securityContext":{"privileged":true}}]}}`)
+	if !podLevel.Introduced(inherited) {
+		t.Error("pod-level update did not introduce the violation of the container inheriting it")
+	}
+	if retagged.Introduced(inherited) {
+		t.Error("image update introduced the unchanged inherited violation")
+	}
+
+	created := NewEvaluation(&admissionv1.AdmissionRequest{Operation: admissionv1.Create, Object: runtime.RawExtension{Raw: []byte(old)}}, nil)
+	if !created.Introduced(privileged) {
+		t.Error("CREATE did not introduce every violation")
+	}
+}
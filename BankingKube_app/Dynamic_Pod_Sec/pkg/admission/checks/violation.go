@@ -0,0 +1,83 @@
+package checks
+
+import (
+	"fmt"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Violation describes a single policy breach reported by a check
+type Violation struct {
+	CheckID   string   `json:"checkID"`                 // Name of the check that reported the violation
+	Reason    string   `json:"reason"`                  // Machine readable reason, matches the denial_reason metric attribute
+	Container string   `json:"container,omitempty"`     // Offending container, empty for object-level findings
+	Field     string   `json:"field,omitempty"`         // Field path of the offending value (e.g. spec.containers[0].image)
+	Related   []string `json:"relatedFields,omitempty"` // Other fields the offending value is resolved from, e.g. the pod's field a container inherits
+	Value     string   `json:"value,omitempty"`         // Offending value
+	Expected  string   `json:"expected,omitempty"`      // Value or constraint required by the policy
+	Message   string   `json:"message"`                 // Human readable description
+	Internal  bool     `json:"internal,omitempty"`      // Set when the check failed to evaluate rather than finding a breach
+}
+
+// String renders the violation as a single readable line
+func (v Violation) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[%s] %s", v.CheckID, v.Message)
+	if v.Field != "" {
+		fmt.Fprintf(&b, " (field: %s", v.Field)
+		if v.Value != "" {
+			fmt.Fprintf(&b, ", value: %q", v.Value)
+		}
+		if v.Expected != "" {
+			fmt.Fprintf(&b, ", expected: %s", v.Expected)
+		}
+		b.WriteString(")")
+	}
+	return b.String()
+}
+
+// ErrorViolation reports a failure to evaluate a check (e.g. an unparsable object).
+// It is marked Internal, so the webhook handles it under the check's error policy instead of as a breach.
+func ErrorViolation(reason string, err error) Violation {
+	return Violation{Reason: reason, Message: fmt.Sprintf("check could not be evaluated: %v", err), Internal: true}
+}
+
+// Summarize builds the combined message returned to the user for a list of violations
+func Summarize(violations []Violation) string {
+	if len(violations) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	if len(violations) == 1 {
+		b.WriteString("1 policy violation found:")
+	} else {
+		fmt.Fprintf(&b, "%d policy violations found:", len(violations))
+	}
+	for _, v := range violations {
+		b.WriteString("\n- ")
+		b.WriteString(v.String())
+	}
+	return b.String()
+}
+
+// Causes converts violations into StatusCauses for metav1.Status.Details
+func Causes(violations []Violation) []metav1.StatusCause {
+	causes := make([]metav1.StatusCause, 0, len(violations))
+	for _, v := range violations {
+		message := v.Message
+		if v.Value != "" {
+			message += fmt.Sprintf(" (value: %q", v.Value)
+			if v.Expected != "" {
+				message += fmt.Sprintf(", expected: %s", v.Expected)
+			}
+			message += ")"
+		}
+		causes = append(causes, metav1.StatusCause{
+			Type:    metav1.CauseType(v.CheckID + "/" + v.Reason),
+			Message: message,
+			Field:   v.Field,
+		})
+	}
+	return causes
+}
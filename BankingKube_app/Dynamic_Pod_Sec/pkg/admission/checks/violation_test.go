@@ -0,0 +1,46 @@
+package checks
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSummarize(t *testing.T) {
+	if got := Summarize(nil); got != "" {
+		t.Errorf("Summarize(nil) = %q, want empty", got)
+	}
+
+	violations := []Violation{
+		{CheckID: "image_tags", Reason: "disallowed_tag", Field: "spec.containers[0].image", Value: "nginx:latest", Expected: "a tag other than latest", Message: `container "web" uses disallowed tag latest`},
+		{CheckID: "host_network", Reason: "host_network", Message: "pod uses the host network"},
+	}
+	want := "2 policy violations found:\n" +
+		`- [image_tags] container "web" uses disallowed tag latest (field: spec.containers[0].image, value: "nginx:latest", expected: a tag other than latest)` + "\n" +
+		"- [host_network] pod uses the host network"
+	if got := Summarize(violations); got != want {
+		t.Errorf("Summarize() = %q, want %q", got, want)
+	}
+	if got := Summarize(violations[1:]); got != "1 policy violation found:\n- [host_network] pod uses the host network" {
+		t.Errorf("Summarize() = %q for a single violation", got)
+	}
+}
+
+func TestCauses(t *testing.T) {
+	causes := Causes([]Violation{
+		{CheckID: "image_registry", Reason: "disallowed_registry", Field: "spec.containers[1].image", Value: "docker.io/nginx", Expected: "one of ecr", Message: "disallowed registry"},
+		{CheckID: "host_network", Reason: "host_network", Field: "spec.hostNetwork", Message: "pod uses the host network"},
+	})
+	want := []metav1.StatusCause{
+		{Type: "image_registry/disallowed_registry", Message: `disallowed registry (value: "docker.io/nginx", expected: one of ecr)`, Field: "spec.containers[1].image"},
+		{Type: "host_network/host_network", Message: "pod uses the host network", Field: "spec.hostNetwork"},
+	}
+	if len(causes) != len(want) {
+		t.Fatalf("Causes() = %+v, want %+v", causes, want)
+	}
+	for i := range want {
+		if causes[i] != want[i] {
+			t.Errorf("cause %d = %+v, want %+v", i, causes[i], want[i])
+		}
+	}
+}
@@ -2,17 +2,20 @@ package context_capabilities
 
 import (
 	"context"
-	"encoding/json"
+	"fmt"
 	"log"
-	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/checks"
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/policy"
 
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/metric"
 	"go.opentelemetry.io/otel/trace"
 
-	"gopkg.in/yaml.v2"
-	admissionv1 "k8s.io/api/admission/v1"
 	corev1 "k8s.io/api/core/v1"
 )
 
@@ -35,31 +38,16 @@ func init() {
 	}
 }
 
-// Capabilities defines a structure for capabilities policies
-type Capabilities struct {
-	AllowedCapabilities    []string `yaml:"allowedCapabilities"`
-	DisallowedCapabilities []string `yaml:"disallowedCapabilities"`
-	RequiredDrops          []string `yaml:"requiredDrops"`
-}
-
-// SecurityPoliciesCap represents the structure of the security-policies.yaml file
-type SecurityPoliciesCap struct {
-	Policies struct {
-		Capabilities Capabilities `yaml:"capabilities"`
-	} `yaml:"policies"`
-}
-
 // CheckCapabilities ensures that the pod does not use dangerous Linux capabilities
-func CheckCapabilities(ctx context.Context, request *admissionv1.AdmissionRequest) bool {
+func CheckCapabilities(ctx context.Context, eval *checks.Evaluation) []checks.Violation {
 	ctx, span := capTracer.Start(ctx, "CheckCapabilities", trace.WithAttributes(
-		attribute.String("operation", string(request.Operation)),
-		attribute.String("resource", request.Resource.Resource),
+		attribute.String("operation", string(eval.Request.Operation)),
+		attribute.String("resource", eval.Request.Resource.Resource),
 	))
 	defer span.End()
 
-	// Parse the Pod object from the request
-	pod := &corev1.Pod{}
-	err := json.Unmarshal(request.Object.Raw, pod)
+	// Extract the pod, or the pod template of a workload, from the request
+	template, err := eval.PodTemplate()
 	if err != nil {
 		log.Println("Failed to parse pod object:", err)
 		span.SetAttributes(
@@ -67,8 +55,9 @@ func CheckCapabilities(ctx context.Context, request *admissionv1.AdmissionReques
 			attribute.String("result", "denied"),
 		)
 		span.RecordError(err)
-		return false // Fails the validation if the pod can't be parsed
+		return []checks.Violation{checks.ErrorViolation("failed_to_parse_pod", err)} // Fails the validation if the pod can't be parsed
 	}
+	pod := template.Pod
 
 	span.SetAttributes(
 		attribute.String("pod", pod.Name),
@@ -76,7 +65,7 @@ func CheckCapabilities(ctx context.Context, request *admissionv1.AdmissionReques
 	)
 
 	// Retrieve the capabilities policies
-	capabilities, err := getCapabilities()
+	policies, err := eval.Policies()
 	if err != nil {
 		log.Println("Failed to load capabilities policies:", err)
 		span.SetAttributes(
@@ -84,66 +73,90 @@ func CheckCapabilities(ctx context.Context, request *admissionv1.AdmissionReques
 			attribute.String("result", "denied"),
 		)
 		span.RecordError(err)
-		return false
+		return []checks.Violation{checks.ErrorViolation("failed_to_load_policies", err)}
+	}
+	capabilities := &policies.Capabilities
+
+	var violations []checks.Violation
+
+	// deny records a violation for a container together with its log line, metric and span event
+	deny := func(ref checks.ContainerRef, reason, field, value, expected, message string) {
+		log.Printf("Pod %s in namespace %s: %s\n", pod.Name, pod.Namespace, message)
+
+		capDenied.Add(ctx, 1, metric.WithAttributes(
+			attribute.String("pod", pod.Name),
+			attribute.String("namespace", pod.Namespace),
+			attribute.String("container", ref.Container.Name),
+			attribute.String("capability", value),
+			attribute.String("denial_reason", reason),
+		))
+
+		span.AddEvent("Capability violation", trace.WithAttributes(
+			attribute.String("container", ref.Container.Name),
+			attribute.String("capability", value),
+			attribute.String("denial_reason", reason),
+		))
+
+		violations = append(violations, checks.Violation{
+			Reason:    reason,
+			Container: ref.Container.Name,
+			Field:     field,
+			Value:     value,
+			Expected:  expected,
+			Message:   message,
+		})
 	}
 
 	// Check capabilities for each container
-	for _, container := range pod.Spec.Containers {
+	for _, ref := range checks.PodContainers(&pod.Spec, template.SpecPath) {
+		container := ref.Container
 		span.AddEvent("Checking container", trace.WithAttributes(
 			attribute.String("container", container.Name),
+			attribute.String("container_type", ref.Type),
 		))
 
-		if container.SecurityContext != nil && container.SecurityContext.Capabilities != nil {
-			// Check for disallowed capabilities
-			for _, cap := range container.SecurityContext.Capabilities.Add {
-				if isDisallowedCapability(string(cap), capabilities.DisallowedCapabilities) {
-					log.Println("Disallowed capability found in container:", container.Name, "Capability:", cap)
-
-					capDenied.Add(ctx, 1, metric.WithAttributes(
-						attribute.String("pod", pod.Name),
-						attribute.String("namespace", pod.Namespace),
-						attribute.String("container", container.Name),
-						attribute.String("disallowed_capability", string(cap)),
-						attribute.String("denial_reason", "disallowed_capability"),
-					))
-
-					span.SetAttributes(
-						attribute.String("container", container.Name),
-						attribute.String("disallowed_capability", string(cap)),
-						attribute.String("result", "denied"),
-						attribute.String("denial_reason", "disallowed_capability"),
-					)
-
-					return false // Return false if any disallowed capability is found
-				}
-			}
-
-			// Check for required capability drops
-			if !hasDroppedAllRequiredCapabilities(container.SecurityContext.Capabilities.Drop, capabilities.RequiredDrops) {
-				log.Println("Necessary capabilities not dropped in container:", container.Name)
-
-				missingDrops := getMissingRequiredDrops(container.SecurityContext.Capabilities.Drop, capabilities.RequiredDrops)
+		// A container without capabilities keeps the runtime's default set and drops nothing
+		var added, dropped []string
+		if sc := container.SecurityContext; sc != nil && sc.Capabilities != nil {
+			added = capabilityList(sc.Capabilities.Add)
+			dropped = capabilityList(sc.Capabilities.Drop)
+		}
 
-				capDenied.Add(ctx, 1, metric.WithAttributes(
-					attribute.String("pod", pod.Name),
-					attribute.String("namespace", pod.Namespace),
-					attribute.String("container", container.Name),
-					attribute.String("missing_required_drops", string(missingDrops)),
-					attribute.String("denial_reason", "missing_required_drops"),
-				))
+		// Check for disallowed capabilities, and in allowlist mode for capabilities outside the allowlist
+		for i, cap := range added {
+			field := fmt.Sprintf("%s.securityContext.capabilities.add[%d]", ref.Path, i)
+			switch {
+			case isDisallowedCapability(cap, capabilities.DisallowedCapabilities):
+				deny(ref, "disallowed_capability", field, cap, "none of "+strings.Join(policy.CapabilityNames(capabilities.DisallowedCapabilities), ", "),
+					fmt.Sprintf("container %q adds disallowed capability %s", container.Name, cap))
+			case capabilities.EnforceAllowedCapabilities && !isAllowedCapability(cap, capabilities.AllowedCapabilities):
+				deny(ref, "capability_not_allowed", field, cap, allowedCapabilities(capabilities.AllowedCapabilities),
+					fmt.Sprintf("container %q adds capability %s, which is not in the allowlist", container.Name, cap))
+			}
+		}
 
-				span.SetAttributes(
-					attribute.String("container", container.Name),
-					attribute.String("missing_required_drops", string(missingDrops)),
-					attribute.String("result", "denied"),
-					attribute.String("denial_reason", "missing_required_drops"),
-				)
+		// In allowlist mode the runtime's default set must go too
+		if capabilities.EnforceAllowedCapabilities && !policy.HasCapability(dropped, policy.CapabilityAll) {
+			deny(ref, "capabilities_not_dropped", ref.Path+".securityContext.capabilities.drop", strings.Join(dropped, ", "), "drop ALL",
+				fmt.Sprintf("container %q does not drop ALL capabilities, which the allowlist requires", container.Name))
+		}
 
-				return false // Return false if necessary capabilities are not dropped
-			}
+		// Check for required capability drops
+		if missingDrops := getMissingRequiredDrops(dropped, capabilities.RequiredDrops); missingDrops != "" {
+			deny(ref, "missing_required_drops", ref.Path+".securityContext.capabilities.drop", missingDrops,
+				"drop "+strings.Join(policy.CapabilityNames(capabilities.RequiredDrops), ", "),
+				fmt.Sprintf("container %q does not drop required capabilities %s", container.Name, missingDrops))
 		}
 	}
 
+	if len(violations) > 0 {
+		span.SetAttributes(
+			attribute.String("result", "denied"),
+			attribute.Int("violation_count", len(violations)),
+		)
+		return violations
+	}
+
 	// Passes the check if no disallowed capabilities are found and all required capabilities are dropped
 	capAllowed.Add(ctx, 1, metric.WithAttributes(
 		attribute.String("pod", pod.Name),
@@ -151,72 +164,61 @@ func CheckCapabilities(ctx context.Context, request *admissionv1.AdmissionReques
 	))
 
 	span.SetAttributes(attribute.String("result", "allowed"))
-	return true
+	return nil
 }
 
-// isDisallowedCapability checks if a capability is in the list of disallowed capabilities
-func isDisallowedCapability(cap string, disallowedCapabilities []string) bool {
-	for _, disallowedCap := range disallowedCapabilities {
-		if cap == disallowedCap {
-			return true
-		}
+// capabilityList returns the Kubernetes spelling of the capabilities of a container
+func capabilityList(capabilities []corev1.Capability) []string {
+	names := make([]string, len(capabilities))
+	for i, capability := range capabilities {
+		names[i] = policy.CapabilityName(string(capability))
 	}
-	return false
+	return names
 }
 
-// hasDroppedAllRequiredCapabilities checks if all required capabilities are dropped
-func hasDroppedAllRequiredCapabilities(dropped []corev1.Capability, requiredDrops []string) bool {
-	requiredDropsMap := make(map[string]bool)
-	for _, cap := range requiredDrops {
-		requiredDropsMap[cap] = true
-	}
-
-	for _, cap := range dropped {
-		delete(requiredDropsMap, string(cap))
+// isDisallowedCapability checks if a capability is in the list of disallowed capabilities.
+// Adding ALL adds every disallowed capability.
+func isDisallowedCapability(cap string, disallowedCapabilities []string) bool {
+	if cap == policy.CapabilityAll {
+		return len(disallowedCapabilities) > 0
 	}
-
-	return len(requiredDropsMap) == 0
+	return policy.HasCapability(disallowedCapabilities, cap)
 }
 
-// getMissingRequiredDrops returns a string of capabilities that should have been dropped
-func getMissingRequiredDrops(dropped []corev1.Capability, requiredDrops []string) string {
-	requiredDropsMap := make(map[string]bool)
-	for _, cap := range requiredDrops {
-		requiredDropsMap[cap] = true
-	}
-
-	for _, cap := range dropped {
-		delete(requiredDropsMap, string(cap))
-	}
-
-	missing := ""
-	for cap := range requiredDropsMap {
-		if missing != "" {
-			missing += ", "
+// isAllowedCapability checks if a capability is in the allowlist. ALL is only allowed when the allowlist names it.
+func isAllowedCapability(cap string, allowedCapabilities []string) bool {
+	if cap == policy.CapabilityAll {
+		for _, allowed := range allowedCapabilities {
+			if policy.CapabilityName(allowed) == policy.CapabilityAll {
+				return true
+			}
 		}
-		missing += cap
+		return false
 	}
-
-	return missing
+	return policy.HasCapability(allowedCapabilities, cap)
 }
 
-// getCapabilities loads the capabilities policies from the configuration file
-func getCapabilities() (*Capabilities, error) {
-	configPath := os.Getenv("SECURITY_POLICIES_PATH")
-	if configPath == "" {
-		configPath = "configs/security-policies.yaml" // Default path
+// allowedCapabilities renders the allowlist for the expected value of violations
+func allowedCapabilities(allowedCapabilities []string) string {
+	if len(allowedCapabilities) == 0 {
+		return "no added capabilities"
 	}
+	return "one of " + strings.Join(policy.CapabilityNames(allowedCapabilities), ", ")
+}
 
-	data, err := os.ReadFile(configPath)
-	if err != nil {
-		return nil, err
+// getMissingRequiredDrops returns a string of capabilities that should have been dropped.
+// Dropping ALL drops every required capability.
+func getMissingRequiredDrops(dropped []string, requiredDrops []string) string {
+	if policy.HasCapability(dropped, policy.CapabilityAll) {
+		return ""
 	}
-
-	var policies SecurityPoliciesCap
-	err = yaml.Unmarshal(data, &policies)
-	if err != nil {
-		return nil, err
+	missing := make([]string, 0, len(requiredDrops))
+	for _, cap := range requiredDrops {
+		if !policy.HasCapability(dropped, cap) {
+			missing = append(missing, policy.CapabilityName(cap))
+		}
 	}
+	sort.Strings(missing)
 
-	return &policies.Policies.Capabilities, nil
+	return strings.Join(slices.Compact(missing), ", ")
 }
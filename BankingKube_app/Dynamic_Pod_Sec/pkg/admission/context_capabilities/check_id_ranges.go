@@ -0,0 +1,186 @@
+package context_capabilities
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/checks"
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/policy"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+	corev1 "k8s.io/api/core/v1"
+)
+
+var (
+	idTracer  = otel.Tracer("bankingkube/dynamicpodsec")
+	idMeter   = otel.Meter("bankingkube/dynamicpodsec")
+	idDenied  metric.Int64Counter
+	idAllowed metric.Int64Counter
+)
+
+func init() {
+	var err error
+	idDenied, err = idMeter.Int64Counter("id_ranges.denied")
+	if err != nil {
+		log.Println("Failed to create metric: id_ranges.denied")
+	}
+	idAllowed, err = idMeter.Int64Counter("id_ranges.allowed")
+	if err != nil {
+		log.Println("Failed to create metric: id_ranges.allowed")
+	}
+}
+
+// CheckIDRanges checks the user and group IDs of the pod against the runAsUser, runAsGroup, fsGroup and
+// supplementalGroups rules of the pod security context policies
+func CheckIDRanges(ctx context.Context, eval *checks.Evaluation) []checks.Violation {
+	ctx, span := idTracer.Start(ctx, "CheckIDRanges", trace.WithAttributes(
+		attribute.String("operation", string(eval.Request.Operation)),
+		attribute.String("resource", eval.Request.Resource.Resource),
+	))
+	defer span.End()
+
+	// Extract the pod, or the pod template of a workload, from the request
+	template, err := eval.PodTemplate()
+	if err != nil {
+		log.Println("Failed to parse pod object:", err)
+		span.SetAttributes(
+			attribute.String("error", "failed_to_parse_pod"),
+			attribute.String("result", "denied"),
+		)
+		span.RecordError(err)
+		return []checks.Violation{checks.ErrorViolation("failed_to_parse_pod", err)} // Fails the validation if the pod can't be parsed
+	}
+	pod := template.Pod
+
+	span.SetAttributes(
+		attribute.String("pod", pod.Name),
+		attribute.String("namespace", pod.Namespace),
+	)
+
+	// Retrieve the ID rules
+	policies, err := eval.Policies()
+	if err != nil {
+		log.Println("Failed to load ID range policies:", err)
+		span.SetAttributes(
+			attribute.String("error", "failed_to_load_policies"),
+			attribute.String("result", "denied"),
+		)
+		span.RecordError(err)
+		return []checks.Violation{checks.ErrorViolation("failed_to_load_policies", err)}
+	}
+	rules := &policies.PodSecurityContext
+
+	// Windows pods cannot set user and group IDs
+	if pod.Spec.OS != nil && pod.Spec.OS.Name == corev1.Windows {
+		span.SetAttributes(attribute.String("result", "allowed"))
+		return nil
+	}
+
+	var violations []checks.Violation
+
+	// deny records a violation of a container, or of the pod when ref is nil
+	deny := func(ref *checks.ContainerRef, reason, field, value, expected, message string, related ...string) {
+		log.Printf("Pod %s in namespace %s: %s\n", pod.Name, pod.Namespace, message)
+
+		var container string
+		if ref != nil {
+			container = ref.Container.Name
+		}
+		idDenied.Add(ctx, 1, metric.WithAttributes(
+			attribute.String("pod", pod.Name),
+			attribute.String("namespace", pod.Namespace),
+			attribute.String("container", container),
+			attribute.String("denial_reason", reason),
+		))
+
+		span.AddEvent("ID range violation", trace.WithAttributes(
+			attribute.String("container", container),
+			attribute.String("denial_reason", reason),
+		))
+
+		violations = append(violations, checks.Violation{
+			Reason:    reason,
+			Container: container,
+			Field:     field,
+			Related:   related,
+			Value:     value,
+			Expected:  expected,
+			Message:   message,
+		})
+	}
+
+	// checkID checks one ID; name is its field name and subject the container or the pod it belongs to
+	checkID := func(ref *checks.ContainerRef, subject, name, reason string, setting checks.Setting[int64], rule *policy.IDPolicy) {
+		if setting.Value == nil {
+			if rule.Rule == policy.IDRuleMustRunAs {
+				deny(ref, reason+"_unset", setting.Field, "unset", rule.Describe(),
+					fmt.Sprintf("%s does not set %s, which the policy requires", subject, name), setting.Related()...)
+			}
+			return
+		}
+		if !rule.Allows(*setting.Value) {
+			id := strconv.FormatInt(*setting.Value, 10)
+			deny(ref, reason+"_not_allowed", setting.Field, id, rule.Describe(),
+				fmt.Sprintf("%s runs with %s %s (set %s), which the policy does not allow", subject, name, id, setting.Origin()), setting.Related()...)
+		}
+	}
+
+	// The user and primary group of each container come from its effective security context
+	for _, ref := range checks.PodContainers(&pod.Spec, template.SpecPath) {
+		subject := fmt.Sprintf("%s container %q", ref.Type, ref.Container.Name)
+		sc := checks.EffectiveSecurityContext(&pod.Spec, template.SpecPath, ref)
+
+		checkID(&ref, subject, "runAsUser", "run_as_user", sc.RunAsUser, &rules.RunAsUser)
+		// Without a UID the image decides; MustRunAsNonRoot then needs runAsNonRoot, which the kubelet enforces
+		nonRoot := sc.RunAsNonRoot.Value != nil && *sc.RunAsNonRoot.Value
+		if rules.RunAsUser.Rule == policy.IDRuleMustRunAsNonRoot && sc.RunAsUser.Value == nil && !nonRoot {
+			deny(&ref, "run_as_user_unset", sc.RunAsUser.Field, "unset", "a non-zero runAsUser or runAsNonRoot set to true",
+				fmt.Sprintf("%s sets neither runAsUser nor runAsNonRoot, so it may run as root", subject),
+				append(sc.RunAsUser.Related(), sc.RunAsNonRoot.Fields...)...)
+		}
+		checkID(&ref, subject, "runAsGroup", "run_as_group", sc.RunAsGroup, &rules.RunAsGroup)
+	}
+
+	// fsGroup and the supplemental groups only exist on the pod
+	podSC := pod.Spec.SecurityContext
+	if podSC == nil {
+		podSC = &corev1.PodSecurityContext{}
+	}
+	podPath := template.SpecPath + ".securityContext."
+	fsGroup := checks.Setting[int64]{Value: podSC.FSGroup, Level: checks.LevelPod, Field: podPath + "fsGroup"}
+	if podSC.FSGroup == nil {
+		fsGroup.Level = checks.LevelDefault
+	}
+	checkID(nil, "pod", "fsGroup", "fs_group", fsGroup, &rules.FSGroup)
+
+	if len(podSC.SupplementalGroups) == 0 && rules.SupplementalGroups.Rule == policy.IDRuleMustRunAs {
+		deny(nil, "supplemental_groups_unset", podPath+"supplementalGroups", "unset", rules.SupplementalGroups.Describe(),
+			"pod does not set supplementalGroups, which the policy requires")
+	}
+	for i := range podSC.SupplementalGroups {
+		group := checks.Setting[int64]{Value: &podSC.SupplementalGroups[i], Level: checks.LevelPod, Field: fmt.Sprintf("%ssupplementalGroups[%d]", podPath, i)}
+		checkID(nil, "pod", "supplemental group", "supplemental_groups", group, &rules.SupplementalGroups)
+	}
+
+	if len(violations) > 0 {
+		span.SetAttributes(
+			attribute.String("result", "denied"),
+			attribute.Int("violation_count", len(violations)),
+		)
+		return violations
+	}
+
+	// Passes the check if every ID complies with the policies
+	idAllowed.Add(ctx, 1, metric.WithAttributes(
+		attribute.String("pod", pod.Name),
+		attribute.String("namespace", pod.Namespace),
+	))
+
+	span.SetAttributes(attribute.String("result", "allowed"))
+	return nil
+}
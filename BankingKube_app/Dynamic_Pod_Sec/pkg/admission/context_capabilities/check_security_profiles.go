@@ -0,0 +1,220 @@
+package context_capabilities
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/checks"
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/policy"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+	corev1 "k8s.io/api/core/v1"
+)
+
+var (
+	spTracer  = otel.Tracer("bankingkube/dynamicpodsec")
+	spMeter   = otel.Meter("bankingkube/dynamicpodsec")
+	spDenied  metric.Int64Counter
+	spAllowed metric.Int64Counter
+)
+
+func init() {
+	var err error
+	spDenied, err = spMeter.Int64Counter("security_profiles.denied")
+	if err != nil {
+		log.Println("Failed to create metric: security_profiles.denied")
+	}
+	spAllowed, err = spMeter.Int64Counter("security_profiles.allowed")
+	if err != nil {
+		log.Println("Failed to create metric: security_profiles.allowed")
+	}
+}
+
+// CheckSecurityProfiles checks the seccomp and AppArmor profiles and the SELinux options of every container
+// against the seccomp, appArmor and seLinux policies
+func CheckSecurityProfiles(ctx context.Context, eval *checks.Evaluation) []checks.Violation {
+	ctx, span := spTracer.Start(ctx, "CheckSecurityProfiles", trace.WithAttributes(
+		attribute.String("operation", string(eval.Request.Operation)),
+		attribute.String("resource", eval.Request.Resource.Resource),
+	))
+	defer span.End()
+
+	// Extract the pod, or the pod template of a workload, from the request
+	template, err := eval.PodTemplate()
+	if err != nil {
+		log.Println("Failed to parse pod object:", err)
+		span.SetAttributes(
+			attribute.String("error", "failed_to_parse_pod"),
+			attribute.String("result", "denied"),
+		)
+		span.RecordError(err)
+		return []checks.Violation{checks.ErrorViolation("failed_to_parse_pod", err)} // Fails the validation if the pod can't be parsed
+	}
+	pod := template.Pod
+
+	span.SetAttributes(
+		attribute.String("pod", pod.Name),
+		attribute.String("namespace", pod.Namespace),
+	)
+
+	// Retrieve the security profile policies
+	policies, err := eval.Policies()
+	if err != nil {
+		log.Println("Failed to load security profile policies:", err)
+		span.SetAttributes(
+			attribute.String("error", "failed_to_load_policies"),
+			attribute.String("result", "denied"),
+		)
+		span.RecordError(err)
+		return []checks.Violation{checks.ErrorViolation("failed_to_load_policies", err)}
+	}
+	rules := &policies.PodSecurityContext
+
+	// Windows pods have no seccomp, AppArmor or SELinux
+	if pod.Spec.OS != nil && pod.Spec.OS.Name == corev1.Windows {
+		span.SetAttributes(attribute.String("result", "allowed"))
+		return nil
+	}
+
+	var violations []checks.Violation
+
+	// deny records a violation for a container together with its log line, metric and span event
+	deny := func(ref checks.ContainerRef, reason, field, value, expected, message string, related ...string) {
+		log.Printf("Pod %s in namespace %s: %s\n", pod.Name, pod.Namespace, message)
+
+		spDenied.Add(ctx, 1, metric.WithAttributes(
+			attribute.String("pod", pod.Name),
+			attribute.String("namespace", pod.Namespace),
+			attribute.String("container", ref.Container.Name),
+			attribute.String("container_type", ref.Type),
+			attribute.String("denial_reason", reason),
+		))
+
+		span.AddEvent("Security profile violation", trace.WithAttributes(
+			attribute.String("container", ref.Container.Name),
+			attribute.String("container_type", ref.Type),
+			attribute.String("denial_reason", reason),
+		))
+
+		violations = append(violations, checks.Violation{
+			Reason:    reason,
+			Container: ref.Container.Name,
+			Field:     field,
+			Related:   related,
+			Value:     value,
+			Expected:  expected,
+			Message:   message,
+		})
+	}
+
+	for _, ref := range checks.PodContainers(&pod.Spec, template.SpecPath) {
+		subject := fmt.Sprintf("%s container %q", ref.Type, ref.Container.Name)
+		sc := checks.EffectiveSecurityContext(&pod.Spec, template.SpecPath, ref)
+
+		// A container without a seccomp profile runs unconfined
+		seccomp := policy.ProfileUnconfined
+		if profile := sc.SeccompProfile.Value; profile != nil {
+			seccomp = profileName(string(profile.Type), profile.LocalhostProfile)
+		}
+		if !rules.Seccomp.Allows(seccomp) {
+			deny(ref, "seccomp_profile_not_allowed", sc.SeccompProfile.Field, seccomp, "one of "+strings.Join(rules.Seccomp.AllowedProfiles, ", "),
+				fmt.Sprintf("%s runs with seccomp profile %s (set %s), which the policy does not allow", subject, seccomp, sc.SeccompProfile.Origin()), sc.SeccompProfile.Related()...)
+		}
+
+		// A container without an AppArmor profile runs with the runtime default
+		appArmor := appArmorProfile(template.Pod, template.MetadataPath, ref, sc.AppArmorProfile)
+		appArmorName := policy.ProfileRuntimeDefault
+		if profile := appArmor.Value; profile != nil {
+			appArmorName = profileName(string(profile.Type), profile.LocalhostProfile)
+		}
+		if !rules.AppArmor.Allows(appArmorName) {
+			deny(ref, "apparmor_profile_not_allowed", appArmor.Field, appArmorName, "one of "+strings.Join(rules.AppArmor.AllowedProfiles, ", "),
+				fmt.Sprintf("%s runs with AppArmor profile %s (set %s), which the policy does not allow", subject, appArmorName, appArmor.Origin()), appArmor.Related()...)
+		}
+
+		// An unset SELinux type or level is left to the container runtime
+		if options := sc.SELinuxOptions.Value; options != nil {
+			if !rules.SELinux.AllowsType(options.Type) {
+				deny(ref, "selinux_type_not_allowed", sc.SELinuxOptions.Field+".type", options.Type, "one of "+strings.Join(rules.SELinux.AllowedTypes, ", "),
+					fmt.Sprintf("%s runs with SELinux type %s (set %s), which the policy does not allow", subject, options.Type, sc.SELinuxOptions.Origin()), suffixed(sc.SELinuxOptions.Related(), ".type")...)
+			}
+			if !rules.SELinux.AllowsLevel(options.Level) {
+				deny(ref, "selinux_level_not_allowed", sc.SELinuxOptions.Field+".level", options.Level, "one of "+strings.Join(rules.SELinux.AllowedLevels, ", "),
+					fmt.Sprintf("%s runs with SELinux level %s (set %s), which the policy does not allow", subject, options.Level, sc.SELinuxOptions.Origin()), suffixed(sc.SELinuxOptions.Related(), ".level")...)
+			}
+		}
+	}
+
+	if len(violations) > 0 {
+		span.SetAttributes(
+			attribute.String("result", "denied"),
+			attribute.Int("violation_count", len(violations)),
+		)
+		return violations
+	}
+
+	// Passes the check if every container runs with allowed profiles
+	spAllowed.Add(ctx, 1, metric.WithAttributes(
+		attribute.String("pod", pod.Name),
+		attribute.String("namespace", pod.Namespace),
+	))
+
+	span.SetAttributes(attribute.String("result", "allowed"))
+	return nil
+}
+
+// profileName renders a seccomp or AppArmor profile the way the policies list it, e.g. Localhost/k8s-nginx
+func profileName(profileType string, localhostProfile *string) string {
+	if profileType == string(corev1.SeccompProfileTypeLocalhost) && localhostProfile != nil {
+		return policy.ProfileLocalhostPrefix + *localhostProfile
+	}
+	return profileType
+}
+
+// appArmorProfile returns the AppArmor profile of a container. The container's field wins over its
+// annotation, which wins over the pod's field, as in the kubelet.
+func appArmorProfile(pod *corev1.Pod, metadataPath string, ref checks.ContainerRef, effective checks.Setting[corev1.AppArmorProfile]) checks.Setting[corev1.AppArmorProfile] {
+	if effective.Level == checks.LevelContainer {
+		return effective
+	}
+	annotation := corev1.DeprecatedAppArmorBetaContainerAnnotationKeyPrefix + ref.Container.Name
+	annotationField := fmt.Sprintf("%s.annotations[%s]", metadataPath, annotation)
+	// Setting the annotation would override the inherited or default profile
+	fields := append([]string{annotationField}, effective.Fields...)
+	value, ok := pod.Annotations[annotation]
+	if !ok {
+		effective.Fields = fields
+		return effective
+	}
+
+	profile := &corev1.AppArmorProfile{Type: corev1.AppArmorProfileType(value)}
+	switch {
+	case value == corev1.DeprecatedAppArmorBetaProfileRuntimeDefault:
+		profile.Type = corev1.AppArmorProfileTypeRuntimeDefault
+	case value == corev1.DeprecatedAppArmorBetaProfileNameUnconfined:
+		profile.Type = corev1.AppArmorProfileTypeUnconfined
+	case strings.HasPrefix(value, corev1.DeprecatedAppArmorBetaProfileNamePrefix):
+		name := strings.TrimPrefix(value, corev1.DeprecatedAppArmorBetaProfileNamePrefix)
+		profile.Type, profile.LocalhostProfile = corev1.AppArmorProfileTypeLocalhost, &name
+	}
+	return checks.Setting[corev1.AppArmorProfile]{
+		Value:  profile,
+		Level:  checks.LevelContainer,
+		Field:  annotationField,
+		Fields: fields,
+	}
+}
+
+// suffixed appends suffix to every field, e.g. the .type of each seLinuxOptions a value is resolved from
+func suffixed(fields []string, suffix string) []string {
+	result := make([]string, len(fields))
+	for i, field := range fields {
+		result[i] = field + suffix
+	}
+	return result
+}
@@ -0,0 +1,16 @@
+package context_capabilities
+
+import (
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/checks"
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/workload"
+)
+
+// Group is the check group served on /validate/context
+const Group = "context"
+
+func init() {
+	checks.Register(Group, checks.NewCheck("pod_security_context", workload.Kinds, CheckPodSecurityContext))
+	checks.Register(Group, checks.NewCheck("capabilities", workload.Kinds, CheckCapabilities))
+	checks.Register(Group, checks.NewCheck("id_ranges", workload.Kinds, CheckIDRanges))
+	checks.Register(Group, checks.NewCheck("security_profiles", workload.Kinds, CheckSecurityProfiles))
+}
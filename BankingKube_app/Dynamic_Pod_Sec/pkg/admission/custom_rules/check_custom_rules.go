@@ -0,0 +1,163 @@
+package custom_rules
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/checks"
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/policy"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	ruleTracer  = otel.Tracer("bankingkube/dynamicpodsec")
+	ruleMeter   = otel.Meter("bankingkube/dynamicpodsec")
+	ruleDenied  metric.Int64Counter
+	ruleAllowed metric.Int64Counter
+)
+
+func init() {
+	var err error
+	ruleDenied, err = ruleMeter.Int64Counter("custom_rules.denied")
+	if err != nil {
+		log.Println("Failed to create metric: custom_rules.denied")
+	}
+	ruleAllowed, err = ruleMeter.Int64Counter("custom_rules.allowed")
+	if err != nil {
+		log.Println("Failed to create metric: custom_rules.allowed")
+	}
+}
+
+// Rules returns a check for every custom rule of the request's policies. When the policies cannot
+// be loaded it returns a single custom_rules check that reports the error, so the request is not
+// silently admitted without its rules.
+func Rules(eval *checks.Evaluation) []checks.Check {
+	policies, err := eval.Policies()
+	if err != nil {
+		log.Println("Failed to load custom rules:", err)
+		return []checks.Check{checks.NewCheck("custom_rules", nil, checks.WithoutContext(func(*checks.Evaluation) []checks.Violation {
+			return []checks.Violation{checks.ErrorViolation("failed_to_load_policies", err)}
+		}))}
+	}
+
+	list := make([]checks.Check, 0, len(policies.CustomRules))
+	for _, rule := range policies.CustomRules {
+		list = append(list, checks.NewCheck(rule.Name, rule.Kinds, CheckRule(rule), checks.InMode(rule.Mode)))
+	}
+	return list
+}
+
+// CheckRule returns the check function of a custom rule: the object passes when the rule's
+// expression evaluates to true
+func CheckRule(rule policy.CustomRule) checks.CheckFunc {
+	return func(ctx context.Context, eval *checks.Evaluation) []checks.Violation {
+		ctx, span := ruleTracer.Start(ctx, "CheckRule", trace.WithAttributes(
+			attribute.String("rule", rule.Name),
+			attribute.String("operation", string(eval.Request.Operation)),
+			attribute.String("resource", eval.Request.Resource.Resource),
+		))
+		defer span.End()
+
+		// Compiled when the policies were loaded, so this is a cache hit
+		program, err := rule.Program()
+		if err != nil {
+			span.RecordError(err)
+			return []checks.Violation{checks.ErrorViolation("failed_to_compile_rule", err)}
+		}
+
+		vars, err := variables(eval)
+		if err != nil {
+			log.Printf("Failed to prepare custom rule %s: %v\n", rule.Name, err)
+			span.RecordError(err)
+			return []checks.Violation{checks.ErrorViolation("failed_to_parse_object", err)}
+		}
+
+		out, _, err := program.ContextEval(ctx, vars)
+		if err != nil {
+			log.Printf("Failed to evaluate custom rule %s: %v\n", rule.Name, err)
+			span.RecordError(err)
+			return []checks.Violation{checks.ErrorViolation("failed_to_evaluate_rule", err)}
+		}
+		passed, ok := out.Value().(bool)
+		if !ok {
+			err := fmt.Errorf("expression evaluated to %v (%s), not a bool", out.Value(), out.Type().TypeName())
+			span.RecordError(err)
+			return []checks.Violation{checks.ErrorViolation("failed_to_evaluate_rule", err)}
+		}
+
+		attributes := metric.WithAttributes(
+			attribute.String("rule", rule.Name),
+			attribute.String("kind", eval.Request.Kind.Kind),
+			attribute.String("namespace", eval.Request.Namespace),
+		)
+		if passed {
+			ruleAllowed.Add(ctx, 1, attributes)
+			span.SetAttributes(attribute.String("result", "allowed"))
+			return nil
+		}
+
+		log.Printf("%s %s/%s violates custom rule %s\n", eval.Request.Kind.Kind, eval.Request.Namespace, eval.Request.Name, rule.Name)
+		ruleDenied.Add(ctx, 1, attributes)
+		span.SetAttributes(attribute.String("result", "denied"))
+		return []checks.Violation{{
+			Reason:   "custom_rule",
+			Expected: rule.Expression,
+			Message:  rule.Message,
+		}}
+	}
+}
+
+// variables binds the variables of a rule to the request. The namespace is looked up only when
+// the expression reads its labels.
+func variables(eval *checks.Evaluation) (map[string]interface{}, error) {
+	object, oldObject, err := eval.Unstructured()
+	if err != nil {
+		return nil, err
+	}
+	request, err := requestAttributes(eval)
+	if err != nil {
+		return nil, err
+	}
+
+	vars := map[string]interface{}{
+		"object":    object,
+		"oldObject": nil,
+		"request":   request,
+		"namespaceLabels": func() ref.Val {
+			labels, err := eval.NamespaceLabels()
+			if err != nil {
+				return types.NewErr("%v", err)
+			}
+			return types.DefaultTypeAdapter.NativeToValue(labels)
+		},
+	}
+	if oldObject != nil {
+		vars["oldObject"] = oldObject
+	}
+	return vars, nil
+}
+
+// requestAttributes returns the admission request without its objects, keyed as in the AdmissionReview
+// (e.g. request.userInfo.username, request.operation)
+func requestAttributes(eval *checks.Evaluation) (map[string]interface{}, error) {
+	request := *eval.Request
+	request.Object.Raw, request.OldObject.Raw, request.Options.Raw = nil, nil, nil
+	request.Object.Object, request.OldObject.Object, request.Options.Object = nil, nil, nil
+
+	data, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	var attributes map[string]interface{}
+	if err := json.Unmarshal(data, &attributes); err != nil {
+		return nil, err
+	}
+	return attributes, nil
+}
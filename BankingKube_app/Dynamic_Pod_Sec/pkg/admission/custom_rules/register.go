@@ -0,0 +1,12 @@
+package custom_rules
+
+import (
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/checks"
+)
+
+// Group is the check group served on /validate/custom
+const Group = "custom"
+
+func init() {
+	checks.RegisterProvider(Group, Rules)
+}
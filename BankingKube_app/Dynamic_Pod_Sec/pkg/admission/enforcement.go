@@ -0,0 +1,247 @@
+package admission
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/checks"
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/policy"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	admissionv1 "k8s.io/api/admission/v1"
+)
+
+var (
+	enforcementMeter   = otel.Meter("bankingkube/dynamicpodsec")
+	violationsObserved metric.Int64Counter
+	checksUnfinished   metric.Int64Counter
+	internalErrors     metric.Int64Counter
+	exemptionsApplied  metric.Int64Counter
+)
+
+func init() {
+	var err error
+	violationsObserved, err = enforcementMeter.Int64Counter("admission.violations")
+	if err != nil {
+		log.Println("Failed to create metric: admission.violations")
+	}
+	checksUnfinished, err = enforcementMeter.Int64Counter("admission.checks_unfinished")
+	if err != nil {
+		log.Println("Failed to create metric: admission.checks_unfinished")
+	}
+	internalErrors, err = enforcementMeter.Int64Counter("admission.internal_errors")
+	if err != nil {
+		log.Println("Failed to create metric: admission.internal_errors")
+	}
+	exemptionsApplied, err = enforcementMeter.Int64Counter("admission.exemptions_applied")
+	if err != nil {
+		log.Println("Failed to create metric: admission.exemptions_applied")
+	}
+}
+
+// decision accumulates the outcome of a group's checks according to their enforcement modes
+type decision struct {
+	denied           []checks.Violation
+	warnings         []string
+	auditAnnotations map[string]string
+	exemptions       []exemptionRecord
+}
+
+// exemptionRecord is an exemption applied to the violations of a check, as written to the exemptions audit annotation
+type exemptionRecord struct {
+	Check         string             `json:"check"`
+	Exemption     string             `json:"exemption"`
+	Owner         string             `json:"owner"`
+	Justification string             `json:"justification"`
+	Expires       policy.Date        `json:"expires"`
+	Violations    []checks.Violation `json:"violations"`
+}
+
+// exempt files violations lifted by an exemption. They are logged, counted and recorded in the
+// exemptions audit annotation, but never deny the request.
+func (d *decision) exempt(ctx context.Context, group string, check checks.Check, exemption *policy.Exemption, request *admissionv1.AdmissionRequest, violations []checks.Violation) {
+	name := check.Name()
+	log.Printf("Exemption %s (owner %s, expires %s) lifts %d %s violation(s) for %s %s/%s\n",
+		exemption.Name, exemption.Owner, exemption.Expires.Format(time.DateOnly), len(violations), name, request.Kind.Kind, request.Namespace, request.Name)
+
+	exemptionsApplied.Add(ctx, int64(len(violations)), metric.WithAttributes(
+		attribute.String("group", group),
+		attribute.String("check", name),
+		attribute.String("exemption", exemption.Name),
+		attribute.String("namespace", request.Namespace),
+	))
+
+	d.exemptions = append(d.exemptions, exemptionRecord{
+		Check:         name,
+		Exemption:     exemption.Name,
+		Owner:         exemption.Owner,
+		Justification: exemption.Justification,
+		Expires:       exemption.Expires,
+		Violations:    violations,
+	})
+}
+
+// applyExemptions files the violations an active exemption lifts and returns the others.
+// A violation only matched by an expired exemption stays, with a warning naming the exemption.
+func (d *decision) applyExemptions(ctx context.Context, group string, check checks.Check, eval *checks.Evaluation, exemptions []policy.Exemption, violations []checks.Violation) []checks.Violation {
+	if len(exemptions) == 0 {
+		return violations
+	}
+	now := time.Now()
+	var remaining []checks.Violation
+	lifted := make(map[*policy.Exemption][]checks.Violation)
+	var order []*policy.Exemption
+	warned := make(map[*policy.Exemption]bool)
+	for _, v := range violations {
+		exemption := eval.Exemption(v, exemptions, now)
+		switch {
+		case exemption == nil:
+			remaining = append(remaining, v)
+		case exemption.Expired(now):
+			if !warned[exemption] {
+				warned[exemption] = true
+				d.warnings = append(d.warnings, fmt.Sprintf("[%s] exemption %s (owner %s) expired on %s and no longer applies",
+					v.CheckID, exemption.Name, exemption.Owner, exemption.Expires.Format(time.DateOnly)))
+			}
+			remaining = append(remaining, v)
+		default:
+			if _, ok := lifted[exemption]; !ok {
+				order = append(order, exemption)
+			}
+			lifted[exemption] = append(lifted[exemption], v)
+		}
+	}
+	for _, exemption := range order {
+		d.exempt(ctx, group, check, exemption, eval.Request, lifted[exemption])
+	}
+	return remaining
+}
+
+// finish writes the applied exemptions into the audit annotations
+func (d *decision) finish() {
+	if len(d.exemptions) == 0 {
+		return
+	}
+	encoded, err := json.Marshal(d.exemptions)
+	if err != nil {
+		log.Println("Failed to encode exemptions annotation:", err)
+		return
+	}
+	if d.auditAnnotations == nil {
+		d.auditAnnotations = make(map[string]string)
+	}
+	d.auditAnnotations["exemptions"] = string(encoded)
+}
+
+// unfinished files a check that did not finish in time. Failing closed turns it into a violation
+// handled under the check's enforcement mode; failing open allows the request with a warning.
+// Either way the check is named in the warnings and in the unfinished_checks audit annotation.
+func (d *decision) unfinished(ctx context.Context, group string, outcome checks.Outcome, mode policy.Mode, failurePolicy policy.FailurePolicy, request *admissionv1.AdmissionRequest) {
+	name := outcome.Check.Name()
+	log.Printf("Check %s did not finish for %s %s/%s after %s (failing %s): %v\n",
+		name, request.Kind.Kind, request.Namespace, request.Name, outcome.Duration.Round(time.Millisecond), failurePolicy, outcome.Err)
+
+	checksUnfinished.Add(ctx, 1, metric.WithAttributes(
+		attribute.String("group", group),
+		attribute.String("check", name),
+		attribute.String("failure_policy", string(failurePolicy)),
+		attribute.String("namespace", request.Namespace),
+	))
+
+	d.annotateCheck("unfinished_checks", name)
+
+	if failurePolicy == policy.FailOpen {
+		d.warnings = append(d.warnings, fmt.Sprintf("[%s] check did not finish and was skipped (fail open): %v", name, outcome.Err))
+		return
+	}
+	d.record(ctx, group, outcome.Check, mode, request, []checks.Violation{{
+		CheckID:  name,
+		Reason:   "check_unfinished",
+		Expected: "the check to finish within its time budget",
+		Message:  fmt.Sprintf("check did not finish and the request fails closed: %v", outcome.Err),
+	}})
+}
+
+// internalErrors files the internal errors of a check. The deny error policy turns them into violations
+// handled under the check's enforcement mode; warn allows the request with a warning.
+// Either way the check is named in the check_errors audit annotation.
+func (d *decision) internalErrors(ctx context.Context, group string, check checks.Check, mode policy.Mode, errorPolicy policy.ErrorPolicy, request *admissionv1.AdmissionRequest, errs []checks.Violation) {
+	name := check.Name()
+	for _, e := range errs {
+		log.Printf("Check %s failed with an internal error for %s %s/%s (error policy %s): %s\n",
+			name, request.Kind.Kind, request.Namespace, request.Name, errorPolicy, e.Message)
+
+		internalErrors.Add(ctx, 1, metric.WithAttributes(
+			attribute.String("group", group),
+			attribute.String("check", name),
+			attribute.String("reason", e.Reason),
+			attribute.String("error_policy", string(errorPolicy)),
+			attribute.String("namespace", request.Namespace),
+		))
+	}
+
+	d.annotateCheck("check_errors", name)
+
+	if errorPolicy == policy.ErrorPolicyWarn {
+		for _, e := range errs {
+			d.warnings = append(d.warnings, fmt.Sprintf("[%s] check failed with an internal error and was skipped: %s", name, e.Message))
+		}
+		return
+	}
+	d.record(ctx, group, check, mode, request, errs)
+}
+
+// annotateCheck appends a check name to a comma separated audit annotation
+func (d *decision) annotateCheck(key, name string) {
+	if d.auditAnnotations == nil {
+		d.auditAnnotations = make(map[string]string)
+	}
+	if previous := d.auditAnnotations[key]; previous != "" {
+		d.auditAnnotations[key] = previous + "," + name
+	} else {
+		d.auditAnnotations[key] = name
+	}
+}
+
+// record files the violations of a check under its enforcement mode
+func (d *decision) record(ctx context.Context, group string, check checks.Check, mode policy.Mode, request *admissionv1.AdmissionRequest, violations []checks.Violation) {
+	for _, v := range violations {
+		violationsObserved.Add(ctx, 1, metric.WithAttributes(
+			attribute.String("group", group),
+			attribute.String("check", v.CheckID),
+			attribute.String("reason", v.Reason),
+			attribute.String("mode", string(mode)),
+			attribute.String("namespace", request.Namespace),
+		))
+	}
+
+	switch mode {
+	case policy.ModeWarn:
+		for _, v := range violations {
+			d.warnings = append(d.warnings, v.String())
+		}
+	case policy.ModeAudit:
+		for _, v := range violations {
+			log.Printf("Audit: %s %s/%s would be denied: %s\n", request.Kind.Kind, request.Namespace, request.Name, v)
+		}
+		// The API server prefixes the key with the webhook name when writing the audit event
+		encoded, err := json.Marshal(violations)
+		if err != nil {
+			log.Println("Failed to encode audit annotation:", err)
+			return
+		}
+		if d.auditAnnotations == nil {
+			d.auditAnnotations = make(map[string]string)
+		}
+		d.auditAnnotations[check.Name()] = string(encoded)
+	case policy.ModeDryRun:
+		// Evaluated for the metrics only
+	default:
+		d.denied = append(d.denied, violations...)
+	}
+}
@@ -0,0 +1,86 @@
+package admission
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"testing"
+
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/checks"
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/policy"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// podGroups are the check groups the validating webhook configuration sends pods to
+var podGroups = []string{"api", "context", "image", "network", "resources", "volumes"}
+
+// manyContainerPodRequest builds the admission request of a pod with n containers and n init containers
+func manyContainerPodRequest(b *testing.B, n int) *admissionv1.AdmissionRequest {
+	b.Helper()
+	pod := &corev1.Pod{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+		ObjectMeta: metav1.ObjectMeta{Name: "batch", Namespace: "payments"},
+	}
+	for i := 0; i < n; i++ {
+		container := corev1.Container{
+			Name:  fmt.Sprintf("worker-%d", i),
+			Image: fmt.Sprintf("myregistry.com/worker:%d.0", i),
+		}
+		pod.Spec.Containers = append(pod.Spec.Containers, container)
+		container.Name = fmt.Sprintf("init-%d", i)
+		pod.Spec.InitContainers = append(pod.Spec.InitContainers, container)
+	}
+	raw, err := json.Marshal(pod)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return &admissionv1.AdmissionRequest{
+		Name:      pod.Name,
+		Namespace: pod.Namespace,
+		Kind:      metav1.GroupVersionKind{Version: "v1", Kind: "Pod"},
+		Resource:  metav1.GroupVersionResource{Version: "v1", Resource: "pods"},
+		Operation: admissionv1.Create,
+		Object:    runtime.RawExtension{Raw: raw},
+	}
+}
+
+// BenchmarkEvaluation compares one shared evaluation per request with decoding the pod
+// and reading the policy file in every check, which is what each check used to do
+func BenchmarkEvaluation(b *testing.B) {
+	const path = "../../configs/security-policies.yaml"
+	b.Setenv("SECURITY_POLICIES_PATH", path)
+	log.SetOutput(io.Discard)
+	b.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	ctx := context.Background()
+	for _, n := range []int{1, 10, 50} {
+		request := manyContainerPodRequest(b, n)
+
+		b.Run(fmt.Sprintf("containers=%d/shared", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				for _, group := range podGroups {
+					validate(ctx, checks.Default, group, checks.NewEvaluation(request, policy.LoadDefault))
+				}
+			}
+		})
+
+		b.Run(fmt.Sprintf("containers=%d/per_check", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				for _, group := range podGroups {
+					for _, check := range checks.Default.Checks(group, "Pod") {
+						// policy.LoadDefault serves a cached snapshot, so read the file as the old loaders did
+						check.Validate(ctx, checks.NewEvaluation(request, func() (*policy.SecurityPolicies, error) { return policy.Load(path) }))
+					}
+				}
+			}
+		})
+	}
+}
@@ -2,19 +2,15 @@ package image_security
 
 import (
 	"context"
-	"encoding/json"
+	"fmt"
 	"log"
-	"os"
 	"strings"
 
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/checks"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/metric"
 	"go.opentelemetry.io/otel/trace"
-
-	"gopkg.in/yaml.v2"
-	admissionv1 "k8s.io/api/admission/v1"
-	corev1 "k8s.io/api/core/v1"
 )
 
 var (
@@ -36,29 +32,16 @@ func init() {
 	}
 }
 
-// ImageSecurity defines a structure for image security policies
-type ImageSecurity struct {
-	AllowedRegistries []string `yaml:"allowedRegistries"`
-}
-
-// SecurityPoliciesImg represents the structure of the security-policies.yaml file
-type SecurityPoliciesImg struct {
-	Policies struct {
-		ImageSecurity ImageSecurity `yaml:"imageSecurity"`
-	} `yaml:"policies"`
-}
-
 // CheckImageRegistry validates if a pod is using images from allowed registries
-func CheckImageRegistry(ctx context.Context, request *admissionv1.AdmissionRequest) bool {
+func CheckImageRegistry(ctx context.Context, eval *checks.Evaluation) []checks.Violation {
 	ctx, span := imgTracer.Start(ctx, "CheckImageRegistry", trace.WithAttributes(
-		attribute.String("operation", string(request.Operation)),
-		attribute.String("resource", request.Resource.Resource),
+		attribute.String("operation", string(eval.Request.Operation)),
+		attribute.String("resource", eval.Request.Resource.Resource),
 	))
 	defer span.End()
 
-	// Parse the Pod object from the request
-	pod := &corev1.Pod{}
-	err := json.Unmarshal(request.Object.Raw, pod)
+	// Extract the pod, or the pod template of a workload, from the request
+	template, err := eval.PodTemplate()
 	if err != nil {
 		log.Println("Failed to parse pod object:", err)
 		span.SetAttributes(
@@ -66,8 +49,9 @@ func CheckImageRegistry(ctx context.Context, request *admissionv1.AdmissionReque
 			attribute.String("result", "denied"),
 		)
 		span.RecordError(err)
-		return false // Fails the validation if the pod can't be parsed
+		return []checks.Violation{checks.ErrorViolation("failed_to_parse_pod", err)} // Fails the validation if the pod can't be parsed
 	}
+	pod := template.Pod
 
 	span.SetAttributes(
 		attribute.String("pod", pod.Name),
@@ -75,7 +59,7 @@ func CheckImageRegistry(ctx context.Context, request *admissionv1.AdmissionReque
 	)
 
 	// Retrieve the allowed registries
-	imageSecurity, err := getImageSecurity()
+	policies, err := eval.Policies()
 	if err != nil {
 		log.Println("Failed to load image security policies:", err)
 		span.SetAttributes(
@@ -83,97 +67,56 @@ func CheckImageRegistry(ctx context.Context, request *admissionv1.AdmissionReque
 			attribute.String("result", "denied"),
 		)
 		span.RecordError(err)
-		return false
+		return []checks.Violation{checks.ErrorViolation("failed_to_load_policies", err)}
 	}
+	imageSecurity := &policies.ImageSecurity
+
+	var violations []checks.Violation
 
-	// Check if the pod's containers are using images from allowed registries
-	for _, container := range pod.Spec.Containers {
+	// Check if the pod's containers, init containers and ephemeral containers use images from allowed registries
+	for _, ref := range checks.PodContainers(&pod.Spec, template.SpecPath) {
+		container := ref.Container
 		span.AddEvent("Checking container image", trace.WithAttributes(
 			attribute.String("container", container.Name),
+			attribute.String("container_type", ref.Type),
 			attribute.String("image", container.Image),
 		))
 
 		if !isImageFromAllowedRegistry(container.Image, imageSecurity.AllowedRegistries) {
-			log.Printf("Pod %s in namespace %s is using an image from a disallowed registry: %s\n",
-				pod.Name, pod.Namespace, container.Image)
+			log.Printf("Pod %s in namespace %s is using an image from a disallowed registry in %s container %s: %s\n",
+				pod.Name, pod.Namespace, ref.Type, container.Name, container.Image)
 
 			imgDenied.Add(ctx, 1, metric.WithAttributes(
 				attribute.String("pod", pod.Name),
 				attribute.String("namespace", pod.Namespace),
 				attribute.String("container", container.Name),
+				attribute.String("container_type", ref.Type),
 				attribute.String("image", container.Image),
 				attribute.String("denial_reason", "disallowed_registry"),
 			))
 
-			span.SetAttributes(
+			span.AddEvent("Disallowed registry", trace.WithAttributes(
 				attribute.String("container", container.Name),
 				attribute.String("image", container.Image),
-				attribute.String("result", "denied"),
-				attribute.String("denial_reason", "disallowed_registry"),
-			)
-
-			return false
-		}
-	}
-
-	// Check init containers as well
-	for _, initContainer := range pod.Spec.InitContainers {
-		span.AddEvent("Checking init container image", trace.WithAttributes(
-			attribute.String("init_container", initContainer.Name),
-			attribute.String("image", initContainer.Image),
-		))
-
-		if !isImageFromAllowedRegistry(initContainer.Image, imageSecurity.AllowedRegistries) {
-			log.Printf("Pod %s in namespace %s is using an init container image from a disallowed registry: %s\n",
-				pod.Name, pod.Namespace, initContainer.Image)
-
-			imgDenied.Add(ctx, 1, metric.WithAttributes(
-				attribute.String("pod", pod.Name),
-				attribute.String("namespace", pod.Namespace),
-				attribute.String("init_container", initContainer.Name),
-				attribute.String("image", initContainer.Image),
-				attribute.String("denial_reason", "disallowed_registry"),
 			))
 
-			span.SetAttributes(
-				attribute.String("init_container", initContainer.Name),
-				attribute.String("image", initContainer.Image),
-				attribute.String("result", "denied"),
-				attribute.String("denial_reason", "disallowed_registry"),
-			)
-
-			return false
+			violations = append(violations, checks.Violation{
+				Reason:    "disallowed_registry",
+				Container: container.Name,
+				Field:     ref.Path + ".image",
+				Value:     container.Image,
+				Expected:  "one of " + strings.Join(imageSecurity.AllowedRegistries, ", "),
+				Message:   fmt.Sprintf("%s container %q uses image from disallowed registry %s", ref.Type, container.Name, extractRegistry(container.Image)),
+			})
 		}
 	}
 
-	// Check ephemeral containers if they exist
-	for _, ephemeralContainer := range pod.Spec.EphemeralContainers {
-		span.AddEvent("Checking ephemeral container image", trace.WithAttributes(
-			attribute.String("ephemeral_container", ephemeralContainer.Name),
-			attribute.String("image", ephemeralContainer.Image),
-		))
-
-		if !isImageFromAllowedRegistry(ephemeralContainer.Image, imageSecurity.AllowedRegistries) {
-			log.Printf("Pod %s in namespace %s is using an ephemeral container image from a disallowed registry: %s\n",
-				pod.Name, pod.Namespace, ephemeralContainer.Image)
-
-			imgDenied.Add(ctx, 1, metric.WithAttributes(
-				attribute.String("pod", pod.Name),
-				attribute.String("namespace", pod.Namespace),
-				attribute.String("ephemeral_container", ephemeralContainer.Name),
-				attribute.String("image", ephemeralContainer.Image),
-				attribute.String("denial_reason", "disallowed_registry"),
-			))
-
-			span.SetAttributes(
-				attribute.String("ephemeral_container", ephemeralContainer.Name),
-				attribute.String("image", ephemeralContainer.Image),
-				attribute.String("result", "denied"),
-				attribute.String("denial_reason", "disallowed_registry"),
-			)
-
-			return false
-		}
+	if len(violations) > 0 {
+		span.SetAttributes(
+			attribute.String("result", "denied"),
+			attribute.Int("violation_count", len(violations)),
+		)
+		return violations
 	}
 
 	// All images are from allowed registries
@@ -192,28 +135,7 @@ func CheckImageRegistry(ctx context.Context, request *admissionv1.AdmissionReque
 		attribute.Int("ephemeral_container_count", len(pod.Spec.EphemeralContainers)),
 	)
 
-	return true // Passes the check if all images are from allowed registries
-}
-
-// getImageSecurity loads the image security policies from the configuration file
-func getImageSecurity() (*ImageSecurity, error) {
-	configPath := os.Getenv("SECURITY_POLICIES_PATH")
-	if configPath == "" {
-		configPath = "configs/security-policies.yaml" // Default path
-	}
-
-	data, err := os.ReadFile(configPath)
-	if err != nil {
-		return nil, err
-	}
-
-	var policies SecurityPoliciesImg
-	err = yaml.Unmarshal(data, &policies)
-	if err != nil {
-		return nil, err
-	}
-
-	return &policies.Policies.ImageSecurity, nil
+	return nil // Passes the check if all images are from allowed registries
 }
 
 // isImageFromAllowedRegistry checks if an image is from an allowed registry
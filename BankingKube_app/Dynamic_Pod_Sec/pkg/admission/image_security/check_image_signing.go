@@ -2,19 +2,16 @@ package image_security
 
 import (
 	"context"
-	"encoding/json"
+	"fmt"
 	"log"
 	"os"
 	"os/exec"
 
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/checks"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/metric"
 	"go.opentelemetry.io/otel/trace"
-
-	"gopkg.in/yaml.v2"
-	admissionv1 "k8s.io/api/admission/v1"
-	corev1 "k8s.io/api/core/v1"
 )
 
 var (
@@ -46,31 +43,16 @@ func init() {
 	}
 }
 
-// RequireImageSigning defines a structure for the requireImageSigning policy
-type RequireImageSigning struct {
-	RequireImageSigning bool `yaml:"requireImageSigning"`
-}
-
-// SecurityPoliciesSign represents the structure of the security-policies.yaml file
-type SecurityPoliciesSign struct {
-	Policies struct {
-		ImageSecurity struct {
-			RequireImageSigning RequireImageSigning `yaml:"requireImageSigning"`
-		} `yaml:"imageSecurity"`
-	} `yaml:"policies"`
-}
-
 // CheckImageSigning validates if a pod's images are signed and verified
-func CheckImageSigning(ctx context.Context, request *admissionv1.AdmissionRequest) bool {
+func CheckImageSigning(ctx context.Context, eval *checks.Evaluation) []checks.Violation {
 	ctx, span := signTracer.Start(ctx, "CheckImageSigning", trace.WithAttributes(
-		attribute.String("operation", string(request.Operation)),
-		attribute.String("resource", request.Resource.Resource),
+		attribute.String("operation", string(eval.Request.Operation)),
+		attribute.String("resource", eval.Request.Resource.Resource),
 	))
 	defer span.End()
 
-	// Parse the Pod object from the request
-	pod := &corev1.Pod{}
-	err := json.Unmarshal(request.Object.Raw, pod)
+	// Extract the pod, or the pod template of a workload, from the request
+	template, err := eval.PodTemplate()
 	if err != nil {
 		log.Println("Failed to parse pod object:", err)
 		span.SetAttributes(
@@ -78,8 +60,9 @@ func CheckImageSigning(ctx context.Context, request *admissionv1.AdmissionReques
 			attribute.String("result", "denied"),
 		)
 		span.RecordError(err)
-		return false // Fails the validation if the pod can't be parsed
+		return []checks.Violation{checks.ErrorViolation("failed_to_parse_pod", err)} // Fails the validation if the pod can't be parsed
 	}
+	pod := template.Pod
 
 	span.SetAttributes(
 		attribute.String("pod", pod.Name),
@@ -87,7 +70,7 @@ func CheckImageSigning(ctx context.Context, request *admissionv1.AdmissionReques
 	)
 
 	// Retrieve the requireImageSigning policy
-	requireImageSigning, err := getRequireImageSigning()
+	policies, err := eval.Policies()
 	if err != nil {
 		log.Println("Failed to load requireImageSigning policy:", err)
 		span.SetAttributes(
@@ -95,8 +78,9 @@ func CheckImageSigning(ctx context.Context, request *admissionv1.AdmissionReques
 			attribute.String("result", "denied"),
 		)
 		span.RecordError(err)
-		return false
+		return []checks.Violation{checks.ErrorViolation("failed_to_load_policy", err)}
 	}
+	requireImageSigning := &policies.ImageSecurity
 
 	// Check if image signing enforcement is enabled
 	if !requireImageSigning.RequireImageSigning {
@@ -112,22 +96,25 @@ func CheckImageSigning(ctx context.Context, request *admissionv1.AdmissionReques
 			attribute.String("reason", "signing_not_required"),
 		))
 
-		return true // Passes the check if enforcement is not enabled
+		return nil // Passes the check if enforcement is not enabled
 	}
 
 	span.SetAttributes(attribute.Bool("image_signing_required", true))
 
-	// Check regular containers
-	for _, container := range pod.Spec.Containers {
+	var violations []checks.Violation
+
+	// Check regular, init and ephemeral containers
+	for _, ref := range checks.PodContainers(&pod.Spec, template.SpecPath) {
+		container := ref.Container
 		containerCtx, containerSpan := signTracer.Start(ctx, "VerifyContainerImage", trace.WithAttributes(
 			attribute.String("container", container.Name),
 			attribute.String("image", container.Image),
-			attribute.String("container_type", "regular"),
+			attribute.String("container_type", ref.Type),
 		))
 
 		if !isImageSigned(containerCtx, container.Image) {
-			log.Printf("Pod %s in namespace %s is using an unsigned image: %s\n",
-				pod.Name, pod.Namespace, container.Image)
+			log.Printf("Pod %s in namespace %s is using an unsigned image in %s container %s: %s\n",
+				pod.Name, pod.Namespace, ref.Type, container.Name, container.Image)
 
 			signDenied.Add(ctx, 1, metric.WithAttributes(
 				attribute.String("pod", pod.Name),
@@ -135,7 +122,7 @@ func CheckImageSigning(ctx context.Context, request *admissionv1.AdmissionReques
 				attribute.String("container", container.Name),
 				attribute.String("image", container.Image),
 				attribute.String("denial_reason", "unsigned_image"),
-				attribute.String("container_type", "regular"),
+				attribute.String("container_type", ref.Type),
 			))
 
 			containerSpan.SetAttributes(
@@ -144,103 +131,27 @@ func CheckImageSigning(ctx context.Context, request *admissionv1.AdmissionReques
 			)
 			containerSpan.End()
 
-			span.SetAttributes(
-				attribute.String("result", "denied"),
-				attribute.String("container", container.Name),
-				attribute.String("image", container.Image),
-				attribute.String("reason", "unsigned_image"),
-				attribute.String("container_type", "regular"),
-			)
-
-			return false
+			violations = append(violations, checks.Violation{
+				Reason:    "unsigned_image",
+				Container: container.Name,
+				Field:     ref.Path + ".image",
+				Value:     container.Image,
+				Expected:  "an image signature verifiable with cosign",
+				Message:   fmt.Sprintf("%s container %q uses an image whose signature could not be verified", ref.Type, container.Name),
+			})
+			continue
 		}
 
 		containerSpan.SetAttributes(attribute.String("result", "verified"))
 		containerSpan.End()
 	}
 
-	// Check init containers
-	for _, initContainer := range pod.Spec.InitContainers {
-		initCtx, initSpan := signTracer.Start(ctx, "VerifyInitContainerImage", trace.WithAttributes(
-			attribute.String("init_container", initContainer.Name),
-			attribute.String("image", initContainer.Image),
-			attribute.String("container_type", "init"),
-		))
-
-		if !isImageSigned(initCtx, initContainer.Image) {
-			log.Printf("Pod %s in namespace %s is using an unsigned init container image: %s\n",
-				pod.Name, pod.Namespace, initContainer.Image)
-
-			signDenied.Add(ctx, 1, metric.WithAttributes(
-				attribute.String("pod", pod.Name),
-				attribute.String("namespace", pod.Namespace),
-				attribute.String("init_container", initContainer.Name),
-				attribute.String("image", initContainer.Image),
-				attribute.String("denial_reason", "unsigned_image"),
-				attribute.String("container_type", "init"),
-			))
-
-			initSpan.SetAttributes(
-				attribute.String("result", "denied"),
-				attribute.String("reason", "unsigned_image"),
-			)
-			initSpan.End()
-
-			span.SetAttributes(
-				attribute.String("result", "denied"),
-				attribute.String("init_container", initContainer.Name),
-				attribute.String("image", initContainer.Image),
-				attribute.String("reason", "unsigned_image"),
-				attribute.String("container_type", "init"),
-			)
-
-			return false
-		}
-
-		initSpan.SetAttributes(attribute.String("result", "verified"))
-		initSpan.End()
-	}
-
-	// Check ephemeral containers
-	for _, ephemeralContainer := range pod.Spec.EphemeralContainers {
-		ephemeralCtx, ephemeralSpan := signTracer.Start(ctx, "VerifyEphemeralContainerImage", trace.WithAttributes(
-			attribute.String("ephemeral_container", ephemeralContainer.Name),
-			attribute.String("image", ephemeralContainer.Image),
-			attribute.String("container_type", "ephemeral"),
-		))
-
-		if !isImageSigned(ephemeralCtx, ephemeralContainer.Image) {
-			log.Printf("Pod %s in namespace %s is using an unsigned ephemeral container image: %s\n",
-				pod.Name, pod.Namespace, ephemeralContainer.Image)
-
-			signDenied.Add(ctx, 1, metric.WithAttributes(
-				attribute.String("pod", pod.Name),
-				attribute.String("namespace", pod.Namespace),
-				attribute.String("ephemeral_container", ephemeralContainer.Name),
-				attribute.String("image", ephemeralContainer.Image),
-				attribute.String("denial_reason", "unsigned_image"),
-				attribute.String("container_type", "ephemeral"),
-			))
-
-			ephemeralSpan.SetAttributes(
-				attribute.String("result", "denied"),
-				attribute.String("reason", "unsigned_image"),
-			)
-			ephemeralSpan.End()
-
-			span.SetAttributes(
-				attribute.String("result", "denied"),
-				attribute.String("ephemeral_container", ephemeralContainer.Name),
-				attribute.String("image", ephemeralContainer.Image),
-				attribute.String("reason", "unsigned_image"),
-				attribute.String("container_type", "ephemeral"),
-			)
-
-			return false
-		}
-
-		ephemeralSpan.SetAttributes(attribute.String("result", "verified"))
-		ephemeralSpan.End()
+	if len(violations) > 0 {
+		span.SetAttributes(
+			attribute.String("result", "denied"),
+			attribute.Int("violation_count", len(violations)),
+		)
+		return violations
 	}
 
 	// All images are signed
@@ -259,28 +170,7 @@ func CheckImageSigning(ctx context.Context, request *admissionv1.AdmissionReques
 		attribute.Int("ephemeral_container_count", len(pod.Spec.EphemeralContainers)),
 	)
 
-	return true // Passes the check if all images are signed
-}
-
-// getRequireImageSigning loads the requireImageSigning policy from the configuration file
-func getRequireImageSigning() (*RequireImageSigning, error) {
-	configPath := os.Getenv("SECURITY_POLICIES_PATH")
-	if configPath == "" {
-		configPath = "configs/security-policies.yaml" // Default path
-	}
-
-	data, err := os.ReadFile(configPath)
-	if err != nil {
-		return nil, err
-	}
-
-	var policies SecurityPoliciesSign
-	err = yaml.Unmarshal(data, &policies)
-	if err != nil {
-		return nil, err
-	}
-
-	return &policies.Policies.ImageSecurity.RequireImageSigning, nil
+	return nil // Passes the check if all images are signed
 }
 
 // isImageSigned checks if the image is signed using cosign
@@ -309,8 +199,8 @@ func isImageSigned(ctx context.Context, image string) bool {
 
 	span.SetAttributes(attribute.String("public_key_path", publicKeyPath))
 
-	// Construct the cosign verify command
-	cmd := exec.Command("cosign", "verify", "--key", publicKeyPath, image)
+	// Construct the cosign verify command, killed when the check runs out of time
+	cmd := exec.CommandContext(ctx, "cosign", "verify", "--key", publicKeyPath, image)
 
 	// Run the command and capture the output
 	output, err := cmd.CombinedOutput()
@@ -2,19 +2,15 @@ package image_security
 
 import (
 	"context"
-	"encoding/json"
+	"fmt"
 	"log"
-	"os"
 	"strings"
 
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/checks"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/metric"
 	"go.opentelemetry.io/otel/trace"
-
-	"gopkg.in/yaml.v2"
-	admissionv1 "k8s.io/api/admission/v1"
-	corev1 "k8s.io/api/core/v1"
 )
 
 var (
@@ -36,31 +32,16 @@ func init() {
 	}
 }
 
-// DisallowedTags defines a structure for disallowed image tags
-type DisallowedTags struct {
-	DisallowedTags []string `yaml:"disallowedTags"`
-}
-
-// SecurityPoliciesTags represents the structure of the security-policies.yaml file
-type SecurityPoliciesTags struct {
-	Policies struct {
-		ImageSecurity struct {
-			DisallowedTags DisallowedTags `yaml:"disallowedTags"`
-		} `yaml:"imageSecurity"`
-	} `yaml:"policies"`
-}
-
 // CheckImageTags validates if a pod's images are using allowed tags
-func CheckImageTags(ctx context.Context, request *admissionv1.AdmissionRequest) bool {
+func CheckImageTags(ctx context.Context, eval *checks.Evaluation) []checks.Violation {
 	ctx, span := tagTracer.Start(ctx, "CheckImageTags", trace.WithAttributes(
-		attribute.String("operation", string(request.Operation)),
-		attribute.String("resource", request.Resource.Resource),
+		attribute.String("operation", string(eval.Request.Operation)),
+		attribute.String("resource", eval.Request.Resource.Resource),
 	))
 	defer span.End()
 
-	// Parse the Pod object from the request
-	pod := &corev1.Pod{}
-	err := json.Unmarshal(request.Object.Raw, pod)
+	// Extract the pod, or the pod template of a workload, from the request
+	template, err := eval.PodTemplate()
 	if err != nil {
 		log.Println("Failed to parse pod object:", err)
 		span.SetAttributes(
@@ -68,8 +49,9 @@ func CheckImageTags(ctx context.Context, request *admissionv1.AdmissionRequest)
 			attribute.String("result", "denied"),
 		)
 		span.RecordError(err)
-		return false // Fails the validation if the pod can't be parsed
+		return []checks.Violation{checks.ErrorViolation("failed_to_parse_pod", err)} // Fails the validation if the pod can't be parsed
 	}
+	pod := template.Pod
 
 	span.SetAttributes(
 		attribute.String("pod", pod.Name),
@@ -77,7 +59,7 @@ func CheckImageTags(ctx context.Context, request *admissionv1.AdmissionRequest)
 	)
 
 	// Retrieve the disallowed tags policies
-	disallowedTags, err := getDisallowedTags()
+	policies, err := eval.Policies()
 	if err != nil {
 		log.Println("Failed to load disallowed tags policies:", err)
 		span.SetAttributes(
@@ -85,20 +67,25 @@ func CheckImageTags(ctx context.Context, request *admissionv1.AdmissionRequest)
 			attribute.String("result", "denied"),
 		)
 		span.RecordError(err)
-		return false
+		return []checks.Violation{checks.ErrorViolation("failed_to_load_policies", err)}
 	}
+	disallowedTags := &policies.ImageSecurity
+
+	var violations []checks.Violation
 
-	// Check regular containers
-	for _, container := range pod.Spec.Containers {
+	// Check regular, init and ephemeral containers
+	for _, ref := range checks.PodContainers(&pod.Spec, template.SpecPath) {
+		container := ref.Container
 		span.AddEvent("Checking container image tag", trace.WithAttributes(
 			attribute.String("container", container.Name),
+			attribute.String("container_type", ref.Type),
 			attribute.String("image", container.Image),
 		))
 
 		tag := extractImageTag(container.Image)
 		if !isImageTagAllowed(container.Image, disallowedTags.DisallowedTags) {
-			log.Printf("Pod %s in namespace %s is using an image with a disallowed tag: %s\n",
-				pod.Name, pod.Namespace, container.Image)
+			log.Printf("Pod %s in namespace %s is using an image with a disallowed tag in %s container %s: %s\n",
+				pod.Name, pod.Namespace, ref.Type, container.Name, container.Image)
 
 			tagDenied.Add(ctx, 1, metric.WithAttributes(
 				attribute.String("pod", pod.Name),
@@ -106,88 +93,33 @@ func CheckImageTags(ctx context.Context, request *admissionv1.AdmissionRequest)
 				attribute.String("container", container.Name),
 				attribute.String("image", container.Image),
 				attribute.String("tag", tag),
-				attribute.String("container_type", "regular"),
+				attribute.String("container_type", ref.Type),
 				attribute.String("denial_reason", "disallowed_tag"),
 			))
 
-			span.SetAttributes(
+			span.AddEvent("Disallowed tag", trace.WithAttributes(
 				attribute.String("container", container.Name),
 				attribute.String("image", container.Image),
 				attribute.String("tag", tag),
-				attribute.String("result", "denied"),
-				attribute.String("denial_reason", "disallowed_tag"),
-			)
-
-			return false
-		}
-	}
-
-	// Check init containers
-	for _, initContainer := range pod.Spec.InitContainers {
-		span.AddEvent("Checking init container image tag", trace.WithAttributes(
-			attribute.String("init_container", initContainer.Name),
-			attribute.String("image", initContainer.Image),
-		))
-
-		tag := extractImageTag(initContainer.Image)
-		if !isImageTagAllowed(initContainer.Image, disallowedTags.DisallowedTags) {
-			log.Printf("Pod %s in namespace %s is using an init container image with a disallowed tag: %s\n",
-				pod.Name, pod.Namespace, initContainer.Image)
-
-			tagDenied.Add(ctx, 1, metric.WithAttributes(
-				attribute.String("pod", pod.Name),
-				attribute.String("namespace", pod.Namespace),
-				attribute.String("init_container", initContainer.Name),
-				attribute.String("image", initContainer.Image),
-				attribute.String("tag", tag),
-				attribute.String("container_type", "init"),
-				attribute.String("denial_reason", "disallowed_tag"),
 			))
 
-			span.SetAttributes(
-				attribute.String("init_container", initContainer.Name),
-				attribute.String("image", initContainer.Image),
-				attribute.String("tag", tag),
-				attribute.String("result", "denied"),
-				attribute.String("denial_reason", "disallowed_tag"),
-			)
-
-			return false
+			violations = append(violations, checks.Violation{
+				Reason:    "disallowed_tag",
+				Container: container.Name,
+				Field:     ref.Path + ".image",
+				Value:     container.Image,
+				Expected:  "a tag other than " + strings.Join(disallowedTags.DisallowedTags, ", "),
+				Message:   fmt.Sprintf("%s container %q uses disallowed image tag %q", ref.Type, container.Name, tag),
+			})
 		}
 	}
 
-	// Check ephemeral containers
-	for _, ephemeralContainer := range pod.Spec.EphemeralContainers {
-		span.AddEvent("Checking ephemeral container image tag", trace.WithAttributes(
-			attribute.String("ephemeral_container", ephemeralContainer.Name),
-			attribute.String("image", ephemeralContainer.Image),
-		))
-
-		tag := extractImageTag(ephemeralContainer.Image)
-		if !isImageTagAllowed(ephemeralContainer.Image, disallowedTags.DisallowedTags) {
-			log.Printf("Pod %s in namespace %s is using an ephemeral container image with a disallowed tag: %s\n",
-				pod.Name, pod.Namespace, ephemeralContainer.Image)
-
-			tagDenied.Add(ctx, 1, metric.WithAttributes(
-				attribute.String("pod", pod.Name),
-				attribute.String("namespace", pod.Namespace),
-				attribute.String("ephemeral_container", ephemeralContainer.Name),
-				attribute.String("image", ephemeralContainer.Image),
-				attribute.String("tag", tag),
-				attribute.String("container_type", "ephemeral"),
-				attribute.String("denial_reason", "disallowed_tag"),
-			))
-
-			span.SetAttributes(
-				attribute.String("ephemeral_container", ephemeralContainer.Name),
-				attribute.String("image", ephemeralContainer.Image),
-				attribute.String("tag", tag),
-				attribute.String("result", "denied"),
-				attribute.String("denial_reason", "disallowed_tag"),
-			)
-
-			return false
-		}
+	if len(violations) > 0 {
+		span.SetAttributes(
+			attribute.String("result", "denied"),
+			attribute.Int("violation_count", len(violations)),
+		)
+		return violations
 	}
 
 	// All images have allowed tags
@@ -206,28 +138,7 @@ func CheckImageTags(ctx context.Context, request *admissionv1.AdmissionRequest)
 		attribute.Int("ephemeral_container_count", len(pod.Spec.EphemeralContainers)),
 	)
 
-	return true // Passes the check if all images have allowed tags
-}
-
-// getDisallowedTags loads the disallowed tags policies from the configuration file
-func getDisallowedTags() (*DisallowedTags, error) {
-	configPath := os.Getenv("SECURITY_POLICIES_PATH")
-	if configPath == "" {
-		configPath = "configs/security-policies.yaml" // Default path
-	}
-
-	data, err := os.ReadFile(configPath)
-	if err != nil {
-		return nil, err
-	}
-
-	var policies SecurityPoliciesTags
-	err = yaml.Unmarshal(data, &policies)
-	if err != nil {
-		return nil, err
-	}
-
-	return &policies.Policies.ImageSecurity.DisallowedTags, nil
+	return nil // Passes the check if all images have allowed tags
 }
 
 // isImageTagAllowed checks if an image tag is allowed
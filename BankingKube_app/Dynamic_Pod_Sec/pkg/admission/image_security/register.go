@@ -0,0 +1,15 @@
+package image_security
+
+import (
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/checks"
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/workload"
+)
+
+// Group is the check group served on /validate/image
+const Group = "image"
+
+func init() {
+	checks.Register(Group, checks.NewCheck("image_registry", workload.Kinds, CheckImageRegistry))
+	checks.Register(Group, checks.NewCheck("image_signing", workload.Kinds, CheckImageSigning))
+	checks.Register(Group, checks.NewCheck("image_tags", workload.Kinds, CheckImageTags))
+}
@@ -0,0 +1,165 @@
+package jsonpatch
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Apply applies a JSON Patch document to a JSON document. It supports the
+// add, replace and remove operations emitted by CreatePatch.
+func Apply(document, patch []byte) ([]byte, error) {
+	var ops []Operation
+	if err := json.Unmarshal(patch, &ops); err != nil {
+		return nil, fmt.Errorf("failed to decode patch: %w", err)
+	}
+
+	var doc interface{}
+	if err := json.Unmarshal(document, &doc); err != nil {
+		return nil, fmt.Errorf("failed to decode document: %w", err)
+	}
+
+	for _, op := range ops {
+		var err error
+		doc, err = applyOperation(doc, op)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(doc)
+}
+
+// applyOperation applies a single operation and returns the new root
+func applyOperation(doc interface{}, op Operation) (interface{}, error) {
+	if op.Path == "" {
+		if op.Op == OpRemove {
+			return nil, fmt.Errorf("%s: cannot remove the document root", op.Op)
+		}
+		return op.Value, nil
+	}
+	if !strings.HasPrefix(op.Path, "/") {
+		return nil, fmt.Errorf("%s %s: path must start with /", op.Op, op.Path)
+	}
+
+	tokens := strings.Split(op.Path[1:], "/")
+	for i := range tokens {
+		tokens[i] = unescapePointer(tokens[i])
+	}
+
+	parent, err := resolve(doc, tokens[:len(tokens)-1])
+	if err != nil {
+		return nil, fmt.Errorf("%s %s: %w", op.Op, op.Path, err)
+	}
+	last := tokens[len(tokens)-1]
+
+	switch container := parent.(type) {
+	case map[string]interface{}:
+		switch op.Op {
+		case OpAdd:
+			container[last] = op.Value
+		case OpReplace, OpRemove:
+			if _, ok := container[last]; !ok {
+				return nil, fmt.Errorf("%s %s: member %q does not exist", op.Op, op.Path, last)
+			}
+			if op.Op == OpReplace {
+				container[last] = op.Value
+			} else {
+				delete(container, last)
+			}
+		default:
+			return nil, fmt.Errorf("unsupported operation %q", op.Op)
+		}
+		return doc, nil
+	case []interface{}:
+		updated, err := applyToArray(container, last, op)
+		if err != nil {
+			return nil, err
+		}
+		// Arrays are values, so the updated slice has to be written back into its parent
+		return setAt(doc, tokens[:len(tokens)-1], updated)
+	default:
+		return nil, fmt.Errorf("%s %s: parent is not an object or array", op.Op, op.Path)
+	}
+}
+
+// applyToArray applies an operation to an element of an array
+func applyToArray(array []interface{}, token string, op Operation) ([]interface{}, error) {
+	if op.Op == OpAdd && token == "-" {
+		return append(array, op.Value), nil
+	}
+	index, err := strconv.Atoi(token)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s: invalid array index %q", op.Op, op.Path, token)
+	}
+
+	switch op.Op {
+	case OpAdd:
+		if index < 0 || index > len(array) {
+			return nil, fmt.Errorf("%s %s: index out of range", op.Op, op.Path)
+		}
+		array = append(array, nil)
+		copy(array[index+1:], array[index:])
+		array[index] = op.Value
+	case OpReplace:
+		if index < 0 || index >= len(array) {
+			return nil, fmt.Errorf("%s %s: index out of range", op.Op, op.Path)
+		}
+		array[index] = op.Value
+	case OpRemove:
+		if index < 0 || index >= len(array) {
+			return nil, fmt.Errorf("%s %s: index out of range", op.Op, op.Path)
+		}
+		array = append(array[:index], array[index+1:]...)
+	default:
+		return nil, fmt.Errorf("unsupported operation %q", op.Op)
+	}
+	return array, nil
+}
+
+// resolve walks the document along the given reference tokens
+func resolve(doc interface{}, tokens []string) (interface{}, error) {
+	current := doc
+	for _, token := range tokens {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			child, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("member %q does not exist", token)
+			}
+			current = child
+		case []interface{}:
+			index, err := strconv.Atoi(token)
+			if err != nil || index < 0 || index >= len(node) {
+				return nil, fmt.Errorf("invalid array index %q", token)
+			}
+			current = node[index]
+		default:
+			return nil, fmt.Errorf("cannot traverse into %q", token)
+		}
+	}
+	return current, nil
+}
+
+// setAt replaces the value at the given reference tokens and returns the new root
+func setAt(doc interface{}, tokens []string, value interface{}) (interface{}, error) {
+	if len(tokens) == 0 {
+		return value, nil
+	}
+	parent, err := resolve(doc, tokens[:len(tokens)-1])
+	if err != nil {
+		return nil, err
+	}
+	last := tokens[len(tokens)-1]
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		node[last] = value
+	case []interface{}:
+		index, err := strconv.Atoi(last)
+		if err != nil {
+			return nil, fmt.Errorf("invalid array index %q", last)
+		}
+		node[index] = value
+	}
+	return doc, nil
+}
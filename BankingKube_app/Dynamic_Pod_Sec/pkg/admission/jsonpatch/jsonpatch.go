@@ -0,0 +1,149 @@
+// Package jsonpatch generates and applies RFC 6902 JSON Patch documents.
+//
+// The mutating webhook must answer with a list of patch operations rather than
+// the mutated object, so CreatePatch diffs the object before and after mutation
+// and emits the minimal add, replace and remove operations between them.
+package jsonpatch
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Operation types emitted by CreatePatch
+const (
+	OpAdd     = "add"
+	OpReplace = "replace"
+	OpRemove  = "remove"
+)
+
+// Operation is a single RFC 6902 patch operation
+type Operation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// MarshalJSON always writes the value of add and replace operations, even when it is null
+func (o Operation) MarshalJSON() ([]byte, error) {
+	if o.Op == OpRemove {
+		return json.Marshal(struct {
+			Op   string `json:"op"`
+			Path string `json:"path"`
+		}{o.Op, o.Path})
+	}
+	return json.Marshal(struct {
+		Op    string      `json:"op"`
+		Path  string      `json:"path"`
+		Value interface{} `json:"value"`
+	}{o.Op, o.Path, o.Value})
+}
+
+// CreatePatch returns the JSON Patch document that turns original into modified
+func CreatePatch(original, modified []byte) ([]byte, error) {
+	ops, err := Diff(original, modified)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(ops)
+}
+
+// Diff returns the operations that turn the original JSON document into the modified one
+func Diff(original, modified []byte) ([]Operation, error) {
+	var from, to interface{}
+	if err := json.Unmarshal(original, &from); err != nil {
+		return nil, fmt.Errorf("failed to decode original document: %w", err)
+	}
+	if err := json.Unmarshal(modified, &to); err != nil {
+		return nil, fmt.Errorf("failed to decode modified document: %w", err)
+	}
+	return diff("", from, to, []Operation{}), nil
+}
+
+// diff appends the operations needed to turn from into to at the given pointer
+func diff(path string, from, to interface{}, ops []Operation) []Operation {
+	switch fromValue := from.(type) {
+	case map[string]interface{}:
+		if toValue, ok := to.(map[string]interface{}); ok {
+			return diffObjects(path, fromValue, toValue, ops)
+		}
+	case []interface{}:
+		if toValue, ok := to.([]interface{}); ok {
+			return diffArrays(path, fromValue, toValue, ops)
+		}
+	}
+
+	if !reflect.DeepEqual(from, to) {
+		ops = append(ops, Operation{Op: OpReplace, Path: path, Value: to})
+	}
+	return ops
+}
+
+// diffObjects compares two JSON objects key by key in a stable order
+func diffObjects(path string, from, to map[string]interface{}, ops []Operation) []Operation {
+	for _, key := range sortedKeys(from) {
+		if _, ok := to[key]; !ok {
+			ops = append(ops, Operation{Op: OpRemove, Path: path + "/" + EscapePointer(key)})
+		}
+	}
+	for _, key := range sortedKeys(to) {
+		childPath := path + "/" + EscapePointer(key)
+		fromChild, ok := from[key]
+		if !ok {
+			ops = append(ops, Operation{Op: OpAdd, Path: childPath, Value: to[key]})
+			continue
+		}
+		ops = diff(childPath, fromChild, to[key], ops)
+	}
+	return ops
+}
+
+// diffArrays compares arrays element by element. Elements appended to or removed from the
+// end are expressed as add and remove operations, any other length change replaces the array.
+func diffArrays(path string, from, to []interface{}, ops []Operation) []Operation {
+	common := len(from)
+	if len(to) < common {
+		common = len(to)
+	}
+
+	if len(from) != len(to) && !reflect.DeepEqual(from[:common], to[:common]) {
+		return append(ops, Operation{Op: OpReplace, Path: path, Value: to})
+	}
+
+	for i := 0; i < common; i++ {
+		ops = diff(path+"/"+strconv.Itoa(i), from[i], to[i], ops)
+	}
+	for i := common; i < len(to); i++ {
+		ops = append(ops, Operation{Op: OpAdd, Path: path + "/" + strconv.Itoa(i), Value: to[i]})
+	}
+	// Remove trailing elements from the end so earlier indexes stay valid
+	for i := len(from) - 1; i >= common; i-- {
+		ops = append(ops, Operation{Op: OpRemove, Path: path + "/" + strconv.Itoa(i)})
+	}
+	return ops
+}
+
+// EscapePointer escapes a single reference token of a JSON Pointer (RFC 6901)
+func EscapePointer(token string) string {
+	token = strings.ReplaceAll(token, "~", "~0")
+	return strings.ReplaceAll(token, "/", "~1")
+}
+
+// unescapePointer reverses EscapePointer
+func unescapePointer(token string) string {
+	token = strings.ReplaceAll(token, "~1", "/")
+	return strings.ReplaceAll(token, "~0", "~")
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
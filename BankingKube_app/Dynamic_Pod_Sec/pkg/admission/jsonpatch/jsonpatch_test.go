@@ -0,0 +1,110 @@
+package jsonpatch
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden patch files")
+
+// TestCreatePatchGolden diffs every testdata/<case>.original.json against
+// <case>.modified.json, compares the patch with <case>.patch.golden.json and
+// checks that applying it to the original yields the modified document.
+func TestCreatePatchGolden(t *testing.T) {
+	originals, err := filepath.Glob(filepath.Join("testdata", "*.original.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(originals) == 0 {
+		t.Fatal("no test cases found in testdata")
+	}
+
+	for _, originalPath := range originals {
+		name := strings.TrimSuffix(filepath.Base(originalPath), ".original.json")
+		t.Run(name, func(t *testing.T) {
+			original := readFile(t, originalPath)
+			modified := readFile(t, filepath.Join("testdata", name+".modified.json"))
+			goldenPath := filepath.Join("testdata", name+".patch.golden.json")
+
+			patch, err := CreatePatch(original, modified)
+			if err != nil {
+				t.Fatalf("CreatePatch: %v", err)
+			}
+
+			var indented bytes.Buffer
+			if err := json.Indent(&indented, patch, "", "  "); err != nil {
+				t.Fatal(err)
+			}
+			indented.WriteByte('\n')
+
+			if *update {
+				if err := os.WriteFile(goldenPath, indented.Bytes(), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if golden := readFile(t, goldenPath); !bytes.Equal(golden, indented.Bytes()) {
+				t.Errorf("patch does not match %s\ngot:\n%s\nwant:\n%s", goldenPath, indented.String(), golden)
+			}
+
+			applied, err := Apply(original, patch)
+			if err != nil {
+				t.Fatalf("Apply: %v", err)
+			}
+			assertJSONEqual(t, applied, modified)
+		})
+	}
+}
+
+func TestEscapePointer(t *testing.T) {
+	tests := map[string]string{
+		"name":                      "name",
+		"k8s.io/app":                "k8s.io~1app",
+		"a~b":                       "a~0b",
+		"~/":                        "~0~1",
+		"seccomp.security/pod~test": "seccomp.security~1pod~0test",
+	}
+	for in, want := range tests {
+		if got := EscapePointer(in); got != want {
+			t.Errorf("EscapePointer(%q) = %q, want %q", in, got, want)
+		}
+		if got := unescapePointer(want); got != in {
+			t.Errorf("unescapePointer(%q) = %q, want %q", want, got, in)
+		}
+	}
+}
+
+func TestApplyRejectsMissingPath(t *testing.T) {
+	_, err := Apply([]byte(`{"spec":{}}`), []byte(`[{"op":"replace","path":"/spec/hostname","value":"a"}]`))
+	if err == nil {
+		t.Fatal("expected an error when replacing a missing member")
+	}
+}
+
+func readFile(t *testing.T, path string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func assertJSONEqual(t *testing.T, got, want []byte) {
+	t.Helper()
+	var g, w interface{}
+	if err := json.Unmarshal(got, &g); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(want, &w); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(g, w) {
+		t.Errorf("documents differ\ngot:  %s\nwant: %s", got, want)
+	}
+}
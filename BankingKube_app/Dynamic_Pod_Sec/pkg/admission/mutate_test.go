@@ -0,0 +1,140 @@
+package admission
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/jsonpatch"
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/policy"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden patch files")
+
+// noPolicies is a policy source without ID rules, so only the baseline defaults apply
+func noPolicies(string) (*policy.SecurityPolicies, error) { return &policy.SecurityPolicies{}, nil }
+
+// TestMutatePodGolden runs mutatePod on every testdata/mutate/<case>.pod.json, compares the
+// patch with <case>.patch.golden.json and checks that the API server could apply it to the raw pod.
+func TestMutatePodGolden(t *testing.T) {
+	pods, err := filepath.Glob(filepath.Join("testdata", "mutate", "*.pod.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pods) == 0 {
+		t.Fatal("no test cases found in testdata/mutate")
+	}
+
+	for _, podPath := range pods {
+		name := strings.TrimSuffix(filepath.Base(podPath), ".pod.json")
+		t.Run(name, func(t *testing.T) {
+			raw, err := os.ReadFile(podPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			response := mutatePod(&admissionv1.AdmissionRequest{Object: runtime.RawExtension{Raw: raw}}, noPolicies)
+			if !response.Allowed {
+				t.Fatalf("pod was not admitted: %v", response.Result)
+			}
+
+			patch := response.Patch
+			if patch == nil {
+				patch = []byte("[]")
+			} else if response.PatchType == nil || *response.PatchType != admissionv1.PatchTypeJSONPatch {
+				t.Fatalf("unexpected patch type %v", response.PatchType)
+			}
+
+			var indented bytes.Buffer
+			if err := json.Indent(&indented, patch, "", "  "); err != nil {
+				t.Fatalf("patch is not valid JSON: %v", err)
+			}
+			indented.WriteByte('\n')
+
+			goldenPath := filepath.Join("testdata", "mutate", name+".patch.golden.json")
+			if *update {
+				if err := os.WriteFile(goldenPath, indented.Bytes(), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			golden, err := os.ReadFile(goldenPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(golden, indented.Bytes()) {
+				t.Errorf("patch does not match %s\ngot:\n%s\nwant:\n%s", goldenPath, indented.String(), golden)
+			}
+
+			// The patch must apply cleanly to the object exactly as the API server sent it
+			patched, err := jsonpatch.Apply(raw, patch)
+			if err != nil {
+				t.Fatalf("patch does not apply to the admitted pod: %v", err)
+			}
+
+			got := &corev1.Pod{}
+			if err := json.Unmarshal(patched, got); err != nil {
+				t.Fatal(err)
+			}
+			want := &corev1.Pod{}
+			if err := json.Unmarshal(raw, want); err != nil {
+				t.Fatal(err)
+			}
+			ApplyBaselineSecurity(want)
+			if !reflect.DeepEqual(got.Spec, want.Spec) {
+				t.Errorf("patched pod spec differs from ApplyBaselineSecurity output\ngot:  %+v\nwant: %+v", got.Spec, want.Spec)
+			}
+		})
+	}
+}
+
+func TestMutatePodIDDefaults(t *testing.T) {
+	rules := policy.PodSecurityContext{
+		RunAsUser:          policy.IDPolicy{Rule: policy.IDRuleMustRunAs, Ranges: []policy.IDRange{{Min: 10000, Max: 19999}}},
+		RunAsGroup:         policy.IDPolicy{Rule: policy.IDRuleMustRunAsNonRoot},
+		FSGroup:            policy.IDPolicy{Rule: policy.IDRuleMustRunAs, Ranges: []policy.IDRange{{Min: 2000, Max: 2999}, {Min: 1, Max: 10}}},
+		SupplementalGroups: policy.IDPolicy{Rule: policy.IDRuleMustRunAs, Ranges: []policy.IDRange{{Min: 3000, Max: 3999}}},
+	}
+	source := func(string) (*policy.SecurityPolicies, error) {
+		return &policy.SecurityPolicies{PodSecurityContext: rules}, nil
+	}
+	const raw = `{"metadata":{"name":"web"},"spec":{"securityContext":{"fsGroup":2500,"seccompProfile":{"type":"RuntimeDefault"}},"containers":[{"name":"app","image":"nginx"}]}}`
+
+	patches := func() []jsonpatch.Operation {
+		response := mutatePod(&admissionv1.AdmissionRequest{Object: runtime.RawExtension{Raw: []byte(raw)}}, source)
+		var ops []jsonpatch.Operation
+		if err := json.Unmarshal(response.Patch, &ops); err != nil {
+			t.Fatal(err)
+		}
+		return ops
+	}
+	for _, op := range patches() {
+		if strings.HasPrefix(op.Path, "/spec/securityContext") {
+			t.Errorf("ID defaults applied without defaultIDs: %+v", op)
+		}
+	}
+
+	rules.DefaultIDs = true
+	var got []string
+	for _, op := range patches() {
+		if strings.HasPrefix(op.Path, "/spec/securityContext") {
+			value, _ := json.Marshal(op.Value)
+			got = append(got, op.Op+" "+op.Path+" "+string(value))
+		}
+	}
+	// fsGroup is already set and runAsGroup has no range to default from
+	want := []string{
+		"add /spec/securityContext/runAsUser 10000",
+		"add /spec/securityContext/supplementalGroups [3000]",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ID defaults = %q, want %q", got, want)
+	}
+}
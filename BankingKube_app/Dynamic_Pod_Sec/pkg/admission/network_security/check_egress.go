@@ -2,20 +2,16 @@ package network_security
 
 import (
 	"context"
-	"encoding/json"
+	"fmt"
 	"log"
 	"net"
-	"os"
 	"strings"
 
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/checks"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/metric"
 	"go.opentelemetry.io/otel/trace"
-
-	"gopkg.in/yaml.v2"
-	admissionv1 "k8s.io/api/admission/v1"
-	corev1 "k8s.io/api/core/v1"
 )
 
 var (
@@ -37,28 +33,15 @@ func init() {
 	}
 }
 
-// EgressPolicy defines a structure for egress network policies
-type EgressPolicy struct {
-	AllowedEgressCIDRs []string `yaml:"allowedEgressCIDRs"`
-}
-
-// SecurityPoliciesEgress represents the structure of the security-policies.yaml file
-type SecurityPolicies struct {
-	NetworkSecurity struct {
-		EgressPolicy EgressPolicy `yaml:"egressPolicy"`
-	} `yaml:"NetworkSecurity"`
-}
-
 // CheckEgress validates if a pod has correct egress restrictions
-func CheckEgress(ctx context.Context, request *admissionv1.AdmissionRequest) bool {
+func CheckEgress(ctx context.Context, eval *checks.Evaluation) []checks.Violation {
 	ctx, span := egressTracer.Start(ctx, "CheckEgress", trace.WithAttributes(
-		attribute.String("operation", string(request.Operation)),
-		attribute.String("resource", request.Resource.Resource),
+		attribute.String("operation", string(eval.Request.Operation)),
+		attribute.String("resource", eval.Request.Resource.Resource),
 	))
 	defer span.End()
 
-	pod := &corev1.Pod{}
-	err := json.Unmarshal(request.Object.Raw, pod)
+	template, err := eval.PodTemplate()
 	if err != nil {
 		log.Println("Failed to parse pod object:", err)
 		span.SetAttributes(
@@ -66,15 +49,16 @@ func CheckEgress(ctx context.Context, request *admissionv1.AdmissionRequest) boo
 			attribute.String("result", "denied"),
 		)
 		span.RecordError(err)
-		return false
+		return []checks.Violation{checks.ErrorViolation("failed_to_parse_pod", err)}
 	}
+	pod := template.Pod
 
 	span.SetAttributes(
 		attribute.String("pod", pod.Name),
 		attribute.String("namespace", pod.Namespace),
 	)
 
-	egressPolicy, err := getEgressPolicy()
+	policies, err := eval.Policies()
 	if err != nil {
 		log.Println("Failed to load egress policy:", err)
 		span.SetAttributes(
@@ -82,8 +66,12 @@ func CheckEgress(ctx context.Context, request *admissionv1.AdmissionRequest) boo
 			attribute.String("result", "denied"),
 		)
 		span.RecordError(err)
-		return false
+		return []checks.Violation{checks.ErrorViolation("failed_to_load_policy", err)}
 	}
+	egressPolicy := &policies.NetworkSecurity.EgressPolicy
+
+	field := template.MetadataPath + ".annotations[egressIPs]"
+	allowedCIDRs := strings.Join(egressPolicy.AllowedEgressCIDRs, ", ")
 
 	// Check pod annotations for egress IPs
 	egressIPs, ok := pod.Annotations["egressIPs"]
@@ -101,18 +89,50 @@ func CheckEgress(ctx context.Context, request *admissionv1.AdmissionRequest) boo
 			attribute.String("denial_reason", "missing_egress_ips"),
 		)
 
-		return false
+		return []checks.Violation{{
+			Reason:   "missing_egress_ips",
+			Field:    field,
+			Expected: "comma separated IPs within " + allowedCIDRs,
+			Message:  "pod does not declare its egress IPs in the egressIPs annotation",
+		}}
 	}
 
 	span.SetAttributes(attribute.String("egress_ips", egressIPs))
 
+	// Parse the allowed CIDRs once; a malformed entry is reported but does not stop the evaluation
+	var violations []checks.Violation
+	var allowedNets []*net.IPNet
+	for _, egressCIDR := range egressPolicy.AllowedEgressCIDRs {
+		_, allowedCIDR, err := net.ParseCIDR(egressCIDR)
+		if err != nil {
+			log.Printf("Invalid CIDR format: %s\n", egressCIDR)
+
+			egressDenied.Add(ctx, 1, metric.WithAttributes(
+				attribute.String("pod", pod.Name),
+				attribute.String("namespace", pod.Namespace),
+				attribute.String("cidr", egressCIDR),
+				attribute.String("denial_reason", "invalid_cidr_format"),
+			))
+			span.RecordError(err)
+
+			violations = append(violations, checks.Violation{
+				Reason:   "invalid_cidr_format",
+				Field:    "allowedEgressCIDRs",
+				Value:    egressCIDR,
+				Expected: "a valid CIDR",
+				Message:  "egress policy contains an invalid CIDR",
+			})
+			continue
+		}
+		allowedNets = append(allowedNets, allowedCIDR)
+	}
+
 	// Verify each egress IP against all allowed CIDRs
 	ipList := strings.Split(egressIPs, ",")
 	for _, ip := range ipList {
 		ip = strings.TrimSpace(ip)
 
-		subSpan := trace.SpanFromContext(ctx)
-		subSpan.AddEvent("Checking IP", trace.WithAttributes(
+		span.AddEvent("Checking IP", trace.WithAttributes(
 			attribute.String("ip", ip),
 		))
 
@@ -127,43 +147,23 @@ func CheckEgress(ctx context.Context, request *admissionv1.AdmissionRequest) boo
 				attribute.String("denial_reason", "invalid_ip_format"),
 			))
 
-			span.SetAttributes(
-				attribute.String("result", "denied"),
-				attribute.String("ip", ip),
-				attribute.String("denial_reason", "invalid_ip_format"),
-			)
-
-			return false
+			violations = append(violations, checks.Violation{
+				Reason:   "invalid_ip_format",
+				Field:    field,
+				Value:    ip,
+				Expected: "a valid IP address",
+				Message:  fmt.Sprintf("egress IP %q is not a valid IP address", ip),
+			})
+			continue
 		}
 
 		allowed := false
-		for _, egressCIDR := range egressPolicy.AllowedEgressCIDRs {
-			_, allowedCIDR, err := net.ParseCIDR(egressCIDR)
-			if err != nil {
-				log.Printf("Invalid CIDR format: %s\n", egressCIDR)
-
-				egressDenied.Add(ctx, 1, metric.WithAttributes(
-					attribute.String("pod", pod.Name),
-					attribute.String("namespace", pod.Namespace),
-					attribute.String("cidr", egressCIDR),
-					attribute.String("denial_reason", "invalid_cidr_format"),
-				))
-
-				span.SetAttributes(
-					attribute.String("result", "denied"),
-					attribute.String("cidr", egressCIDR),
-					attribute.String("denial_reason", "invalid_cidr_format"),
-				)
-				span.RecordError(err)
-
-				return false
-			}
-
+		for _, allowedCIDR := range allowedNets {
 			if allowedCIDR.Contains(parsedIP) {
 				allowed = true
-				subSpan.AddEvent("IP allowed by CIDR", trace.WithAttributes(
+				span.AddEvent("IP allowed by CIDR", trace.WithAttributes(
 					attribute.String("ip", ip),
-					attribute.String("cidr", egressCIDR),
+					attribute.String("cidr", allowedCIDR.String()),
 				))
 				break
 			}
@@ -179,16 +179,24 @@ func CheckEgress(ctx context.Context, request *admissionv1.AdmissionRequest) boo
 				attribute.String("denial_reason", "ip_not_in_allowed_cidrs"),
 			))
 
-			span.SetAttributes(
-				attribute.String("result", "denied"),
-				attribute.String("ip", ip),
-				attribute.String("denial_reason", "ip_not_in_allowed_cidrs"),
-			)
-
-			return false
+			violations = append(violations, checks.Violation{
+				Reason:   "ip_not_in_allowed_cidrs",
+				Field:    field,
+				Value:    ip,
+				Expected: "within " + allowedCIDRs,
+				Message:  fmt.Sprintf("egress IP %s is not within any allowed CIDR range", ip),
+			})
 		}
 	}
 
+	if len(violations) > 0 {
+		span.SetAttributes(
+			attribute.String("result", "denied"),
+			attribute.Int("violation_count", len(violations)),
+		)
+		return violations
+	}
+
 	// All egress IPs are allowed
 	egressAllowed.Add(ctx, 1, metric.WithAttributes(
 		attribute.String("pod", pod.Name),
@@ -202,26 +210,5 @@ func CheckEgress(ctx context.Context, request *admissionv1.AdmissionRequest) boo
 		attribute.Int("ip_count", len(ipList)),
 	)
 
-	return true
-}
-
-// getEgressPolicy loads the egress policy from the configuration file
-func getEgressPolicy() (*EgressPolicy, error) {
-	configPath := os.Getenv("SECURITY_POLICIES_PATH")
-	if configPath == "" {
-		configPath = "configs/security-policies.yaml"
-	}
-
-	data, err := os.ReadFile(configPath)
-	if err != nil {
-		return nil, err
-	}
-
-	var policies SecurityPolicies
-	err = yaml.Unmarshal(data, &policies)
-	if err != nil {
-		return nil, err
-	}
-
-	return &policies.NetworkSecurity.EgressPolicy, nil
+	return nil
 }
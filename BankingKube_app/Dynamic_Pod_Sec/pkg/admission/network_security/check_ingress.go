@@ -1,92 +1,118 @@
 package network_security
 
 import (
-	"encoding/json"
+	"fmt"
 	"log"
 	"net"
-	"os"
 	"strings"
 
-	"gopkg.in/yaml.v2"
-	admissionv1 "k8s.io/api/admission/v1"
-	corev1 "k8s.io/api/core/v1"
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/checks"
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/workload"
 )
 
-// IngressPolicy defines a structure for ingress network policies
-type IngressPolicy struct {
-	AllowedIngressCIDRs []string `yaml:"allowedIngressCIDRs"`
-}
-
 // CheckIngress validates if a pod has correct ingress restrictions
-func CheckIngress(request *admissionv1.AdmissionRequest) bool {
-	pod := &corev1.Pod{}
-	err := json.Unmarshal(request.Object.Raw, pod)
+func CheckIngress(eval *checks.Evaluation) []checks.Violation {
+	template, err := eval.PodTemplate()
 	if err != nil {
 		log.Println("Failed to parse pod object:", err)
-		return false
+		return []checks.Violation{checks.ErrorViolation("failed_to_parse_pod", err)}
 	}
+	pod := template.Pod
 
-	ingressPolicy, err := getIngressPolicy()
+	policies, err := eval.Policies()
 	if err != nil {
 		log.Println("Failed to load ingress policy:", err)
-		return false
+		return []checks.Violation{checks.ErrorViolation("failed_to_load_policy", err)}
 	}
+	ingressPolicy := &policies.NetworkSecurity.IngressPolicy
 
-	for _, ingressCIDR := range ingressPolicy.AllowedIngressCIDRs {
-		if !isIngressAllowed(pod, ingressCIDR) {
-			log.Printf("Pod %s in namespace %s has an ingress route that violates policy\n", pod.Name, pod.Namespace)
-			return false
-		}
+	// Nothing to enforce when no ingress CIDRs are configured; an empty list allows no ingress at all
+	if ingressPolicy.AllowedIngressCIDRs == nil {
+		return nil
 	}
-	return true
-}
 
-func getIngressPolicy() (*IngressPolicy, error) {
-	configPath := os.Getenv("SECURITY_POLICIES_PATH")
-	if configPath == "" {
-		configPath = "configs/security-policies.yaml"
-	}
-	data, err := os.ReadFile(configPath)
-	if err != nil {
-		return nil, err
+	violations := validateIngressIPs(template, ingressPolicy.AllowedIngressCIDRs)
+	if len(violations) > 0 {
+		log.Printf("Pod %s in namespace %s has an ingress route that violates policy\n", pod.Name, pod.Namespace)
 	}
-	var policies struct {
-		IngressPolicy IngressPolicy `yaml:"ingressPolicy"`
-	}
-	err = yaml.Unmarshal(data, &policies)
-	return &policies.IngressPolicy, err
+	return violations
 }
 
-// isIngressAllowed checks if the pod's ingress traffic is within the allowed CIDR ranges
-func isIngressAllowed(pod *corev1.Pod, ingressCIDR string) bool {
-	// Parse the allowed ingress CIDR
-	_, allowedCIDR, err := net.ParseCIDR(ingressCIDR)
-	if err != nil {
-		log.Printf("Invalid CIDR format: %s\n", ingressCIDR)
-		return false
+// validateIngressIPs checks that every ingress IP of the pod (template) is within one of the allowed CIDR ranges
+func validateIngressIPs(template *workload.PodTemplate, allowedIngressCIDRs []string) []checks.Violation {
+	pod := template.Pod
+	field := template.MetadataPath + ".annotations[ingressIPs]"
+	allowedRanges := strings.Join(allowedIngressCIDRs, ", ")
+	if len(allowedIngressCIDRs) == 0 {
+		allowedRanges = "no allowed range"
+	}
+	var violations []checks.Violation
+
+	// Parse the allowed ingress CIDRs
+	var allowedNets []*net.IPNet
+	for _, ingressCIDR := range allowedIngressCIDRs {
+		_, allowedCIDR, err := net.ParseCIDR(ingressCIDR)
+		if err != nil {
+			log.Printf("Invalid CIDR format: %s\n", ingressCIDR)
+			violations = append(violations, checks.Violation{
+				Reason:   "invalid_cidr_format",
+				Field:    "allowedIngressCIDRs",
+				Value:    ingressCIDR,
+				Expected: "a valid CIDR",
+				Message:  "ingress policy contains an invalid CIDR",
+			})
+			continue
+		}
+		allowedNets = append(allowedNets, allowedCIDR)
 	}
 
 	// Check pod annotations for ingress IPs (assuming annotations are used to specify ingress IPs)
 	ingressIPs, ok := pod.Annotations["ingressIPs"]
 	if !ok {
 		log.Printf("Pod %s in namespace %s does not have ingress IPs specified\n", pod.Name, pod.Namespace)
-		return false
+		return append(violations, checks.Violation{
+			Reason:   "missing_ingress_ips",
+			Field:    field,
+			Expected: "comma separated IPs within " + allowedRanges,
+			Message:  "pod does not declare its ingress IPs in the ingressIPs annotation",
+		})
 	}
 
 	// Split the ingress IPs and check each one
 	for _, ip := range strings.Split(ingressIPs, ",") {
+		ip = strings.TrimSpace(ip)
 		parsedIP := net.ParseIP(ip)
 		if parsedIP == nil {
 			log.Printf("Invalid IP format: %s\n", ip)
-			return false
+			violations = append(violations, checks.Violation{
+				Reason:   "invalid_ip_format",
+				Field:    field,
+				Value:    ip,
+				Expected: "a valid IP address",
+				Message:  fmt.Sprintf("ingress IP %q is not a valid IP address", ip),
+			})
+			continue
 		}
 
-		// Check if the IP is within the allowed CIDR range
-		if !allowedCIDR.Contains(parsedIP) {
-			log.Printf("IP %s is not within the allowed CIDR range %s\n", ip, ingressCIDR)
-			return false
+		// Check if the IP is within one of the allowed CIDR ranges
+		allowed := false
+		for _, allowedCIDR := range allowedNets {
+			if allowedCIDR.Contains(parsedIP) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			log.Printf("IP %s is not within any allowed ingress CIDR range\n", ip)
+			violations = append(violations, checks.Violation{
+				Reason:   "ip_not_in_allowed_cidrs",
+				Field:    field,
+				Value:    ip,
+				Expected: "within " + allowedRanges,
+				Message:  fmt.Sprintf("ingress IP %s is not within any allowed CIDR range", ip),
+			})
 		}
 	}
 
-	return true
+	return violations
 }
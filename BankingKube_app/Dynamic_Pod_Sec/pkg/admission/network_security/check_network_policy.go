@@ -1,70 +1,47 @@
 package network_security
 
 import (
-	"encoding/json"
+	"fmt"
 	"log"
-	"os"
 
-	"gopkg.in/yaml.v2"
-	admissionv1 "k8s.io/api/admission/v1"
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/checks"
 	corev1 "k8s.io/api/core/v1"
 )
 
-// NetworkPolicy defines a structure for network policies
-type NetworkPolicy struct {
-	RequiredNetworkPolicies []string `yaml:"requiredNetworkPolicies"`
-}
-
 // CheckNetworkPolicy validates if a pod is using the required network policies
-func CheckNetworkPolicy(request *admissionv1.AdmissionRequest) bool {
-	// Parse the Pod object from the request
-	pod := &corev1.Pod{}
-	err := json.Unmarshal(request.Object.Raw, pod)
+func CheckNetworkPolicy(eval *checks.Evaluation) []checks.Violation {
+	// Extract the pod, or the pod template of a workload, from the request
+	template, err := eval.PodTemplate()
 	if err != nil {
 		log.Println("Failed to parse pod object:", err)
-		return false // Fails the validation if the pod can't be parsed
+		return []checks.Violation{checks.ErrorViolation("failed_to_parse_pod", err)} // Fails the validation if the pod can't be parsed
 	}
+	pod := template.Pod
 
 	// Retrieve the network policies
-	networkPolicy, err := getNetworkPolicy()
+	policies, err := eval.Policies()
 	if err != nil {
 		log.Println("Failed to load network policies:", err)
-		return false
+		return []checks.Violation{checks.ErrorViolation("failed_to_load_policies", err)}
 	}
+	networkPolicy := &policies.NetworkSecurity.NetworkPolicy
 
 	// Check if the pod's network policies are in the required list
+	var violations []checks.Violation
 	for _, policy := range networkPolicy.RequiredNetworkPolicies {
 		if !isNetworkPolicyPresent(pod, policy) {
 			log.Printf("Pod %s in namespace %s is missing required network policy: %s\n", pod.Name, pod.Namespace, policy)
-			return false
+			violations = append(violations, checks.Violation{
+				Reason:   "missing_network_policy",
+				Field:    template.MetadataPath + ".annotations[k8s.v1.cni.cncf.io/networks]",
+				Value:    pod.Annotations["k8s.v1.cni.cncf.io/networks"],
+				Expected: policy,
+				Message:  fmt.Sprintf("pod is missing required network policy %q", policy),
+			})
 		}
 	}
 
-	return true // Passes the check if all required network policies are present
-}
-
-// getNetworkPolicy loads the network policies from the configuration file
-func getNetworkPolicy() (*NetworkPolicy, error) {
-	configPath := os.Getenv("SECURITY_POLICIES_PATH")
-	if configPath == "" {
-		configPath = "configs/security-policies.yaml" // Default path
-	}
-
-	data, err := os.ReadFile(configPath)
-	if err != nil {
-		return nil, err
-	}
-
-	var policies struct {
-		NetworkPolicy NetworkPolicy `yaml:"networkPolicy"`
-	}
-
-	err = yaml.Unmarshal(data, &policies)
-	if err != nil {
-		return nil, err
-	}
-
-	return &policies.NetworkPolicy, nil
+	return violations // Passes the check if all required network policies are present
 }
 
 // isNetworkPolicyPresent checks if a network policy is present in the pod's annotations
@@ -1,99 +1,87 @@
 package network_security
 
 import (
+	"fmt"
 	"log"
 	"net"
-	"os"
 
-	"gopkg.in/yaml.v2"
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/checks"
 )
 
-// Defines a structure for egress network policies
-type accessEgressPolicy struct {
-	AllowedEgressCIDRs []string `yaml:"allowedEgressCIDRs"`
-}
-
-// Defines a structure for ingress network policies
-type accessIngressPolicy struct {
-	AllowedIngressCIDRs []string `yaml:"allowedIngressCIDRs"`
-}
-
-// Defines a structure for allowed overlapping egress CIDRs
-type AllowedOverlappingEgressCIDRs struct {
-	CIDRs []string `yaml:"allowedOverlappingEgressCIDRs"`
-}
-
-// Defines a structure for allowed overlapping ingress CIDRs
-type AllowedOverlappingIngressCIDRs struct {
-	CIDRs []string `yaml:"allowedOverlappingIngressCIDRs"`
-}
-
-// ConsistencyPolicy defines the structure for consistency checks in network policies
-type ConsistencyPolicy struct {
-	EgressPolicy                   accessEgressPolicy             `yaml:"accessEgressPolicy"`
-	IngressPolicy                  accessIngressPolicy            `yaml:"accessIngressPolicy"`
-	AllowedOverlappingEgressCIDRs  AllowedOverlappingEgressCIDRs  `yaml:"allowedOverlappingEgressCIDRs"`
-	AllowedOverlappingIngressCIDRs AllowedOverlappingIngressCIDRs `yaml:"allowedOverlappingIngressCIDRs"`
-}
-
 // CheckPolicyConsistency validates the consistency of security policies
-func CheckPolicyConsistency() bool {
-	policies, err := getConsistencyPolicy()
+func CheckPolicyConsistency(eval *checks.Evaluation) []checks.Violation {
+	policies, err := eval.Policies()
 	if err != nil {
 		log.Println("Failed to load consistency policies:", err)
-		return false
+		return []checks.Violation{checks.ErrorViolation("failed_to_load_policies", err)}
 	}
+	consistency := &policies.NetworkSecurity.ConsistencyPolicy
 
-	if !checkCIDRConsistency(policies.EgressPolicy.AllowedEgressCIDRs, policies.IngressPolicy.AllowedIngressCIDRs, policies.AllowedOverlappingEgressCIDRs.CIDRs, policies.AllowedOverlappingIngressCIDRs.CIDRs) {
+	violations := checkCIDRConsistency(consistency.AccessEgressPolicy.AllowedEgressCIDRs, consistency.AccessIngressPolicy.AllowedIngressCIDRs, consistency.AllowedOverlappingEgressCIDRs, consistency.AllowedOverlappingIngressCIDRs)
+	if len(violations) > 0 {
 		log.Println("CIDR ranges in egress and ingress policies are inconsistent")
-		return false
+		return violations
 	}
 
 	log.Println("Security policies are consistent")
-	return true
+	return nil
 }
 
-// getConsistencyPolicy loads the consistency policy from the configuration file
-func getConsistencyPolicy() (*ConsistencyPolicy, error) {
-	configPath := os.Getenv("SECURITY_POLICIES_PATH")
-	if configPath == "" {
-		configPath = "configs/security-policies.yaml"
-	}
-	data, err := os.ReadFile(configPath)
-	if err != nil {
-		return nil, err
-	}
-	var policies struct {
-		ConsistencyPolicy ConsistencyPolicy `yaml:"consistencyPolicy"`
+// checkCIDRConsistency checks for overlapping CIDR ranges between egress and ingress policies
+func checkCIDRConsistency(egressCIDRs, ingressCIDRs, allowedEgressOverlaps, allowedIngressOverlaps []string) []checks.Violation {
+	var violations []checks.Violation
+
+	ingressNets := make(map[string]*net.IPNet, len(ingressCIDRs))
+	for _, ingressCIDR := range ingressCIDRs {
+		_, ingressNet, err := net.ParseCIDR(ingressCIDR)
+		if err != nil {
+			log.Printf("Invalid ingress CIDR format: %s\n", ingressCIDR)
+			violations = append(violations, checks.Violation{
+				Reason:   "invalid_cidr_format",
+				Field:    "consistencyPolicy.accessIngressPolicy.allowedIngressCIDRs",
+				Value:    ingressCIDR,
+				Expected: "a valid CIDR",
+				Message:  "ingress consistency policy contains an invalid CIDR",
+			})
+			continue
+		}
+		ingressNets[ingressCIDR] = ingressNet
 	}
-	err = yaml.Unmarshal(data, &policies)
-	return &policies.ConsistencyPolicy, err
-}
 
-// checkCIDRConsistency checks for overlapping CIDR ranges between egress and ingress policies
-func checkCIDRConsistency(egressCIDRs, ingressCIDRs, allowedEgressOverlaps, allowedIngressOverlaps []string) bool {
 	for _, egressCIDR := range egressCIDRs {
 		_, egressNet, err := net.ParseCIDR(egressCIDR)
 		if err != nil {
 			log.Printf("Invalid egress CIDR format: %s\n", egressCIDR)
-			return false
+			violations = append(violations, checks.Violation{
+				Reason:   "invalid_cidr_format",
+				Field:    "consistencyPolicy.accessEgressPolicy.allowedEgressCIDRs",
+				Value:    egressCIDR,
+				Expected: "a valid CIDR",
+				Message:  "egress consistency policy contains an invalid CIDR",
+			})
+			continue
 		}
 		for _, ingressCIDR := range ingressCIDRs {
-			_, ingressNet, err := net.ParseCIDR(ingressCIDR)
-			if err != nil {
-				log.Printf("Invalid ingress CIDR format: %s\n", ingressCIDR)
-				return false
+			ingressNet, ok := ingressNets[ingressCIDR]
+			if !ok {
+				continue
 			}
 			if isAllowedOverlap(egressCIDR, ingressCIDR, allowedEgressOverlaps, allowedIngressOverlaps) {
 				continue
 			}
 			if egressNet.Contains(ingressNet.IP) || ingressNet.Contains(egressNet.IP) {
 				log.Printf("Overlapping CIDR ranges found: %s and %s\n", egressCIDR, ingressCIDR)
-				return false
+				violations = append(violations, checks.Violation{
+					Reason:   "overlapping_cidrs",
+					Field:    "consistencyPolicy",
+					Value:    egressCIDR + " / " + ingressCIDR,
+					Expected: "non-overlapping egress and ingress CIDRs, or an allowed overlap",
+					Message:  fmt.Sprintf("egress CIDR %s overlaps ingress CIDR %s", egressCIDR, ingressCIDR),
+				})
 			}
 		}
 	}
-	return true
+	return violations
 }
 
 // isAllowedOverlap checks if the overlap between egress and ingress CIDRs is allowed
@@ -0,0 +1,19 @@
+package network_security
+
+import (
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/checks"
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/workload"
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/policy"
+)
+
+// Group is the check group served on /validate/network
+const Group = "network"
+
+// policy_consistency only reports on the policy file itself, so a file it cannot read must not block workloads
+func init() {
+	checks.Register(Group, checks.NewCheck("policy_consistency", workload.Kinds, checks.WithoutContext(CheckPolicyConsistency), checks.OnError(policy.ErrorPolicyWarn)))
+	checks.Register(Group, checks.NewCheck("network_policy", workload.Kinds, checks.WithoutContext(CheckNetworkPolicy)))
+	checks.Register(Group, checks.NewCheck("host_network", workload.Kinds, checks.WithoutContext(CheckHostNetwork)))
+	checks.Register(Group, checks.NewCheck("egress", workload.Kinds, CheckEgress))
+	checks.Register(Group, checks.NewCheck("ingress", workload.Kinds, checks.WithoutContext(CheckIngress)))
+}
@@ -0,0 +1,308 @@
+package pod_security_standards
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/checks"
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/workload"
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/policy"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// baselineControls prevent known privilege escalations
+var baselineControls = []Control{
+	{ID: "pss_host_process", Level: policy.ProfileBaseline, Check: checkHostProcess},
+	{ID: "pss_host_namespaces", Level: policy.ProfileBaseline, Check: checkHostNamespaces},
+	{ID: "pss_privileged", Level: policy.ProfileBaseline, Check: checkPrivileged},
+	{ID: "pss_capabilities_baseline", Level: policy.ProfileBaseline, ReplacedIn: policy.ProfileRestricted, Check: checkBaselineCapabilities},
+	{ID: "pss_host_path_volumes", Level: policy.ProfileBaseline, Check: checkHostPathVolumes},
+	{ID: "pss_host_ports", Level: policy.ProfileBaseline, Check: checkHostPorts},
+	{ID: "pss_apparmor", Level: policy.ProfileBaseline, Check: checkAppArmor},
+	{ID: "pss_selinux", Level: policy.ProfileBaseline, Check: checkSELinux},
+	{ID: "pss_proc_mount", Level: policy.ProfileBaseline, Check: checkProcMount},
+	{ID: "pss_seccomp_baseline", Level: policy.ProfileBaseline, ReplacedIn: policy.ProfileRestricted, Check: checkBaselineSeccomp},
+	{ID: "pss_sysctls", Level: policy.ProfileBaseline, Check: checkSysctls},
+}
+
+// baselineCapabilities are the capabilities baseline allows containers to add
+var baselineCapabilities = map[string]bool{
+	"AUDIT_WRITE": true, "CHOWN": true, "DAC_OVERRIDE": true, "FOWNER": true, "FSETID": true, "KILL": true, "MKNOD": true,
+	"NET_BIND_SERVICE": true, "SETFCAP": true, "SETGID": true, "SETPCAP": true, "SETUID": true, "SYS_CHROOT": true,
+}
+
+// seLinuxTypes are the SELinux types baseline allows
+var seLinuxTypes = map[string]bool{"": true, "container_t": true, "container_init_t": true, "container_kvm_t": true, "container_engine_t": true}
+
+// safeSysctls are the namespaced sysctls that cannot affect other pods on the node
+var safeSysctls = map[string]bool{
+	"kernel.shm_rmid_forced":              true,
+	"net.ipv4.ip_local_port_range":        true,
+	"net.ipv4.ip_local_reserved_ports":    true,
+	"net.ipv4.ip_unprivileged_port_start": true,
+	"net.ipv4.ping_group_range":           true,
+	"net.ipv4.tcp_syncookies":             true,
+	"net.ipv4.tcp_keepalive_time":         true,
+	"net.ipv4.tcp_fin_timeout":            true,
+	"net.ipv4.tcp_keepalive_intvl":        true,
+	"net.ipv4.tcp_keepalive_probes":       true,
+}
+
+// appArmorAnnotationPrefix is the prefix of the per-container AppArmor annotations
+const appArmorAnnotationPrefix = "container.apparmor.security.beta.kubernetes.io/"
+
+// checkHostProcess denies Windows HostProcess containers
+func checkHostProcess(template *workload.PodTemplate) []checks.Violation {
+	spec := &template.Pod.Spec
+	var violations []checks.Violation
+	if sc := spec.SecurityContext; sc != nil && sc.WindowsOptions != nil && sc.WindowsOptions.HostProcess != nil && *sc.WindowsOptions.HostProcess {
+		violations = append(violations, checks.Violation{
+			Reason:   "host_process",
+			Field:    template.SpecPath + ".securityContext.windowsOptions.hostProcess",
+			Value:    "true",
+			Expected: "unset or false",
+			Message:  "pod runs as a Windows HostProcess pod",
+		})
+	}
+	for _, ref := range checks.PodContainers(spec, template.SpecPath) {
+		if sc := ref.Container.SecurityContext; sc != nil && sc.WindowsOptions != nil && sc.WindowsOptions.HostProcess != nil && *sc.WindowsOptions.HostProcess {
+			violations = append(violations, containerViolation(ref, "host_process", ".securityContext.windowsOptions.hostProcess", "true", "unset or false",
+				fmt.Sprintf("%s container %q runs as a Windows HostProcess container", ref.Type, ref.Container.Name)))
+		}
+	}
+	return violations
+}
+
+// checkHostNamespaces denies sharing the host network, PID and IPC namespaces
+func checkHostNamespaces(template *workload.PodTemplate) []checks.Violation {
+	spec := &template.Pod.Spec
+	var violations []checks.Violation
+	for _, namespace := range []struct {
+		field string
+		set   bool
+	}{
+		{"hostNetwork", spec.HostNetwork},
+		{"hostPID", spec.HostPID},
+		{"hostIPC", spec.HostIPC},
+	} {
+		if namespace.set {
+			violations = append(violations, checks.Violation{
+				Reason:   "host_namespace",
+				Field:    template.SpecPath + "." + namespace.field,
+				Value:    "true",
+				Expected: "unset or false",
+				Message:  fmt.Sprintf("pod sets %s", namespace.field),
+			})
+		}
+	}
+	return violations
+}
+
+// checkPrivileged denies privileged containers
+func checkPrivileged(template *workload.PodTemplate) []checks.Violation {
+	var violations []checks.Violation
+	for _, ref := range checks.PodContainers(&template.Pod.Spec, template.SpecPath) {
+		if sc := ref.Container.SecurityContext; sc != nil && sc.Privileged != nil && *sc.Privileged {
+			violations = append(violations, containerViolation(ref, "privileged", ".securityContext.privileged", "true", "unset or false",
+				fmt.Sprintf("%s container %q is privileged", ref.Type, ref.Container.Name)))
+		}
+	}
+	return violations
+}
+
+// checkBaselineCapabilities denies adding capabilities beyond the default set of the container runtimes
+func checkBaselineCapabilities(template *workload.PodTemplate) []checks.Violation {
+	var violations []checks.Violation
+	for _, ref := range checks.PodContainers(&template.Pod.Spec, template.SpecPath) {
+		sc := ref.Container.SecurityContext
+		if sc == nil || sc.Capabilities == nil {
+			continue
+		}
+		for i, capability := range sc.Capabilities.Add {
+			if !baselineCapabilities[policy.CapabilityName(string(capability))] {
+				violations = append(violations, containerViolation(ref, "capability_added", fmt.Sprintf(".securityContext.capabilities.add[%d]", i),
+					string(capability), "one of the capabilities of the baseline profile",
+					fmt.Sprintf("%s container %q adds capability %s", ref.Type, ref.Container.Name, capability)))
+			}
+		}
+	}
+	return violations
+}
+
+// checkHostPathVolumes denies hostPath volumes
+func checkHostPathVolumes(template *workload.PodTemplate) []checks.Violation {
+	var violations []checks.Violation
+	for i, volume := range template.Pod.Spec.Volumes {
+		if volume.HostPath != nil {
+			violations = append(violations, checks.Violation{
+				Reason:   "host_path_volume",
+				Field:    fmt.Sprintf("%s.volumes[%d].hostPath", template.SpecPath, i),
+				Value:    volume.HostPath.Path,
+				Expected: "no hostPath volumes",
+				Message:  fmt.Sprintf("volume %q mounts host path %s", volume.Name, volume.HostPath.Path),
+			})
+		}
+	}
+	return violations
+}
+
+// checkHostPorts denies binding container ports to the node
+func checkHostPorts(template *workload.PodTemplate) []checks.Violation {
+	var violations []checks.Violation
+	for _, ref := range checks.PodContainers(&template.Pod.Spec, template.SpecPath) {
+		for i, port := range ref.Container.Ports {
+			if port.HostPort != 0 {
+				violations = append(violations, containerViolation(ref, "host_port", fmt.Sprintf(".ports[%d].hostPort", i),
+					strconv.Itoa(int(port.HostPort)), "unset or 0",
+					fmt.Sprintf("%s container %q binds host port %d", ref.Type, ref.Container.Name, port.HostPort)))
+			}
+		}
+	}
+	return violations
+}
+
+// checkAppArmor denies overriding or disabling the default AppArmor profile
+func checkAppArmor(template *workload.PodTemplate) []checks.Violation {
+	spec := &template.Pod.Spec
+	var violations []checks.Violation
+	var names []string
+	for name := range template.Pod.Annotations {
+		if strings.HasPrefix(name, appArmorAnnotationPrefix) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value := template.Pod.Annotations[name]
+		if value != "" && value != corev1.DeprecatedAppArmorBetaProfileRuntimeDefault && !strings.HasPrefix(value, corev1.DeprecatedAppArmorBetaProfileNamePrefix) {
+			violations = append(violations, checks.Violation{
+				Reason:   "apparmor_profile",
+				Field:    fmt.Sprintf("%s.annotations[%s]", template.MetadataPath, name),
+				Value:    value,
+				Expected: "runtime/default or localhost/*",
+				Message:  fmt.Sprintf("annotation %s sets AppArmor profile %s", name, value),
+			})
+		}
+	}
+	if sc := spec.SecurityContext; sc != nil && sc.AppArmorProfile != nil && sc.AppArmorProfile.Type == corev1.AppArmorProfileTypeUnconfined {
+		violations = append(violations, checks.Violation{
+			Reason:   "apparmor_profile",
+			Field:    template.SpecPath + ".securityContext.appArmorProfile.type",
+			Value:    string(corev1.AppArmorProfileTypeUnconfined),
+			Expected: "RuntimeDefault or Localhost",
+			Message:  "pod runs without an AppArmor profile",
+		})
+	}
+	for _, ref := range checks.PodContainers(spec, template.SpecPath) {
+		if sc := ref.Container.SecurityContext; sc != nil && sc.AppArmorProfile != nil && sc.AppArmorProfile.Type == corev1.AppArmorProfileTypeUnconfined {
+			violations = append(violations, containerViolation(ref, "apparmor_profile", ".securityContext.appArmorProfile.type",
+				string(corev1.AppArmorProfileTypeUnconfined), "RuntimeDefault or Localhost",
+				fmt.Sprintf("%s container %q runs without an AppArmor profile", ref.Type, ref.Container.Name)))
+		}
+	}
+	return violations
+}
+
+// checkSELinux denies custom SELinux users and roles and types other than the container types
+func checkSELinux(template *workload.PodTemplate) []checks.Violation {
+	spec := &template.Pod.Spec
+	var violations []checks.Violation
+	check := func(options *corev1.SELinuxOptions, field, subject string, ref *checks.ContainerRef) {
+		if options == nil {
+			return
+		}
+		for _, option := range []struct {
+			name, value string
+			allowed     bool
+			expected    string
+		}{
+			{"type", options.Type, seLinuxTypes[options.Type], "unset or one of container_t, container_init_t, container_kvm_t, container_engine_t"},
+			{"user", options.User, options.User == "", "unset"},
+			{"role", options.Role, options.Role == "", "unset"},
+		} {
+			if option.allowed {
+				continue
+			}
+			v := checks.Violation{
+				Reason:   "selinux_" + option.name,
+				Field:    field + ".seLinuxOptions." + option.name,
+				Value:    option.value,
+				Expected: option.expected,
+				Message:  fmt.Sprintf("%s sets SELinux %s %s", subject, option.name, option.value),
+			}
+			if ref != nil {
+				v.Container = ref.Container.Name
+			}
+			violations = append(violations, v)
+		}
+	}
+
+	if spec.SecurityContext != nil {
+		check(spec.SecurityContext.SELinuxOptions, template.SpecPath+".securityContext", "pod", nil)
+	}
+	for _, ref := range checks.PodContainers(spec, template.SpecPath) {
+		if sc := ref.Container.SecurityContext; sc != nil {
+			check(sc.SELinuxOptions, ref.Path+".securityContext", fmt.Sprintf("%s container %q", ref.Type, ref.Container.Name), &ref)
+		}
+	}
+	return violations
+}
+
+// checkProcMount denies unmasked /proc mounts
+func checkProcMount(template *workload.PodTemplate) []checks.Violation {
+	var violations []checks.Violation
+	for _, ref := range checks.PodContainers(&template.Pod.Spec, template.SpecPath) {
+		if sc := ref.Container.SecurityContext; sc != nil && sc.ProcMount != nil && *sc.ProcMount != corev1.DefaultProcMount {
+			violations = append(violations, containerViolation(ref, "proc_mount", ".securityContext.procMount", string(*sc.ProcMount), "unset or Default",
+				fmt.Sprintf("%s container %q uses procMount %s", ref.Type, ref.Container.Name, *sc.ProcMount)))
+		}
+	}
+	return violations
+}
+
+// checkBaselineSeccomp denies disabling seccomp
+func checkBaselineSeccomp(template *workload.PodTemplate) []checks.Violation {
+	spec := &template.Pod.Spec
+	var violations []checks.Violation
+	if sc := spec.SecurityContext; sc != nil && sc.SeccompProfile != nil && sc.SeccompProfile.Type == corev1.SeccompProfileTypeUnconfined {
+		violations = append(violations, checks.Violation{
+			Reason:   "seccomp_unconfined",
+			Field:    template.SpecPath + ".securityContext.seccompProfile.type",
+			Value:    string(corev1.SeccompProfileTypeUnconfined),
+			Expected: "unset, RuntimeDefault or Localhost",
+			Message:  "pod runs without a seccomp profile",
+		})
+	}
+	for _, ref := range checks.PodContainers(spec, template.SpecPath) {
+		if sc := ref.Container.SecurityContext; sc != nil && sc.SeccompProfile != nil && sc.SeccompProfile.Type == corev1.SeccompProfileTypeUnconfined {
+			violations = append(violations, containerViolation(ref, "seccomp_unconfined", ".securityContext.seccompProfile.type",
+				string(corev1.SeccompProfileTypeUnconfined), "unset, RuntimeDefault or Localhost",
+				fmt.Sprintf("%s container %q runs without a seccomp profile", ref.Type, ref.Container.Name)))
+		}
+	}
+	return violations
+}
+
+// checkSysctls denies sysctls outside the safe set
+func checkSysctls(template *workload.PodTemplate) []checks.Violation {
+	sc := template.Pod.Spec.SecurityContext
+	if sc == nil {
+		return nil
+	}
+	var violations []checks.Violation
+	for i, sysctl := range sc.Sysctls {
+		if !safeSysctls[sysctl.Name] {
+			violations = append(violations, checks.Violation{
+				Reason:   "unsafe_sysctl",
+				Field:    fmt.Sprintf("%s.securityContext.sysctls[%d].name", template.SpecPath, i),
+				Value:    sysctl.Name,
+				Expected: "a safe sysctl",
+				Message:  fmt.Sprintf("pod sets sysctl %s, which is not in the safe set", sysctl.Name),
+			})
+		}
+	}
+	return violations
+}
@@ -0,0 +1,15 @@
+package pod_security_standards
+
+import (
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/checks"
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/workload"
+)
+
+// Group is the check group served on /validate/pss
+const Group = "pss"
+
+func init() {
+	for i := range Controls {
+		checks.Register(Group, checks.NewCheck(Controls[i].ID, workload.Kinds, Controls[i].Validate))
+	}
+}
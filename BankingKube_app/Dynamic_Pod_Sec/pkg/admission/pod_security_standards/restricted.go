@@ -0,0 +1,232 @@
+package pod_security_standards
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/checks"
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/workload"
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/policy"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// restrictedControls enforce current pod hardening best practices on top of baseline
+var restrictedControls = []Control{
+	{ID: "pss_volume_types", Level: policy.ProfileRestricted, Check: checkVolumeTypes},
+	{ID: "pss_privilege_escalation", Level: policy.ProfileRestricted, Check: checkPrivilegeEscalation},
+	{ID: "pss_run_as_non_root", Level: policy.ProfileRestricted, Check: checkRunAsNonRoot},
+	{ID: "pss_run_as_user", Level: policy.ProfileRestricted, Check: checkRunAsUser},
+	{ID: "pss_seccomp_restricted", Level: policy.ProfileRestricted, Check: checkRestrictedSeccomp},
+	{ID: "pss_capabilities_restricted", Level: policy.ProfileRestricted, Check: checkRestrictedCapabilities},
+}
+
+// restrictedVolumeTypes are the volume sources restricted allows
+var restrictedVolumeTypes = map[string]bool{
+	"configMap": true, "csi": true, "downwardAPI": true, "emptyDir": true, "ephemeral": true,
+	"persistentVolumeClaim": true, "projected": true, "secret": true,
+}
+
+// checkVolumeTypes denies volume sources other than the ones restricted allows
+func checkVolumeTypes(template *workload.PodTemplate) []checks.Violation {
+	var violations []checks.Violation
+	for i, volume := range template.Pod.Spec.Volumes {
+		// The JSON keys of a volume source are its types, e.g. hostPath or nfs
+		source, err := json.Marshal(volume.VolumeSource)
+		if err != nil {
+			return []checks.Violation{checks.ErrorViolation("failed_to_encode_volume", err)}
+		}
+		var types map[string]json.RawMessage
+		if err := json.Unmarshal(source, &types); err != nil {
+			return []checks.Violation{checks.ErrorViolation("failed_to_encode_volume", err)}
+		}
+		names := make([]string, 0, len(types))
+		for name := range types {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if restrictedVolumeTypes[name] {
+				continue
+			}
+			violations = append(violations, checks.Violation{
+				Reason:   "volume_type",
+				Field:    fmt.Sprintf("%s.volumes[%d].%s", template.SpecPath, i, name),
+				Value:    name,
+				Expected: "configMap, csi, downwardAPI, emptyDir, ephemeral, persistentVolumeClaim, projected or secret",
+				Message:  fmt.Sprintf("volume %q has type %s", volume.Name, name),
+			})
+		}
+	}
+	return violations
+}
+
+// checkPrivilegeEscalation requires every container to set allowPrivilegeEscalation to false
+func checkPrivilegeEscalation(template *workload.PodTemplate) []checks.Violation {
+	spec := &template.Pod.Spec
+	if windows(spec) {
+		return nil
+	}
+	var violations []checks.Violation
+	for _, ref := range checks.PodContainers(spec, template.SpecPath) {
+		sc := ref.Container.SecurityContext
+		if sc != nil && sc.AllowPrivilegeEscalation != nil && !*sc.AllowPrivilegeEscalation {
+			continue
+		}
+		value := "unset"
+		if sc != nil && sc.AllowPrivilegeEscalation != nil {
+			value = "true"
+		}
+		violations = append(violations, containerViolation(ref, "privilege_escalation", ".securityContext.allowPrivilegeEscalation", value, "false",
+			fmt.Sprintf("%s container %q does not set allowPrivilegeEscalation to false", ref.Type, ref.Container.Name)))
+	}
+	return violations
+}
+
+// checkRunAsNonRoot requires runAsNonRoot true on the pod or on every container, and denies false anywhere
+func checkRunAsNonRoot(template *workload.PodTemplate) []checks.Violation {
+	spec := &template.Pod.Spec
+	var violations []checks.Violation
+	podNonRoot := false
+	if sc := spec.SecurityContext; sc != nil && sc.RunAsNonRoot != nil {
+		podNonRoot = *sc.RunAsNonRoot
+		if !podNonRoot {
+			violations = append(violations, checks.Violation{
+				Reason:   "run_as_root",
+				Field:    template.SpecPath + ".securityContext.runAsNonRoot",
+				Value:    "false",
+				Expected: "true",
+				Message:  "pod sets runAsNonRoot to false",
+			})
+		}
+	}
+	for _, ref := range checks.PodContainers(spec, template.SpecPath) {
+		sc := ref.Container.SecurityContext
+		switch {
+		case sc != nil && sc.RunAsNonRoot != nil && !*sc.RunAsNonRoot:
+			violations = append(violations, containerViolation(ref, "run_as_root", ".securityContext.runAsNonRoot", "false", "true",
+				fmt.Sprintf("%s container %q sets runAsNonRoot to false", ref.Type, ref.Container.Name)))
+		case (sc == nil || sc.RunAsNonRoot == nil) && !podNonRoot:
+			v := containerViolation(ref, "run_as_non_root_unset", ".securityContext.runAsNonRoot", "unset", "true on the pod or the container",
+				fmt.Sprintf("%s container %q does not set runAsNonRoot, and neither does the pod", ref.Type, ref.Container.Name))
+			v.Related = []string{template.SpecPath + ".securityContext.runAsNonRoot"}
+			violations = append(violations, v)
+		}
+	}
+	return violations
+}
+
+// checkRunAsUser denies running as UID 0
+func checkRunAsUser(template *workload.PodTemplate) []checks.Violation {
+	spec := &template.Pod.Spec
+	var violations []checks.Violation
+	if sc := spec.SecurityContext; sc != nil && sc.RunAsUser != nil && *sc.RunAsUser == 0 {
+		violations = append(violations, checks.Violation{
+			Reason:   "run_as_root_user",
+			Field:    template.SpecPath + ".securityContext.runAsUser",
+			Value:    "0",
+			Expected: "a non-zero UID",
+			Message:  "pod runs as UID 0",
+		})
+	}
+	for _, ref := range checks.PodContainers(spec, template.SpecPath) {
+		if sc := ref.Container.SecurityContext; sc != nil && sc.RunAsUser != nil && *sc.RunAsUser == 0 {
+			violations = append(violations, containerViolation(ref, "run_as_root_user", ".securityContext.runAsUser", "0", "a non-zero UID",
+				fmt.Sprintf("%s container %q runs as UID 0", ref.Type, ref.Container.Name)))
+		}
+	}
+	return violations
+}
+
+// checkRestrictedSeccomp requires a RuntimeDefault or Localhost seccomp profile on the pod or on every container
+func checkRestrictedSeccomp(template *workload.PodTemplate) []checks.Violation {
+	spec := &template.Pod.Spec
+	if windows(spec) {
+		return nil
+	}
+	allowed := func(profile *corev1.SeccompProfile) bool {
+		return profile.Type == corev1.SeccompProfileTypeRuntimeDefault || profile.Type == corev1.SeccompProfileTypeLocalhost
+	}
+	const expected = "RuntimeDefault or Localhost"
+
+	var violations []checks.Violation
+	podProfile := false
+	if sc := spec.SecurityContext; sc != nil && sc.SeccompProfile != nil {
+		podProfile = allowed(sc.SeccompProfile)
+		if !podProfile {
+			violations = append(violations, checks.Violation{
+				Reason:   "seccomp_profile",
+				Field:    template.SpecPath + ".securityContext.seccompProfile.type",
+				Value:    string(sc.SeccompProfile.Type),
+				Expected: expected,
+				Message:  fmt.Sprintf("pod sets seccomp profile %s", sc.SeccompProfile.Type),
+			})
+		}
+	}
+	for _, ref := range checks.PodContainers(spec, template.SpecPath) {
+		sc := ref.Container.SecurityContext
+		switch {
+		case sc != nil && sc.SeccompProfile != nil && !allowed(sc.SeccompProfile):
+			violations = append(violations, containerViolation(ref, "seccomp_profile", ".securityContext.seccompProfile.type", string(sc.SeccompProfile.Type), expected,
+				fmt.Sprintf("%s container %q sets seccomp profile %s", ref.Type, ref.Container.Name, sc.SeccompProfile.Type)))
+		case (sc == nil || sc.SeccompProfile == nil) && !podProfile:
+			v := containerViolation(ref, "seccomp_profile_unset", ".securityContext.seccompProfile", "unset", expected+" on the pod or the container",
+				fmt.Sprintf("%s container %q has no seccomp profile, and neither does the pod", ref.Type, ref.Container.Name))
+			v.Related = []string{template.SpecPath + ".securityContext.seccompProfile"}
+			violations = append(violations, v)
+		}
+	}
+	return violations
+}
+
+// checkRestrictedCapabilities requires every container to drop ALL and to add nothing but NET_BIND_SERVICE
+func checkRestrictedCapabilities(template *workload.PodTemplate) []checks.Violation {
+	spec := &template.Pod.Spec
+	if windows(spec) {
+		return nil
+	}
+	var violations []checks.Violation
+	for _, ref := range checks.PodContainers(spec, template.SpecPath) {
+		var capabilities *corev1.Capabilities
+		if sc := ref.Container.SecurityContext; sc != nil {
+			capabilities = sc.Capabilities
+		}
+		if capabilities == nil {
+			capabilities = &corev1.Capabilities{}
+		}
+
+		dropsAll := false
+		for _, capability := range capabilities.Drop {
+			if policy.CapabilityName(string(capability)) == policy.CapabilityAll {
+				dropsAll = true
+			}
+		}
+		if !dropsAll {
+			violations = append(violations, containerViolation(ref, "capabilities_not_dropped", ".securityContext.capabilities.drop",
+				capabilityList(capabilities.Drop), "ALL",
+				fmt.Sprintf("%s container %q does not drop ALL capabilities", ref.Type, ref.Container.Name)))
+		}
+		for i, capability := range capabilities.Add {
+			if policy.CapabilityName(string(capability)) != "NET_BIND_SERVICE" {
+				violations = append(violations, containerViolation(ref, "capability_added", ".securityContext.capabilities.add["+strconv.Itoa(i)+"]",
+					string(capability), "only NET_BIND_SERVICE",
+					fmt.Sprintf("%s container %q adds capability %s", ref.Type, ref.Container.Name, capability)))
+			}
+		}
+	}
+	return violations
+}
+
+func capabilityList(capabilities []corev1.Capability) string {
+	if len(capabilities) == 0 {
+		return "none"
+	}
+	names := make([]string, len(capabilities))
+	for i, capability := range capabilities {
+		names[i] = string(capability)
+	}
+	return strings.Join(names, ", ")
+}
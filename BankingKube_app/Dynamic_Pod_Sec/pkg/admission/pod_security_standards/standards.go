@@ -0,0 +1,127 @@
+// Package pod_security_standards implements the controls of the Kubernetes Pod Security Standards
+// (https://kubernetes.io/docs/concepts/security/pod-security-standards/) as checks of their own, one
+// check ID per control. The podSecurityStandards section of the policies selects the profile of
+// each namespace; a control only reports violations in namespaces whose profile includes it.
+package pod_security_standards
+
+import (
+	"context"
+	"log"
+
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/checks"
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/workload"
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/policy"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+	corev1 "k8s.io/api/core/v1"
+)
+
+var (
+	pssTracer  = otel.Tracer("bankingkube/dynamicpodsec")
+	pssMeter   = otel.Meter("bankingkube/dynamicpodsec")
+	pssDenied  metric.Int64Counter
+	pssAllowed metric.Int64Counter
+)
+
+func init() {
+	var err error
+	pssDenied, err = pssMeter.Int64Counter("pss.denied")
+	if err != nil {
+		log.Println("Failed to create metric: pss.denied")
+	}
+	pssAllowed, err = pssMeter.Int64Counter("pss.allowed")
+	if err != nil {
+		log.Println("Failed to create metric: pss.allowed")
+	}
+}
+
+// Control is a single control of the Pod Security Standards
+type Control struct {
+	ID    string         // Check ID, e.g. pss_host_namespaces
+	Level policy.Profile // Lowest profile that includes the control
+	// ReplacedIn is the profile in which a stricter control takes over, e.g. restricted for the
+	// baseline seccomp control
+	ReplacedIn policy.Profile
+	Check      func(template *workload.PodTemplate) []checks.Violation
+}
+
+// Controls lists every control, baseline first
+var Controls = append(append([]Control{}, baselineControls...), restrictedControls...)
+
+// Applies reports whether the control is part of profile
+func (c *Control) Applies(profile policy.Profile) bool {
+	return profile.Includes(c.Level) && (c.ReplacedIn == "" || !profile.Includes(c.ReplacedIn))
+}
+
+// Validate evaluates the control when the profile of the request's namespace includes it
+func (c *Control) Validate(ctx context.Context, eval *checks.Evaluation) []checks.Violation {
+	// Retrieve the profile of the namespace
+	policies, err := eval.Policies()
+	if err != nil {
+		log.Println("Failed to load pod security standards policy:", err)
+		return []checks.Violation{checks.ErrorViolation("failed_to_load_policies", err)}
+	}
+	profile := policies.PodSecurityStandards.ProfileFor(eval.Request.Namespace)
+	if !c.Applies(profile) {
+		return nil
+	}
+
+	// Extract the pod, or the pod template of a workload, from the request
+	template, err := eval.PodTemplate()
+	if err != nil {
+		log.Println("Failed to parse pod object:", err)
+		return []checks.Violation{checks.ErrorViolation("failed_to_parse_pod", err)} // Fails the validation if the pod can't be parsed
+	}
+	pod := template.Pod
+
+	_, span := pssTracer.Start(ctx, "CheckPodSecurityStandards", trace.WithAttributes(
+		attribute.String("control", c.ID),
+		attribute.String("profile", string(profile)),
+		attribute.String("pod", pod.Name),
+		attribute.String("namespace", pod.Namespace),
+	))
+	defer span.End()
+
+	violations := c.Check(template)
+	for _, v := range violations {
+		log.Printf("Pod %s in namespace %s violates the %s Pod Security Standard: %s\n", pod.Name, pod.Namespace, profile, v.Message)
+		pssDenied.Add(ctx, 1, metric.WithAttributes(
+			attribute.String("control", c.ID),
+			attribute.String("profile", string(profile)),
+			attribute.String("namespace", pod.Namespace),
+			attribute.String("denial_reason", v.Reason),
+		))
+	}
+	if len(violations) > 0 {
+		span.SetAttributes(attribute.String("result", "denied"), attribute.Int("violation_count", len(violations)))
+		return violations
+	}
+
+	pssAllowed.Add(ctx, 1, metric.WithAttributes(
+		attribute.String("control", c.ID),
+		attribute.String("profile", string(profile)),
+		attribute.String("namespace", pod.Namespace),
+	))
+	span.SetAttributes(attribute.String("result", "allowed"))
+	return nil
+}
+
+// containerViolation reports a breach in one container
+func containerViolation(ref checks.ContainerRef, reason, field, value, expected, message string) checks.Violation {
+	return checks.Violation{
+		Reason:    reason,
+		Container: ref.Container.Name,
+		Field:     ref.Path + field,
+		Value:     value,
+		Expected:  expected,
+		Message:   message,
+	}
+}
+
+// windows reports whether the pod runs on Windows nodes, which some restricted controls exempt
+func windows(spec *corev1.PodSpec) bool {
+	return spec.OS != nil && spec.OS.Name == corev1.Windows
+}
@@ -0,0 +1,117 @@
+package pod_security_standards
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/checks"
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/policy"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// restrictedPod passes every control of the restricted profile
+const restrictedPod = `{"metadata":{"name":"web"},"spec":{
+	"securityContext":{"runAsNonRoot":true,"seccompProfile":{"type":"RuntimeDefault"}},
+	"containers":[{"name":"app","image":"app:1.0","securityContext":{"allowPrivilegeEscalation":false,"capabilities":{"drop":["ALL"],"add":["NET_BIND_SERVICE"]}}}],
+	"volumes":[{"name":"data","emptyDir":{}}]}}`
+
+// violations runs every control against a pod in namespace and returns the IDs of the controls that fail
+func violations(t *testing.T, pod, namespace string, standards policy.PodSecurityStandards) []string {
+	t.Helper()
+	request := &admissionv1.AdmissionRequest{
+		Name:      "web",
+		Namespace: namespace,
+		Kind:      metav1.GroupVersionKind{Kind: "Pod"},
+		Object:    runtime.RawExtension{Raw: []byte(pod)},
+	}
+	eval := checks.NewEvaluationWithPolicies(request, &policy.SecurityPolicies{PodSecurityStandards: standards})
+	var ids []string
+	for i := range Controls {
+		for _, v := range Controls[i].Validate(context.Background(), eval) {
+			if v.Internal {
+				t.Fatalf("%s failed to evaluate: %s", Controls[i].ID, v.Message)
+			}
+			ids = append(ids, Controls[i].ID)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func TestControls(t *testing.T) {
+	restricted := policy.PodSecurityStandards{Profile: policy.ProfileRestricted}
+	if got := violations(t, restrictedPod, "payments", restricted); len(got) != 0 {
+		t.Fatalf("restricted pod violates %v", got)
+	}
+
+	tests := []struct {
+		name string
+		old  string // Fragment of restrictedPod to replace
+		new  string
+		want string // Comma separated IDs of the failing controls
+	}{
+		{"host namespaces", `"securityContext":{"runAsNonRoot"`, `"hostPID":true,"hostIPC":true,"securityContext":{"runAsNonRoot"`, "pss_host_namespaces,pss_host_namespaces"},
+		{"host process", `"seccompProfile":{"type":"RuntimeDefault"}},`, `"seccompProfile":{"type":"RuntimeDefault"},"windowsOptions":{"hostProcess":true}},`, "pss_host_process"},
+		{"privileged", `"allowPrivilegeEscalation":false,`, `"allowPrivilegeEscalation":false,"privileged":true,`, "pss_privileged"},
+		{"host path", `{"name":"data","emptyDir":{}}`, `{"name":"data","hostPath":{"path":"/var/run"}}`, "pss_host_path_volumes,pss_volume_types"},
+		{"volume types", `{"name":"data","emptyDir":{}}`, `{"name":"data","nfs":{"server":"nfs","path":"/"}}`, "pss_volume_types"},
+		{"host ports", `"image":"app:1.0",`, `"image":"app:1.0","ports":[{"containerPort":80,"hostPort":80}],`, "pss_host_ports"},
+		{"apparmor annotation", `"metadata":{"name":"web"}`, `"metadata":{"name":"web","annotations":{"container.apparmor.security.beta.kubernetes.io/app":"unconfined"}}`, "pss_apparmor"},
+		{"apparmor field", `"allowPrivilegeEscalation":false,`, `"allowPrivilegeEscalation":false,"appArmorProfile":{"type":"Unconfined"},`, "pss_apparmor"},
+		{"selinux", `"allowPrivilegeEscalation":false,`, `"allowPrivilegeEscalation":false,"seLinuxOptions":{"type":"spc_t","user":"root"},`, "pss_selinux,pss_selinux"},
+		{"proc mount", `"allowPrivilegeEscalation":false,`, `"allowPrivilegeEscalation":false,"procMount":"Unmasked",`, "pss_proc_mount"},
+		{"sysctls", `"runAsNonRoot":true,`, `"runAsNonRoot":true,"sysctls":[{"name":"kernel.msgmax","value":"1"},{"name":"net.ipv4.tcp_syncookies","value":"1"}],`, "pss_sysctls"},
+		{"privilege escalation", `"allowPrivilegeEscalation":false,`, ``, "pss_privilege_escalation"},
+		{"run as root", `"allowPrivilegeEscalation":false,`, `"allowPrivilegeEscalation":false,"runAsNonRoot":false,"runAsUser":0,`, "pss_run_as_non_root,pss_run_as_user"},
+		{"run as non root unset", `"runAsNonRoot":true,`, ``, "pss_run_as_non_root"},
+		{"seccomp unconfined", `"allowPrivilegeEscalation":false,`, `"allowPrivilegeEscalation":false,"seccompProfile":{"type":"Unconfined"},`, "pss_seccomp_restricted"},
+		{"seccomp unset", `,"seccompProfile":{"type":"RuntimeDefault"}`, ``, "pss_seccomp_restricted"},
+		{"capabilities", `"drop":["ALL"],"add":["NET_BIND_SERVICE"]`, `"drop":["NET_RAW"],"add":["CAP_NET_BIND_SERVICE","SYS_ADMIN"]`, "pss_capabilities_restricted,pss_capabilities_restricted"},
+		{"windows", `"securityContext":{"runAsNonRoot"`, `"os":{"name":"windows"},"securityContext":{"runAsNonRoot"`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := restrictedPod
+			if tt.name == "windows" {
+				// Windows pods are exempt from privilege escalation, seccomp and capabilities
+				pod = strings.Replace(pod, `"allowPrivilegeEscalation":false,`, ``, 1)
+				pod = strings.Replace(pod, `,"seccompProfile":{"type":"RuntimeDefault"}`, ``, 1)
+			}
+			if !strings.Contains(pod, tt.old) {
+				t.Fatalf("restrictedPod does not contain %s", tt.old)
+			}
+			pod = strings.Replace(pod, tt.old, tt.new, 1)
+			if got := strings.Join(violations(t, pod, "payments", restricted), ","); got != tt.want {
+				t.Errorf("violations = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProfiles(t *testing.T) {
+	// Privileged and adds SYS_ADMIN: baseline reports both, restricted replaces the baseline capabilities control
+	pod := strings.Replace(restrictedPod, `"allowPrivilegeEscalation":false,"capabilities":{"drop":["ALL"],"add":["NET_BIND_SERVICE"]}`,
+		`"allowPrivilegeEscalation":false,"privileged":true,"capabilities":{"drop":["ALL"],"add":["SYS_ADMIN"]}`, 1)
+	standards := policy.PodSecurityStandards{
+		Profile:    policy.ProfileBaseline,
+		Namespaces: map[string]policy.Profile{"payments": policy.ProfileRestricted, "kube-system": policy.ProfilePrivileged},
+	}
+
+	for namespace, want := range map[string]string{
+		"default":     "pss_capabilities_baseline,pss_privileged",
+		"payments":    "pss_capabilities_restricted,pss_privileged",
+		"kube-system": "",
+	} {
+		if got := strings.Join(violations(t, pod, namespace, standards), ","); got != want {
+			t.Errorf("%s: violations = %s, want %s", namespace, got, want)
+		}
+	}
+	if got := violations(t, pod, "default", policy.PodSecurityStandards{}); len(got) != 0 {
+		t.Errorf("no profile: violations = %v, want none", got)
+	}
+}
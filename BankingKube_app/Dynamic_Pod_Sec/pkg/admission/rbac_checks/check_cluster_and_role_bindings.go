@@ -1,78 +1,59 @@
 package rbac_checks
 
 import (
-	"encoding/json"
+	"fmt"
 	"log"
-	"os"
+	"strings"
 
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/checks"
 	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/utils"
-	"gopkg.in/yaml.v2"
-	admissionv1 "k8s.io/api/admission/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
 )
 
-// CheckRoleBindingsPolicy defines the structure for RBAC policies
-type CheckRoleBindingsPolicy struct {
-	RestrictedClusterRoles []string `yaml:"restrictedClusterRoles"`
-	RestrictedRoleBindings []string `yaml:"restrictedRoleBindings"`
-}
-
 // CheckRBACBinding validates if a ClusterRoleBinding or RoleBinding complies with RBAC policies
-func CheckRBACBinding(request *admissionv1.AdmissionRequest) bool {
-	var roleBinding interface{}
-	if request.Kind.Kind == "ClusterRoleBinding" {
-		roleBinding = &rbacv1.ClusterRoleBinding{}
-	} else if request.Kind.Kind == "RoleBinding" {
-		roleBinding = &rbacv1.RoleBinding{}
-	} else {
-		log.Println("Unsupported kind:", request.Kind.Kind)
-		return false
+func CheckRBACBinding(eval *checks.Evaluation) []checks.Violation {
+	if eval.Request.Kind.Kind != "ClusterRoleBinding" && eval.Request.Kind.Kind != "RoleBinding" {
+		log.Println("Unsupported kind:", eval.Request.Kind.Kind)
+		return []checks.Violation{checks.ErrorViolation("unsupported_kind", fmt.Errorf("unsupported kind %q", eval.Request.Kind.Kind))}
 	}
 
-	err := json.Unmarshal(request.Object.Raw, roleBinding)
+	roleBinding, err := eval.Object()
 	if err != nil {
 		log.Println("Failed to parse RBAC binding object:", err)
-		return false
+		return []checks.Violation{checks.ErrorViolation("failed_to_parse_binding", err)}
 	}
 
-	rbacPolicy, err := getRBACPolicy()
+	policies, err := eval.Policies()
 	if err != nil {
 		log.Println("Failed to load RBAC policy:", err)
-		return false
+		return []checks.Violation{checks.ErrorViolation("failed_to_load_policy", err)}
 	}
+	rbacPolicy := &policies.CheckRoleBindingsPolicy
 
 	switch binding := roleBinding.(type) {
 	case *rbacv1.ClusterRoleBinding:
 		if utils.Contains(rbacPolicy.RestrictedClusterRoles, binding.RoleRef.Name) {
 			log.Printf("ClusterRoleBinding %s uses restricted ClusterRole %s\n", binding.Name, binding.RoleRef.Name)
-			return false
+			return []checks.Violation{{
+				Reason:   "restricted_cluster_role",
+				Field:    "roleRef.name",
+				Value:    binding.RoleRef.Name,
+				Expected: "a ClusterRole other than " + strings.Join(rbacPolicy.RestrictedClusterRoles, ", "),
+				Message:  fmt.Sprintf("ClusterRoleBinding %s uses restricted ClusterRole %s", binding.Name, binding.RoleRef.Name),
+			}}
 		}
 	case *rbacv1.RoleBinding:
 		if utils.Contains(rbacPolicy.RestrictedRoleBindings, binding.RoleRef.Name) {
 			log.Printf("RoleBinding %s uses restricted Role %s\n", binding.Name, binding.RoleRef.Name)
-			return false
+			return []checks.Violation{{
+				Reason:   "restricted_role",
+				Field:    "roleRef.name",
+				Value:    binding.RoleRef.Name,
+				Expected: "a role other than " + strings.Join(rbacPolicy.RestrictedRoleBindings, ", "),
+				Message:  fmt.Sprintf("RoleBinding %s uses restricted role %s", binding.Name, binding.RoleRef.Name),
+			}}
 		}
-	default:
-		log.Println("Unsupported binding type")
-		return false
 	}
 
-	return true
-}
-
-// getRBACPolicy loads the RBAC policy from the configuration file
-func getRBACPolicy() (*CheckRoleBindingsPolicy, error) {
-	configPath := os.Getenv("SECURITY_POLICIES_PATH")
-	if configPath == "" {
-		configPath = "configs/security-policies.yaml"
-	}
-	data, err := os.ReadFile(configPath)
-	if err != nil {
-		return nil, err
-	}
-	var policies struct {
-		CheckRoleBindings CheckRoleBindingsPolicy `yaml:"checkRoleBindings"`
-	}
-	err = yaml.Unmarshal(data, &policies)
-	return &policies.CheckRoleBindings, err
+	return nil
 }
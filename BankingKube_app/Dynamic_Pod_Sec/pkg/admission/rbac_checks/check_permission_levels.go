@@ -1,95 +1,79 @@
 package rbac_checks
 
 import (
-	"encoding/json"
+	"fmt"
 	"log"
-	"os"
+	"strings"
 
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/checks"
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/policy"
 	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/utils"
-	"gopkg.in/yaml.v2"
-	admissionv1 "k8s.io/api/admission/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
 )
 
-// PermissionLevelsPolicy defines the structure for permission level policies
-type PermissionLevelsPolicy struct {
-	RestrictedVerbs     []string `yaml:"restrictedVerbs"`
-	RestrictedResources []string `yaml:"restrictedResources"`
-}
-
 // CheckPermissionLevels validates if a Role or ClusterRole complies with permission level policies
-func CheckPermissionLevels(request *admissionv1.AdmissionRequest) bool {
-	var role interface{}
-	if request.Kind.Kind == "ClusterRole" {
-		role = &rbacv1.ClusterRole{}
-	} else if request.Kind.Kind == "Role" {
-		role = &rbacv1.Role{}
-	} else {
-		log.Println("Unsupported kind:", request.Kind.Kind)
-		return false
+func CheckPermissionLevels(eval *checks.Evaluation) []checks.Violation {
+	if eval.Request.Kind.Kind != "ClusterRole" && eval.Request.Kind.Kind != "Role" {
+		log.Println("Unsupported kind:", eval.Request.Kind.Kind)
+		return []checks.Violation{checks.ErrorViolation("unsupported_kind", fmt.Errorf("unsupported kind %q", eval.Request.Kind.Kind))}
 	}
 
-	err := json.Unmarshal(request.Object.Raw, role)
+	role, err := eval.Object()
 	if err != nil {
 		log.Println("Failed to parse RBAC role object:", err)
-		return false
+		return []checks.Violation{checks.ErrorViolation("failed_to_parse_role", err)}
 	}
 
-	permissionPolicy, err := getPermissionLevelsPolicy()
+	policies, err := eval.Policies()
 	if err != nil {
 		log.Println("Failed to load permission levels policy:", err)
-		return false
+		return []checks.Violation{checks.ErrorViolation("failed_to_load_policy", err)}
 	}
+	permissionPolicy := &policies.PermissionLevelsPolicy
 
+	var violations []checks.Violation
 	switch r := role.(type) {
 	case *rbacv1.ClusterRole:
-		if !validateRules(r.Rules, permissionPolicy) {
+		violations = validateRules(r.Rules, permissionPolicy)
+		if len(violations) > 0 {
 			log.Printf("ClusterRole %s has restricted permissions\n", r.Name)
-			return false
 		}
 	case *rbacv1.Role:
-		if !validateRules(r.Rules, permissionPolicy) {
+		violations = validateRules(r.Rules, permissionPolicy)
+		if len(violations) > 0 {
 			log.Printf("Role %s has restricted permissions\n", r.Name)
-			return false
 		}
-	default:
-		log.Println("Unsupported role type")
-		return false
 	}
 
-	return true
-}
-
-// getPermissionLevelsPolicy loads the permission levels policy from the configuration file
-func getPermissionLevelsPolicy() (*PermissionLevelsPolicy, error) {
-	configPath := os.Getenv("SECURITY_POLICIES_PATH")
-	if configPath == "" {
-		configPath = "configs/security-policies.yaml"
-	}
-	data, err := os.ReadFile(configPath)
-	if err != nil {
-		return nil, err
-	}
-	var policies struct {
-		PermissionLevels PermissionLevelsPolicy `yaml:"permissionLevels"`
-	}
-	err = yaml.Unmarshal(data, &policies)
-	return &policies.PermissionLevels, err
+	return violations
 }
 
 // validateRules checks if the rules comply with the permission levels policy
-func validateRules(rules []rbacv1.PolicyRule, policy *PermissionLevelsPolicy) bool {
-	for _, rule := range rules {
-		for _, verb := range rule.Verbs {
+func validateRules(rules []rbacv1.PolicyRule, policy *policy.PermissionLevelsPolicy) []checks.Violation {
+	var violations []checks.Violation
+	for i, rule := range rules {
+		for j, verb := range rule.Verbs {
 			if utils.Contains(policy.RestrictedVerbs, verb) {
-				return false
+				violations = append(violations, checks.Violation{
+					Reason:   "restricted_verb",
+					Field:    fmt.Sprintf("rules[%d].verbs[%d]", i, j),
+					Value:    verb,
+					Expected: "none of " + strings.Join(policy.RestrictedVerbs, ", "),
+					Message:  fmt.Sprintf("rule %d grants restricted verb %q", i, verb),
+				})
 			}
 		}
-		for _, resource := range rule.Resources {
+		for j, resource := range rule.Resources {
 			if utils.Contains(policy.RestrictedResources, resource) {
-				return false
+				violations = append(violations, checks.Violation{
+					Reason:   "restricted_resource",
+					Field:    fmt.Sprintf("rules[%d].resources[%d]", i, j),
+					Value:    resource,
+					Expected: "none of " + strings.Join(policy.RestrictedResources, ", "),
+					Message:  fmt.Sprintf("rule %d grants access to restricted resource %q", i, resource),
+				})
 			}
 		}
 	}
-	return true
+	return violations
 }
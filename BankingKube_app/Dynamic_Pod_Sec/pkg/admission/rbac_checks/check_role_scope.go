@@ -1,89 +1,68 @@
 package rbac_checks
 
 import (
-	"encoding/json"
+	"fmt"
 	"log"
-	"os"
+	"strings"
 
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/checks"
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/policy"
 	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/utils"
-	"gopkg.in/yaml.v2"
-	admissionv1 "k8s.io/api/admission/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
 )
 
-// RoleScopePolicy defines the structure for role scope policies
-type RoleScopePolicy struct {
-	RestrictedNamespaces []string `yaml:"restrictedNamespaces"`
-}
-
 // CheckRoleScope validates if a Role or ClusterRole complies with role scope policies
-func CheckRoleScope(request *admissionv1.AdmissionRequest) bool {
-	var role interface{}
-	if request.Kind.Kind == "ClusterRole" {
-		role = &rbacv1.ClusterRole{}
-	} else if request.Kind.Kind == "Role" {
-		role = &rbacv1.Role{}
-	} else {
-		log.Println("Unsupported kind:", request.Kind.Kind)
-		return false
+func CheckRoleScope(eval *checks.Evaluation) []checks.Violation {
+	if eval.Request.Kind.Kind != "ClusterRole" && eval.Request.Kind.Kind != "Role" {
+		log.Println("Unsupported kind:", eval.Request.Kind.Kind)
+		return []checks.Violation{checks.ErrorViolation("unsupported_kind", fmt.Errorf("unsupported kind %q", eval.Request.Kind.Kind))}
 	}
 
-	err := json.Unmarshal(request.Object.Raw, role)
+	role, err := eval.Object()
 	if err != nil {
 		log.Println("Failed to parse RBAC role object:", err)
-		return false
+		return []checks.Violation{checks.ErrorViolation("failed_to_parse_role", err)}
 	}
 
-	roleScopePolicy, err := getRoleScopePolicy()
+	policies, err := eval.Policies()
 	if err != nil {
 		log.Println("Failed to load role scope policy:", err)
-		return false
+		return []checks.Violation{checks.ErrorViolation("failed_to_load_policy", err)}
 	}
+	roleScopePolicy := &policies.RoleScopePolicy
 
+	var violations []checks.Violation
 	switch r := role.(type) {
 	case *rbacv1.ClusterRole:
-		if !validateRoleScope(r.Rules, roleScopePolicy) {
+		violations = validateRoleScope(r.Rules, roleScopePolicy)
+		if len(violations) > 0 {
 			log.Printf("ClusterRole %s has restricted scope\n", r.Name)
-			return false
 		}
 	case *rbacv1.Role:
-		if !validateRoleScope(r.Rules, roleScopePolicy) {
+		violations = validateRoleScope(r.Rules, roleScopePolicy)
+		if len(violations) > 0 {
 			log.Printf("Role %s has restricted scope\n", r.Name)
-			return false
 		}
-	default:
-		log.Println("Unsupported role type")
-		return false
 	}
 
-	return true
-}
-
-// getRoleScopePolicy loads the role scope policy from the configuration file
-func getRoleScopePolicy() (*RoleScopePolicy, error) {
-	configPath := os.Getenv("SECURITY_POLICIES_PATH")
-	if configPath == "" {
-		configPath = "configs/security-policies.yaml"
-	}
-	data, err := os.ReadFile(configPath)
-	if err != nil {
-		return nil, err
-	}
-	var policies struct {
-		RoleScope RoleScopePolicy `yaml:"roleScope"`
-	}
-	err = yaml.Unmarshal(data, &policies)
-	return &policies.RoleScope, err
+	return violations
 }
 
 // validateRoleScope checks if the rules comply with the role scope policy
-func validateRoleScope(rules []rbacv1.PolicyRule, policy *RoleScopePolicy) bool {
-	for _, rule := range rules {
-		for _, namespace := range rule.ResourceNames {
+func validateRoleScope(rules []rbacv1.PolicyRule, policy *policy.RoleScopePolicy) []checks.Violation {
+	var violations []checks.Violation
+	for i, rule := range rules {
+		for j, namespace := range rule.ResourceNames {
 			if utils.Contains(policy.RestrictedNamespaces, namespace) {
-				return false
+				violations = append(violations, checks.Violation{
+					Reason:   "restricted_namespace",
+					Field:    fmt.Sprintf("rules[%d].resourceNames[%d]", i, j),
+					Value:    namespace,
+					Expected: "none of " + strings.Join(policy.RestrictedNamespaces, ", "),
+					Message:  fmt.Sprintf("rule %d is scoped to restricted namespace %q", i, namespace),
+				})
 			}
 		}
 	}
-	return true
+	return violations
 }
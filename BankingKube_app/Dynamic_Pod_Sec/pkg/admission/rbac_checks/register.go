@@ -0,0 +1,14 @@
+package rbac_checks
+
+import (
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/checks"
+)
+
+// Group is the check group served on /validate/rbac
+const Group = "rbac"
+
+func init() {
+	checks.Register(Group, checks.NewCheck("rbac_binding", []string{"ClusterRoleBinding", "RoleBinding"}, checks.WithoutContext(CheckRBACBinding)))
+	checks.Register(Group, checks.NewCheck("permission_levels", []string{"ClusterRole", "Role"}, checks.WithoutContext(CheckPermissionLevels)))
+	checks.Register(Group, checks.NewCheck("role_scope", []string{"ClusterRole", "Role"}, checks.WithoutContext(CheckRoleScope)))
+}
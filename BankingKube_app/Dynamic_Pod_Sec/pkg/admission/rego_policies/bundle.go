@@ -0,0 +1,89 @@
+package rego_policies
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/loader"
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/open-policy-agent/opa/storage/inmem"
+)
+
+// Bundle is a compiled set of Rego modules and data documents. Every package that defines a
+// deny rule becomes a check named after the package, e.g. package bankingkube.pci is the
+// bankingkube.pci check.
+type Bundle struct {
+	Path     string
+	policies []*Policy
+}
+
+// Policy is one package of a bundle with its deny rule prepared for evaluation
+type Policy struct {
+	Name  string // Package path without the data. prefix
+	query rego.PreparedEvalQuery
+}
+
+// LoadBundle reads and compiles the .rego modules and the data.json and data.yaml documents of
+// a directory, or of a bundle tarball (.tar.gz)
+func LoadBundle(ctx context.Context, path string) (*Bundle, error) {
+	// The loader does not wrap its errors, so a missing path is reported here for errors.Is
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+	b, err := loader.NewFileLoader().
+		WithFilter(hidden).
+		WithFollowSymlinks(true).
+		AsBundle(path)
+	if err != nil {
+		return nil, err
+	}
+
+	modules := make(map[string]*ast.Module, len(b.Modules))
+	for _, module := range b.Modules {
+		modules[module.Path] = module.Parsed
+	}
+	compiler := ast.NewCompiler()
+	if compiler.Compile(modules); compiler.Failed() {
+		return nil, fmt.Errorf("bundle %s: %w", path, compiler.Errors)
+	}
+	store := inmem.NewFromObject(b.Data)
+
+	bundle := &Bundle{Path: path}
+	seen := make(map[string]bool)
+	for _, module := range modules {
+		deny := module.Package.Path.Append(ast.StringTerm("deny"))
+		name := strings.TrimPrefix(module.Package.Path.String(), "data.")
+		if seen[name] || len(compiler.GetRulesExact(deny)) == 0 {
+			continue
+		}
+		seen[name] = true
+
+		query, err := rego.New(
+			rego.Query(deny.String()),
+			rego.Compiler(compiler),
+			rego.Store(store),
+		).PrepareForEval(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("bundle %s: package %s: %w", path, name, err)
+		}
+		bundle.policies = append(bundle.policies, &Policy{Name: name, query: query})
+	}
+	sort.Slice(bundle.policies, func(i, j int) bool { return bundle.policies[i].Name < bundle.policies[j].Name })
+	return bundle, nil
+}
+
+// Policies returns the packages of the bundle that define a deny rule, sorted by name
+func (b *Bundle) Policies() []*Policy {
+	return b.policies
+}
+
+// hidden leaves out dot files and directories, which includes the timestamped directories and the
+// ..data symlink through which a ConfigMap volume publishes its files a second time
+func hidden(_ string, info fs.FileInfo, depth int) bool {
+	return depth > 0 && strings.HasPrefix(info.Name(), ".")
+}
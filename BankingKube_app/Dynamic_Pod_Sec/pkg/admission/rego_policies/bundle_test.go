@@ -0,0 +1,178 @@
+package rego_policies
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/checks"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+var testBundle = map[string]string{
+	"registries.rego": `package bankingkube.registries
+
+deny[msg] {
+	container := input.request.object.spec.containers[_]
+	not startswith(container.image, data.bankingkube.registry)
+	msg := {
+		"msg": sprintf("container %s uses an image outside %s", [container.name, data.bankingkube.registry]),
+		"reason": "untrusted_registry",
+		"container": container.name,
+		"value": container.image,
+	}
+}
+`,
+	"labels.rego": `package bankingkube.labels
+
+deny["pods must have an owner label"] {
+	not input.request.object.metadata.labels.owner
+}
+`,
+	// A helper package without deny is not a check
+	"lib.rego": `package bankingkube.lib
+
+trusted(image) { startswith(image, data.bankingkube.registry) }
+`,
+	"data.json": `{"bankingkube": {"registry": "registry.example.com/"}}`,
+}
+
+func writeBundle(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func podEvaluation(object string) *checks.Evaluation {
+	return checks.NewEvaluation(&admissionv1.AdmissionRequest{
+		Name:      "web",
+		Namespace: "payments",
+		Kind:      metav1.GroupVersionKind{Version: "v1", Kind: "Pod"},
+		Operation: admissionv1.Create,
+		Object:    runtime.RawExtension{Raw: []byte(object)},
+	}, nil)
+}
+
+func names(bundle *Bundle) []string {
+	var names []string
+	for _, p := range bundle.Policies() {
+		names = append(names, p.Name)
+	}
+	return names
+}
+
+func TestLoadBundleDirectory(t *testing.T) {
+	dir := t.TempDir()
+	writeBundle(t, dir, testBundle)
+	// A ConfigMap volume publishes its files again in a timestamped directory; the version there
+	// does not parse, so the bundle only loads when the directory is skipped
+	writeBundle(t, filepath.Join(dir, "..2024_01_01_00_00_00.000000000"), map[string]string{"labels.rego": "package bankingkube.labels\n\ndeny[msg {\n"})
+
+	bundle, err := LoadBundle(context.Background(), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := names(bundle), []string{"bankingkube.labels", "bankingkube.registries"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("policies = %v, want %v", got, want)
+	}
+
+	labels, registries := bundle.Policies()[0], bundle.Policies()[1]
+	eval := podEvaluation(`{"metadata":{"name":"web"},"spec":{"containers":[{"name":"app","image":"docker.io/nginx"}]}}`)
+
+	got := registries.Check(context.Background(), eval)
+	want := []checks.Violation{{
+		Reason:    "untrusted_registry",
+		Container: "app",
+		Value:     "docker.io/nginx",
+		Message:   "container app uses an image outside registry.example.com/",
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("registries violations = %+v, want %+v", got, want)
+	}
+	if got := labels.Check(context.Background(), eval); len(got) != 1 || got[0].Message != "pods must have an owner label" || got[0].Reason != "rego_deny" {
+		t.Errorf("labels violations = %+v, want the deny message", got)
+	}
+
+	compliant := podEvaluation(`{"metadata":{"labels":{"owner":"payments"}},"spec":{"containers":[{"name":"app","image":"registry.example.com/web"}]}}`)
+	for _, p := range bundle.Policies() {
+		if got := p.Check(context.Background(), compliant); len(got) != 0 {
+			t.Errorf("%s reported %+v for a compliant pod", p.Name, got)
+		}
+	}
+}
+
+func TestLoadBundleTarball(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bundle.tar.gz")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gz := gzip.NewWriter(file)
+	tw := tar.NewWriter(gz)
+	for name, content := range testBundle {
+		if err := tw.WriteHeader(&tar.Header{Name: "/" + name, Mode: 0o644, Size: int64(len(content))}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, closer := range []interface{ Close() error }{tw, gz, file} {
+		if err := closer.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	bundle, err := LoadBundle(context.Background(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := names(bundle), []string{"bankingkube.labels", "bankingkube.registries"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("policies = %v, want %v", got, want)
+	}
+}
+
+func TestStoreKeepsLastGoodBundle(t *testing.T) {
+	dir := t.TempDir()
+	writeBundle(t, dir, testBundle)
+	store := NewStore(dir)
+	if err := store.Reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	writeBundle(t, dir, map[string]string{"labels.rego": "package bankingkube.labels\n\ndeny[msg] { msg := undefined_function(input) }\n"})
+	err := store.Reload()
+	if err == nil || !strings.Contains(err.Error(), "undefined_function") {
+		t.Fatalf("Reload() = %v, want the compile error", err)
+	}
+	bundle, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bundle.Policies()) != 2 {
+		t.Errorf("the last good bundle was replaced: %v", names(bundle))
+	}
+}
+
+func TestStoreMissingPath(t *testing.T) {
+	store := NewStore(filepath.Join(t.TempDir(), "missing"))
+	if err := store.Reload(); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Reload() = %v, want fs.ErrNotExist", err)
+	}
+}
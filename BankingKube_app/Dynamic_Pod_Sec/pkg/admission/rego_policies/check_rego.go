@@ -0,0 +1,183 @@
+package rego_policies
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/checks"
+	"github.com/open-policy-agent/opa/rego"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var (
+	regoTracer  = otel.Tracer("bankingkube/dynamicpodsec")
+	regoMeter   = otel.Meter("bankingkube/dynamicpodsec")
+	regoDenied  metric.Int64Counter
+	regoAllowed metric.Int64Counter
+)
+
+func init() {
+	var err error
+	regoDenied, err = regoMeter.Int64Counter("rego.denied")
+	if err != nil {
+		log.Println("Failed to create metric: rego.denied")
+	}
+	regoAllowed, err = regoMeter.Int64Counter("rego.allowed")
+	if err != nil {
+		log.Println("Failed to create metric: rego.allowed")
+	}
+}
+
+// Policies returns a check for every package of the bundle configured through REGO_BUNDLE_PATH.
+// When the bundle has never loaded it returns a single rego check that reports the error, so
+// the request is not silently admitted without its policies.
+func Policies(eval *checks.Evaluation) []checks.Check {
+	store := DefaultStore()
+	if store == nil {
+		return nil
+	}
+	bundle, err := store.Load()
+	if err != nil {
+		// Nothing has loaded yet (e.g. the ConfigMap was mounted late), so try again
+		if err := store.Reload(); err == nil {
+			bundle, _ = store.Load()
+		}
+	}
+	if bundle == nil {
+		log.Println("Failed to load rego policies:", err)
+		return []checks.Check{checks.NewCheck("rego", nil, checks.WithoutContext(func(*checks.Evaluation) []checks.Violation {
+			return []checks.Violation{checks.ErrorViolation("failed_to_load_bundle", err)}
+		}))}
+	}
+	return bundle.Checks()
+}
+
+// Checks returns a check for every package of the bundle
+func (b *Bundle) Checks() []checks.Check {
+	list := make([]checks.Check, 0, len(b.policies))
+	for _, p := range b.policies {
+		list = append(list, checks.NewCheck(p.Name, nil, p.Check))
+	}
+	return list
+}
+
+// Check evaluates the deny rule of the package against the AdmissionReview of the request.
+// Every message of deny is a violation: either a string, or an object with msg and optionally
+// reason, field, container, value and expected.
+func (p *Policy) Check(ctx context.Context, eval *checks.Evaluation) []checks.Violation {
+	ctx, span := regoTracer.Start(ctx, "CheckRego", trace.WithAttributes(
+		attribute.String("policy", p.Name),
+		attribute.String("operation", string(eval.Request.Operation)),
+		attribute.String("resource", eval.Request.Resource.Resource),
+	))
+	defer span.End()
+
+	input, err := reviewInput(eval.Request)
+	if err != nil {
+		span.RecordError(err)
+		return []checks.Violation{checks.ErrorViolation("failed_to_encode_input", err)}
+	}
+
+	results, err := p.query.Eval(ctx, rego.EvalInput(input))
+	if err != nil {
+		log.Printf("Failed to evaluate rego policy %s: %v\n", p.Name, err)
+		span.RecordError(err)
+		return []checks.Violation{checks.ErrorViolation("failed_to_evaluate_policy", err)}
+	}
+
+	var violations []checks.Violation
+	for _, result := range results {
+		for _, expression := range result.Expressions {
+			messages, ok := expression.Value.([]interface{})
+			if !ok {
+				err := fmt.Errorf("deny is %T, not a set", expression.Value)
+				span.RecordError(err)
+				return []checks.Violation{checks.ErrorViolation("failed_to_evaluate_policy", err)}
+			}
+			for _, message := range messages {
+				violations = append(violations, violation(message))
+			}
+		}
+	}
+
+	attributes := metric.WithAttributes(
+		attribute.String("policy", p.Name),
+		attribute.String("kind", eval.Request.Kind.Kind),
+		attribute.String("namespace", eval.Request.Namespace),
+	)
+	if len(violations) == 0 {
+		regoAllowed.Add(ctx, 1, attributes)
+		span.SetAttributes(attribute.String("result", "allowed"))
+		return nil
+	}
+
+	log.Printf("%s %s/%s violates rego policy %s\n", eval.Request.Kind.Kind, eval.Request.Namespace, eval.Request.Name, p.Name)
+	regoDenied.Add(ctx, int64(len(violations)), attributes)
+	span.SetAttributes(
+		attribute.String("result", "denied"),
+		attribute.Int("violation_count", len(violations)),
+	)
+	return violations
+}
+
+// reviewInput wraps the request into the AdmissionReview the policies read as input
+func reviewInput(request *admissionv1.AdmissionRequest) (interface{}, error) {
+	review := admissionv1.AdmissionReview{
+		TypeMeta: metav1.TypeMeta{APIVersion: "admission.k8s.io/v1", Kind: "AdmissionReview"},
+		Request:  request,
+	}
+	data, err := json.Marshal(review)
+	if err != nil {
+		return nil, err
+	}
+	var input interface{}
+	if err := json.Unmarshal(data, &input); err != nil {
+		return nil, err
+	}
+	return input, nil
+}
+
+// violation converts a message of deny into a violation
+func violation(message interface{}) checks.Violation {
+	v := checks.Violation{Reason: "rego_deny"}
+	fields, ok := message.(map[string]interface{})
+	if !ok {
+		v.Message = text(message)
+		return v
+	}
+	v.Message = text(fields["msg"])
+	if v.Message == "" {
+		v.Message = text(fields["message"])
+	}
+	if reason := text(fields["reason"]); reason != "" {
+		v.Reason = reason
+	}
+	v.Field = text(fields["field"])
+	v.Container = text(fields["container"])
+	v.Value = text(fields["value"])
+	v.Expected = text(fields["expected"])
+	return v
+}
+
+// text renders a value of a deny message; strings are used as they are, anything else as JSON
+func text(value interface{}) string {
+	switch value := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(encoded)
+}
@@ -0,0 +1,12 @@
+package rego_policies
+
+import (
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/checks"
+)
+
+// Group is the check group served on /validate/rego
+const Group = "rego"
+
+func init() {
+	checks.RegisterProvider(Group, Policies)
+}
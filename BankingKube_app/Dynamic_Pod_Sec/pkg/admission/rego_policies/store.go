@@ -0,0 +1,164 @@
+package rego_policies
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// reloadDebounce groups the burst of events a single ConfigMap update or bundle copy produces
+const reloadDebounce = 100 * time.Millisecond
+
+var (
+	storeMeter  = otel.Meter("bankingkube/dynamicpodsec")
+	regoReloads metric.Int64Counter
+)
+
+func init() {
+	var err error
+	regoReloads, err = storeMeter.Int64Counter("rego.reloads")
+	if err != nil {
+		log.Println("Failed to create metric: rego.reloads")
+	}
+}
+
+// Store holds the compiled bundle and swaps it atomically on reload. A bundle that fails to
+// compile leaves the last good one in use, like the policy file.
+type Store struct {
+	path    string
+	mu      sync.Mutex // Serialises reloads
+	bundle  atomic.Pointer[Bundle]
+	lastErr atomic.Pointer[error]
+}
+
+// NewStore creates a store for the bundle directory or tarball at path. It holds no bundle until the first Reload.
+func NewStore(path string) *Store {
+	return &Store{path: path}
+}
+
+// Load returns the current bundle. It only fails when no version of the bundle has loaded yet.
+func (s *Store) Load() (*Bundle, error) {
+	if bundle := s.bundle.Load(); bundle != nil {
+		return bundle, nil
+	}
+	if err := s.lastErr.Load(); err != nil {
+		return nil, *err
+	}
+	return nil, fmt.Errorf("rego bundle %s has not been loaded", s.path)
+}
+
+// Reload compiles the bundle and swaps it in. On failure the previous bundle stays in use and the error is returned.
+func (s *Store) Reload() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	bundle, err := LoadBundle(context.Background(), s.path)
+	if err != nil {
+		err = fmt.Errorf("failed to load rego bundle %s: %w", s.path, err)
+		s.lastErr.Store(&err)
+		regoReloads.Add(context.Background(), 1, metric.WithAttributes(attribute.String("result", "failed")))
+		return err
+	}
+
+	s.bundle.Store(bundle)
+	s.lastErr.Store(nil)
+	regoReloads.Add(context.Background(), 1, metric.WithAttributes(attribute.String("result", "loaded")))
+	return nil
+}
+
+// Watch reloads the bundle whenever a file of it changes until ctx is done. A tarball is watched
+// through its directory; a bundle directory is watched with every subdirectory present at start.
+func (s *Store) Watch(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		err = watcher.Add(filepath.Dir(s.path))
+	} else {
+		err = filepath.WalkDir(s.path, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil || !entry.IsDir() {
+				return err
+			}
+			if path != s.path && strings.HasPrefix(entry.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return watcher.Add(path)
+		})
+	}
+	if err != nil {
+		return err
+	}
+
+	var debounce <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return errors.New("rego watcher closed")
+			}
+			if event.Op == fsnotify.Chmod {
+				continue
+			}
+			debounce = time.After(reloadDebounce)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return errors.New("rego watcher closed")
+			}
+			log.Println("Rego watcher error:", err)
+		case <-debounce:
+			debounce = nil
+			if err := s.Reload(); err != nil {
+				log.Println("Keeping the last good rego bundle:", err)
+				continue
+			}
+			log.Println("Rego bundle reloaded:", s.path)
+		}
+	}
+}
+
+var (
+	defaultStore     *Store
+	defaultStoreOnce sync.Once
+)
+
+// Path returns the bundle configured through REGO_BUNDLE_PATH, or "" when Rego policies are not used
+func Path() string {
+	return os.Getenv("REGO_BUNDLE_PATH")
+}
+
+// DefaultStore returns the store of the bundle configured through REGO_BUNDLE_PATH, loading it on
+// first use. It returns nil when REGO_BUNDLE_PATH is not set.
+func DefaultStore() *Store {
+	defaultStoreOnce.Do(func() {
+		if Path() == "" {
+			return
+		}
+		defaultStore = NewStore(Path())
+		if err := defaultStore.Reload(); err != nil {
+			log.Println(err)
+		}
+	})
+	return defaultStore
+}
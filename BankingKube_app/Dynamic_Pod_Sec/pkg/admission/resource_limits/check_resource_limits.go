@@ -1,56 +1,51 @@
 package resource_limits
 
 import (
-	"encoding/json"
+	"fmt"
 	"log"
-	"os"
 
-	"gopkg.in/yaml.v2"
-	admissionv1 "k8s.io/api/admission/v1"
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/checks"
 	corev1 "k8s.io/api/core/v1"
 	resource "k8s.io/apimachinery/pkg/api/resource"
 )
 
-// ResourceLimits defines a structure for resource limits policies
-type ResourceLimits struct {
-	CPULimits struct {
-		Max string `yaml:"max"`
-		Min string `yaml:"min"`
-	} `yaml:"cpuLimits"`
-	MemoryLimits struct {
-		Max string `yaml:"max"`
-		Min string `yaml:"min"`
-	} `yaml:"memoryLimits"`
-	EnforceResourceLimits bool `yaml:"enforceResourceLimits"`
-}
-
 // CheckResourceLimits validates if a pod's containers have resource limits defined and within the specified range
-func CheckResourceLimits(request *admissionv1.AdmissionRequest) bool {
-	// Parse the Pod object from the request
-	pod := &corev1.Pod{}
-	err := json.Unmarshal(request.Object.Raw, pod)
+func CheckResourceLimits(eval *checks.Evaluation) []checks.Violation {
+	// Extract the pod, or the pod template of a workload, from the request
+	template, err := eval.PodTemplate()
 	if err != nil {
 		log.Println("Failed to parse pod object:", err)
-		return false // Fails the validation if the pod can't be parsed
+		return []checks.Violation{checks.ErrorViolation("failed_to_parse_pod", err)} // Fails the validation if the pod can't be parsed
 	}
+	pod := template.Pod
 
 	// Retrieve the resource limits policies
-	resourceLimits, err := getResourceLimits()
+	policies, err := eval.Policies()
 	if err != nil {
 		log.Println("Failed to load resource limits policies:", err)
-		return false
+		return []checks.Violation{checks.ErrorViolation("failed_to_load_policies", err)}
 	}
+	resourceLimits := &policies.ResourceLimits
 
 	// Check if resource limits enforcement is enabled
 	if !resourceLimits.EnforceResourceLimits {
-		return true // Passes the check if enforcement is not enabled
+		return nil // Passes the check if enforcement is not enabled
 	}
 
 	// Check if the pod's containers have resource limits defined and within the specified range
-	for _, container := range pod.Spec.Containers {
+	var violations []checks.Violation
+	for i, container := range pod.Spec.Containers {
+		path := fmt.Sprintf("%s.containers[%d].resources.limits", template.SpecPath, i)
 		if container.Resources.Limits == nil {
 			log.Printf("Pod %s in namespace %s has a container without resource limits: %s\n", pod.Name, pod.Namespace, container.Name)
-			return false
+			violations = append(violations, checks.Violation{
+				Reason:    "missing_resource_limits",
+				Container: container.Name,
+				Field:     path,
+				Expected:  "cpu and memory limits",
+				Message:   fmt.Sprintf("container %q does not define resource limits", container.Name),
+			})
+			continue
 		}
 
 		cpuLimit := container.Resources.Limits[corev1.ResourceCPU]
@@ -58,16 +53,30 @@ func CheckResourceLimits(request *admissionv1.AdmissionRequest) bool {
 
 		if !isWithinRange(cpuLimit, resourceLimits.CPULimits.Min, resourceLimits.CPULimits.Max) {
 			log.Printf("Pod %s in namespace %s has a container with CPU limit out of range: %s\n", pod.Name, pod.Namespace, container.Name)
-			return false
+			violations = append(violations, checks.Violation{
+				Reason:    "cpu_limit_out_of_range",
+				Container: container.Name,
+				Field:     path + ".cpu",
+				Value:     cpuLimit.String(),
+				Expected:  fmt.Sprintf("between %s and %s", resourceLimits.CPULimits.Min, resourceLimits.CPULimits.Max),
+				Message:   fmt.Sprintf("container %q has a CPU limit out of range", container.Name),
+			})
 		}
 
 		if !isWithinRange(memoryLimit, resourceLimits.MemoryLimits.Min, resourceLimits.MemoryLimits.Max) {
 			log.Printf("Pod %s in namespace %s has a container with Memory limit out of range: %s\n", pod.Name, pod.Namespace, container.Name)
-			return false
+			violations = append(violations, checks.Violation{
+				Reason:    "memory_limit_out_of_range",
+				Container: container.Name,
+				Field:     path + ".memory",
+				Value:     memoryLimit.String(),
+				Expected:  fmt.Sprintf("between %s and %s", resourceLimits.MemoryLimits.Min, resourceLimits.MemoryLimits.Max),
+				Message:   fmt.Sprintf("container %q has a memory limit out of range", container.Name),
+			})
 		}
 	}
 
-	return true // Passes the check if all containers have resource limits defined and within the specified range
+	return violations // Passes the check if all containers have resource limits defined and within the specified range
 }
 
 // isWithinRange checks if a resource quantity is within the specified range
@@ -77,27 +86,3 @@ func isWithinRange(quantity resource.Quantity, min string, max string) bool {
 
 	return quantity.Cmp(minQuantity) >= 0 && quantity.Cmp(maxQuantity) <= 0
 }
-
-// getResourceLimits loads the resource limits policies from the configuration file
-func getResourceLimits() (*ResourceLimits, error) {
-	configPath := os.Getenv("SECURITY_POLICIES_PATH")
-	if configPath == "" {
-		configPath = "configs/security-policies.yaml" // Default path
-	}
-
-	data, err := os.ReadFile(configPath)
-	if err != nil {
-		return nil, err
-	}
-
-	var policies struct {
-		ResourceLimits ResourceLimits `yaml:"resourceLimits"`
-	}
-
-	err = yaml.Unmarshal(data, &policies)
-	if err != nil {
-		return nil, err
-	}
-
-	return &policies.ResourceLimits, nil
-}
@@ -0,0 +1,14 @@
+package resource_limits
+
+import (
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/checks"
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/workload"
+)
+
+// Group is the check group served on /validate/resources
+const Group = "resources"
+
+func init() {
+	checks.Register(Group, checks.NewCheck("resource_limits", workload.Kinds, checks.WithoutContext(CheckResourceLimits)))
+	checks.Register(Group, checks.NewCheck("resource_requests", workload.Kinds, checks.WithoutContext(CheckResourceRequests)))
+}
@@ -0,0 +1,488 @@
+package admission
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/checks"
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/context_capabilities"
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/custom_rules"
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/policy"
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// violating returns a check that always reports a single violation
+func violating(name string) checks.Check {
+	return checks.NewCheck(name, []string{"Pod"}, func(context.Context, *checks.Evaluation) []checks.Violation {
+		return []checks.Violation{{Reason: "always", Message: name + " violated"}}
+	})
+}
+
+func TestValidateReportsEveryViolation(t *testing.T) {
+	registry := checks.NewRegistry()
+	registry.Register("test", violating("first"))
+	registry.Register("test", violating("second"))
+	registry.Register("test", checks.NewCheck("deployments", []string{"Deployment"}, func(context.Context, *checks.Evaluation) []checks.Violation {
+		return []checks.Violation{{Reason: "always", Message: "not a pod"}}
+	}))
+
+	request := &admissionv1.AdmissionRequest{Name: "web", Kind: metav1.GroupVersionKind{Version: "v1", Kind: "Pod"}}
+	response := validate(context.Background(), registry, "test", checks.NewEvaluationWithPolicies(request, &policy.SecurityPolicies{}))
+	if response.Allowed {
+		t.Fatal("a violating pod was allowed")
+	}
+
+	var types []string
+	for _, cause := range response.Result.Details.Causes {
+		types = append(types, string(cause.Type))
+	}
+	if got := strings.Join(types, ","); got != "first/always,second/always" {
+		t.Errorf("causes = %s, want one per violation of the checks applying to pods", got)
+	}
+	if message := response.Result.Message; !strings.HasPrefix(message, "2 policy violations found:") ||
+		!strings.Contains(message, "[first] first violated") || !strings.Contains(message, "[second] second violated") {
+		t.Errorf("message = %q, want both violations", message)
+	}
+}
+
+func TestValidateEnforcementModes(t *testing.T) {
+	registry := checks.NewRegistry()
+	registry.Register("test", violating("first"))
+	registry.Register("test", violating("second"))
+
+	request := &admissionv1.AdmissionRequest{
+		Name:      "web",
+		Namespace: "payments",
+		Kind:      metav1.GroupVersionKind{Version: "v1", Kind: "Pod"},
+	}
+
+	tests := []struct {
+		name        string
+		enforcement policy.Enforcement
+		allowed     bool
+		causes      int
+		warnings    int
+		audited     []string
+	}{
+		{name: "default enforces", enforcement: policy.Enforcement{}, allowed: false, causes: 2},
+		{name: "warn", enforcement: policy.Enforcement{DefaultMode: policy.ModeWarn}, allowed: true, warnings: 2},
+		{name: "audit", enforcement: policy.Enforcement{DefaultMode: policy.ModeAudit}, allowed: true, audited: []string{"first", "second"}},
+		{name: "dryrun", enforcement: policy.Enforcement{DefaultMode: policy.ModeDryRun}, allowed: true},
+		{
+			name:        "per check override",
+			enforcement: policy.Enforcement{DefaultMode: policy.ModeEnforce, Checks: map[string]policy.Mode{"second": policy.ModeWarn}},
+			allowed:     false,
+			causes:      1,
+			warnings:    1,
+		},
+		{
+			name:        "override relaxes a single check",
+			enforcement: policy.Enforcement{DefaultMode: policy.ModeDryRun, Checks: map[string]policy.Mode{"first": policy.ModeAudit}},
+			allowed:     true,
+			audited:     []string{"first"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			eval := checks.NewEvaluationWithPolicies(request, &policy.SecurityPolicies{Enforcement: tt.enforcement})
+			response := validate(context.Background(), registry, "test", eval)
+
+			if response.Allowed != tt.allowed {
+				t.Fatalf("Allowed = %v, want %v (%v)", response.Allowed, tt.allowed, response.Result)
+			}
+			causes := 0
+			if response.Result != nil && response.Result.Details != nil {
+				causes = len(response.Result.Details.Causes)
+			}
+			if causes != tt.causes {
+				t.Errorf("got %d causes, want %d", causes, tt.causes)
+			}
+			if len(response.Warnings) != tt.warnings {
+				t.Errorf("got warnings %q, want %d", response.Warnings, tt.warnings)
+			}
+			for _, w := range response.Warnings {
+				if !strings.HasPrefix(w, "[") {
+					t.Errorf("warning %q does not name its check", w)
+				}
+			}
+			if len(response.AuditAnnotations) != len(tt.audited) {
+				t.Errorf("got audit annotations %v, want keys %v", response.AuditAnnotations, tt.audited)
+			}
+			for _, key := range tt.audited {
+				if !strings.Contains(response.AuditAnnotations[key], `"checkID":"`+key+`"`) {
+					t.Errorf("audit annotation %q = %q, want the recorded violation", key, response.AuditAnnotations[key])
+				}
+			}
+		})
+	}
+}
+
+func TestValidateUnfinishedChecks(t *testing.T) {
+	slow := checks.NewCheck("slow", []string{"Pod"}, func(ctx context.Context, _ *checks.Evaluation) []checks.Violation {
+		<-ctx.Done()
+		return nil
+	})
+	registry := checks.NewRegistry()
+	registry.Register("test", slow)
+	registry.Register("test", violating("fast"))
+
+	request := &admissionv1.AdmissionRequest{Name: "web", Namespace: "payments", Kind: metav1.GroupVersionKind{Kind: "Pod"}}
+	execution := func(failurePolicy policy.FailurePolicy) policy.Execution {
+		return policy.Execution{Checks: map[string]policy.CheckExecution{
+			"slow": {Timeout: policy.Duration(20 * time.Millisecond), FailurePolicy: failurePolicy},
+		}}
+	}
+
+	t.Run("fail closed", func(t *testing.T) {
+		policies := &policy.SecurityPolicies{
+			Enforcement: policy.Enforcement{Checks: map[string]policy.Mode{"fast": policy.ModeWarn}},
+			Execution:   execution(policy.FailClosed),
+		}
+		response := validate(context.Background(), registry, "test", checks.NewEvaluationWithPolicies(request, policies))
+		if response.Allowed {
+			t.Fatal("request with an unfinished fail-closed check was allowed")
+		}
+		causes := response.Result.Details.Causes
+		if len(causes) != 1 || causes[0].Type != "slow/check_unfinished" {
+			t.Errorf("got causes %+v, want the unfinished check", causes)
+		}
+		if response.AuditAnnotations["unfinished_checks"] != "slow" {
+			t.Errorf("unfinished_checks annotation = %q, want slow", response.AuditAnnotations["unfinished_checks"])
+		}
+	})
+
+	t.Run("fail open", func(t *testing.T) {
+		policies := &policy.SecurityPolicies{
+			Enforcement: policy.Enforcement{Checks: map[string]policy.Mode{"fast": policy.ModeWarn}},
+			Execution:   execution(policy.FailOpen),
+		}
+		response := validate(context.Background(), registry, "test", checks.NewEvaluationWithPolicies(request, policies))
+		if !response.Allowed {
+			t.Fatalf("request with an unfinished fail-open check was denied: %v", response.Result)
+		}
+		if len(response.Warnings) != 2 || !strings.HasPrefix(response.Warnings[0], "[slow] check did not finish") {
+			t.Errorf("got warnings %q, want the unfinished check and the fast check's violation", response.Warnings)
+		}
+		if response.AuditAnnotations["unfinished_checks"] != "slow" {
+			t.Errorf("unfinished_checks annotation = %q, want slow", response.AuditAnnotations["unfinished_checks"])
+		}
+	})
+}
+
+func TestValidateInternalErrors(t *testing.T) {
+	failing := func(name string, opts ...checks.Option) checks.Check {
+		return checks.NewCheck(name, []string{"Pod"}, func(context.Context, *checks.Evaluation) []checks.Violation {
+			return []checks.Violation{checks.ErrorViolation("failed_to_load_policies", errors.New("configmap not mounted"))}
+		}, opts...)
+	}
+	request := &admissionv1.AdmissionRequest{Name: "web", Namespace: "payments", Kind: metav1.GroupVersionKind{Kind: "Pod"}}
+
+	tests := []struct {
+		name      string
+		check     checks.Check
+		execution policy.Execution
+		allowed   bool
+		warnings  int
+	}{
+		{name: "global default denies", check: failing("broken"), allowed: false},
+		{name: "global default warns", check: failing("broken"), execution: policy.Execution{DefaultErrorPolicy: policy.ErrorPolicyWarn}, allowed: true, warnings: 1},
+		{name: "declared by the check", check: failing("broken", checks.OnError(policy.ErrorPolicyWarn)), allowed: true, warnings: 1},
+		{
+			name:      "policy file overrides the declaration",
+			check:     failing("broken", checks.OnError(policy.ErrorPolicyWarn)),
+			execution: policy.Execution{Checks: map[string]policy.CheckExecution{"broken": {ErrorPolicy: policy.ErrorPolicyDeny}}},
+			allowed:   false,
+		},
+		{
+			name:      "declaration beats the global default",
+			check:     failing("broken", checks.OnError(policy.ErrorPolicyDeny)),
+			execution: policy.Execution{DefaultErrorPolicy: policy.ErrorPolicyWarn},
+			allowed:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := checks.NewRegistry()
+			registry.Register("test", tt.check)
+
+			response := validate(context.Background(), registry, "test", checks.NewEvaluationWithPolicies(request, &policy.SecurityPolicies{Execution: tt.execution}))
+			if response.Allowed != tt.allowed {
+				t.Fatalf("Allowed = %v, want %v (%v)", response.Allowed, tt.allowed, response.Result)
+			}
+			if !tt.allowed {
+				causes := response.Result.Details.Causes
+				if len(causes) != 1 || causes[0].Type != "broken/failed_to_load_policies" {
+					t.Errorf("got causes %+v, want the internal error", causes)
+				}
+			}
+			if len(response.Warnings) != tt.warnings {
+				t.Errorf("got warnings %q, want %d", response.Warnings, tt.warnings)
+			}
+			if response.AuditAnnotations["check_errors"] != "broken" {
+				t.Errorf("check_errors annotation = %q, want broken", response.AuditAnnotations["check_errors"])
+			}
+		})
+	}
+}
+
+func TestValidateOperations(t *testing.T) {
+	imageCheck := checks.NewCheck("image_tags", []string{"Pod"}, func(context.Context, *checks.Evaluation) []checks.Violation {
+		return []checks.Violation{{Reason: "disallowed_tag", Field: "spec.containers[0].image", Message: "image uses a disallowed tag"}}
+	})
+	registry := checks.NewRegistry()
+	registry.Register("test", imageCheck)
+
+	const admitted = `{"metadata":{"name":"web","labels":{"app":"web"}},"spec":{"containers":[{"name":"app","image":"nginx:latest"}]}}`
+	const relabelled = `{"metadata":{"name":"web","labels":{"app":"web","team":"payments"}},"spec":{"containers":[{"name":"app","image":"nginx:latest"}]}}`
+	const retagged = `{"metadata":{"name":"web","labels":{"app":"web"}},"spec":{"containers":[{"name":"app","image":"nginx:edge"}]}}`
+	recheckAll := true
+
+	tests := []struct {
+		name      string
+		operation admissionv1.Operation
+		object    string
+		oldObject string
+		updates   policy.Updates
+		allowed   bool
+	}{
+		{name: "create", operation: admissionv1.Create, object: admitted, allowed: false},
+		{name: "label-only update", operation: admissionv1.Update, object: relabelled, oldObject: admitted, allowed: true},
+		{name: "update of the offending field", operation: admissionv1.Update, object: retagged, oldObject: admitted, allowed: false},
+		{name: "recheck all", operation: admissionv1.Update, object: relabelled, oldObject: admitted, updates: policy.Updates{RecheckAll: true}, allowed: false},
+		{
+			name:      "recheck a single check",
+			operation: admissionv1.Update,
+			object:    relabelled,
+			oldObject: admitted,
+			updates:   policy.Updates{Checks: map[string]policy.CheckUpdates{"image_tags": {RecheckAll: &recheckAll}}},
+			allowed:   false,
+		},
+		{name: "delete", operation: admissionv1.Delete, oldObject: admitted, allowed: true},
+		{name: "connect", operation: admissionv1.Connect, object: `{"kind":"PodExecOptions"}`, allowed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := &admissionv1.AdmissionRequest{
+				Name:      "web",
+				Namespace: "payments",
+				Kind:      metav1.GroupVersionKind{Version: "v1", Kind: "Pod"},
+				Operation: tt.operation,
+				Object:    runtime.RawExtension{Raw: []byte(tt.object)},
+				OldObject: runtime.RawExtension{Raw: []byte(tt.oldObject)},
+			}
+			eval := checks.NewEvaluationWithPolicies(request, &policy.SecurityPolicies{Updates: tt.updates})
+			response := validate(context.Background(), registry, "test", eval)
+			if response.Allowed != tt.allowed {
+				t.Errorf("Allowed = %v, want %v (%v)", response.Allowed, tt.allowed, response.Result)
+			}
+		})
+	}
+}
+
+func TestValidateInheritedSecurityContext(t *testing.T) {
+	registry := checks.NewRegistry()
+	registry.Register(context_capabilities.Group, checks.NewCheck("pod_security_context", []string{"Deployment"}, context_capabilities.CheckPodSecurityContext))
+	policies := &policy.SecurityPolicies{PodSecurityContext: policy.PodSecurityContext{RunAsNonRoot: true}}
+
+	// The container inherits runAsNonRoot from the pod template
+	const admitted = `{"metadata":{"name":"web","labels":{"app":"web"}},"spec":{"template":{"spec":{"securityContext":{"runAsNonRoot":true},"containers":[{"name":"app","image":"nginx:1.25","securityContext":{"allowPrivilegeEscalation":false}}]}}}}`
+	const relabelled = `{"metadata":{"name":"web","labels":{"app":"web","team":"payments"}},"spec":{"template":{"spec":{"containers":[{"name":"app","image":"nginx:1.25","securityContext":{"allowPrivilegeEscalation":false}}]}}}}`
+	const removed = `{"metadata":{"name":"web","labels":{"app":"web"}},"spec":{"template":{"spec":{"containers":[{"name":"app","image":"nginx:1.25","securityContext":{"allowPrivilegeEscalation":false}}]}}}}`
+
+	tests := []struct {
+		name      string
+		object    string
+		oldObject string
+		allowed   bool
+	}{
+		{name: "removing the pod-level value", object: removed, oldObject: admitted, allowed: false},
+		{name: "label-only update of an object already missing it", object: relabelled, oldObject: removed, allowed: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := &admissionv1.AdmissionRequest{
+				Name:      "web",
+				Namespace: "payments",
+				Kind:      metav1.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"},
+				Operation: admissionv1.Update,
+				Object:    runtime.RawExtension{Raw: []byte(tt.object)},
+				OldObject: runtime.RawExtension{Raw: []byte(tt.oldObject)},
+			}
+			response := validate(context.Background(), registry, context_capabilities.Group, checks.NewEvaluationWithPolicies(request, policies))
+			if response.Allowed != tt.allowed {
+				t.Errorf("Allowed = %v, want %v (%v)", response.Allowed, tt.allowed, response.Result)
+			}
+		})
+	}
+}
+
+func TestValidateExemptions(t *testing.T) {
+	registry := checks.NewRegistry()
+	registry.Register("test", violating("host_network"))
+	registry.Register("test", violating("capabilities"))
+
+	request := &admissionv1.AdmissionRequest{
+		Name:      "aws-node",
+		Namespace: "kube-system",
+		Kind:      metav1.GroupVersionKind{Version: "v1", Kind: "Pod"},
+		Object:    runtime.RawExtension{Raw: []byte(`{"metadata":{"name":"aws-node"},"spec":{"containers":[{"name":"aws-node","image":"amazon-k8s-cni:v1.18.0"}]}}`)},
+	}
+	exemption := func(expires time.Time) policy.Exemption {
+		return policy.Exemption{
+			Name:          "aws-node",
+			Checks:        []string{"host_network"},
+			Namespaces:    []string{"kube-system"},
+			Justification: "The VPC CNI configures the node network",
+			Owner:         "platform-team",
+			Expires:       policy.Date{Time: expires},
+		}
+	}
+
+	t.Run("active", func(t *testing.T) {
+		policies := &policy.SecurityPolicies{Exemptions: []policy.Exemption{exemption(time.Now().AddDate(0, 1, 0))}}
+		response := validate(context.Background(), registry, "test", checks.NewEvaluationWithPolicies(request, policies))
+		if response.Allowed {
+			t.Fatal("the capabilities violation is not exempted but the request was allowed")
+		}
+		if causes := response.Result.Details.Causes; len(causes) != 1 || causes[0].Type != "capabilities/always" {
+			t.Errorf("got causes %+v, want only the capabilities violation", causes)
+		}
+		annotation := response.AuditAnnotations["exemptions"]
+		for _, want := range []string{`"check":"host_network"`, `"exemption":"aws-node"`, `"owner":"platform-team"`} {
+			if !strings.Contains(annotation, want) {
+				t.Errorf("exemptions annotation %s does not contain %s", annotation, want)
+			}
+		}
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		policies := &policy.SecurityPolicies{Exemptions: []policy.Exemption{exemption(time.Now().AddDate(0, 0, -2))}}
+		response := validate(context.Background(), registry, "test", checks.NewEvaluationWithPolicies(request, policies))
+		if causes := response.Result.Details.Causes; len(causes) != 2 {
+			t.Errorf("got causes %+v, want both violations", causes)
+		}
+		if len(response.Warnings) != 1 || !strings.Contains(response.Warnings[0], "exemption aws-node (owner platform-team) expired") {
+			t.Errorf("got warnings %q, want the expired exemption", response.Warnings)
+		}
+		if _, ok := response.AuditAnnotations["exemptions"]; ok {
+			t.Error("an expired exemption was recorded as applied")
+		}
+	})
+}
+
+func TestValidateCustomRules(t *testing.T) {
+	registry := checks.NewRegistry()
+	registry.RegisterProvider(custom_rules.Group, custom_rules.Rules)
+
+	shipped, err := policy.Load("../../configs/security-policies.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pod := func(object string) *admissionv1.AdmissionRequest {
+		return &admissionv1.AdmissionRequest{
+			Name:      "web",
+			Namespace: "payments",
+			Kind:      metav1.GroupVersionKind{Version: "v1", Kind: "Pod"},
+			Operation: admissionv1.Create,
+			UserInfo:  authenticationv1.UserInfo{Username: "jane"},
+			Object:    runtime.RawExtension{Raw: []byte(object)},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		object string
+		causes []string
+	}{
+		{
+			name:   "compliant",
+			object: `{"metadata":{"labels":{"pci":"true"}},"spec":{"containers":[{"name":"app","resources":{"limits":{"memory":"1Gi"},"requests":{"memory":"1Gi"}},"env":[{"name":"DB_PASSWORD","valueFrom":{"secretKeyRef":{"name":"db","key":"password"}}}]}]}}`,
+		},
+		{
+			name:   "pci memory limit above request",
+			object: `{"metadata":{"labels":{"pci":"true"}},"spec":{"containers":[{"name":"app","resources":{"limits":{"memory":"2Gi"},"requests":{"memory":"1Gi"}}}]}}`,
+			causes: []string{"pci_memory_limits/custom_rule"},
+		},
+		{
+			name:   "literal password in an init container",
+			object: `{"metadata":{},"spec":{"containers":[{"name":"app"}],"initContainers":[{"name":"migrate","env":[{"name":"DB_PASSWORD","value":"hunter2"}]}]}}`,
+			causes: []string{"no_literal_passwords/custom_rule"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := validate(context.Background(), registry, custom_rules.Group, checks.NewEvaluationWithPolicies(pod(tt.object), shipped))
+			var causes []string
+			if response.Result.Details != nil {
+				for _, cause := range response.Result.Details.Causes {
+					causes = append(causes, string(cause.Type))
+				}
+			}
+			if strings.Join(causes, ",") != strings.Join(tt.causes, ",") {
+				t.Errorf("got causes %v, want %v (%s)", causes, tt.causes, response.Result.Message)
+			}
+		})
+	}
+
+	// The rule declares warn; namespace labels and the requesting user are available
+	policies, err := policy.Parse([]byte(`policies:
+  customRules:
+    - name: restricted_namespaces
+      expression: namespaceLabels.?tier.orValue("") != "restricted" || request.userInfo.username.startsWith("system:")
+      message: Only system users may create objects in restricted namespaces
+      mode: warn
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	eval := checks.NewEvaluationWithPolicies(pod(`{"metadata":{},"spec":{}}`), policies)
+	eval.Namespaces = func(name string) (map[string]string, error) { return map[string]string{"tier": "restricted"}, nil }
+	response := validate(context.Background(), registry, custom_rules.Group, eval)
+	if !response.Allowed || len(response.Warnings) != 1 || !strings.Contains(response.Warnings[0], "[restricted_namespaces] Only system users") {
+		t.Errorf("Allowed = %v, warnings %q; want the rule's violation as a warning", response.Allowed, response.Warnings)
+	}
+
+	// The enforcement section overrides the mode the rule declares
+	policies.Enforcement.Checks = map[string]policy.Mode{"restricted_namespaces": policy.ModeEnforce}
+	eval = checks.NewEvaluationWithPolicies(pod(`{"metadata":{},"spec":{}}`), policies)
+	eval.Namespaces = func(name string) (map[string]string, error) { return map[string]string{"tier": "restricted"}, nil }
+	if response := validate(context.Background(), registry, custom_rules.Group, eval); response.Allowed {
+		t.Error("enforcement.checks did not override the mode of the rule")
+	}
+
+	// Without namespace labels the rule cannot be evaluated, which is an internal error rather than a pass
+	eval = checks.NewEvaluationWithPolicies(pod(`{"metadata":{},"spec":{}}`), policies)
+	response = validate(context.Background(), registry, custom_rules.Group, eval)
+	if response.Allowed || response.AuditAnnotations["check_errors"] != "restricted_namespaces" {
+		t.Errorf("Allowed = %v, annotations %v; want the rule to fail with an internal error", response.Allowed, response.AuditAnnotations)
+	}
+}
+
+func TestCheckDeadline(t *testing.T) {
+	tests := []struct {
+		url  string
+		want time.Duration
+	}{
+		{"/validate/image?timeout=5s", 4 * time.Second},
+		{"/validate/image?timeout=2s", 1600 * time.Millisecond},
+		{"/validate/image?timeout=30s", 29 * time.Second},
+		{"/validate/image", 9 * time.Second},
+		{"/validate/image?timeout=soon", 9 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := checkDeadline(httptest.NewRequest(http.MethodPost, tt.url, nil)); got != tt.want {
+			t.Errorf("checkDeadline(%s) = %s, want %s", tt.url, got, tt.want)
+		}
+	}
+}
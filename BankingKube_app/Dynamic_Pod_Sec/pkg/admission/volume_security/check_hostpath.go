@@ -1,74 +1,50 @@
 package volume_security
 
 import (
-	"encoding/json"
+	"fmt"
 	"log"
-	"os"
+	"strings"
 
-	"gopkg.in/yaml.v2"
-	admissionv1 "k8s.io/api/admission/v1"
-	corev1 "k8s.io/api/core/v1"
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/checks"
 )
 
-// VolumeSecurity defines a structure for volume security policies
-type VolumeSecurity struct {
-	DisallowedHostPaths      []string `yaml:"disallowedHostPaths"`
-	RestrictedStorageClasses []string `yaml:"restrictedStorageClasses"`
-}
-
 // CheckHostPath checks if the pod has any disallowed hostPath volumes
-func CheckHostPath(request *admissionv1.AdmissionRequest) bool {
-	// Parse the Pod object from the request
-	pod := &corev1.Pod{}
-	err := json.Unmarshal(request.Object.Raw, pod)
+func CheckHostPath(eval *checks.Evaluation) []checks.Violation {
+	// Extract the pod, or the pod template of a workload, from the request
+	template, err := eval.PodTemplate()
 	if err != nil {
 		log.Println("Failed to parse pod object:", err)
-		return false // Fails the validation if the pod can't be parsed
+		return []checks.Violation{checks.ErrorViolation("failed_to_parse_pod", err)} // Fails the validation if the pod can't be parsed
 	}
+	pod := template.Pod
 
 	// Retrieve the volume security policies
-	volumeSecurity, err := getVolumeSecurity()
+	policies, err := eval.Policies()
 	if err != nil {
 		log.Println("Failed to load volume security policies:", err)
-		return false
+		return []checks.Violation{checks.ErrorViolation("failed_to_load_policies", err)}
 	}
+	volumeSecurity := &policies.VolumeSecurity
 
 	// Check for disallowed hostPath volumes
-	for _, volume := range pod.Spec.Volumes {
+	var violations []checks.Violation
+	for i, volume := range pod.Spec.Volumes {
 		if volume.HostPath != nil {
 			for _, disallowedPath := range volumeSecurity.DisallowedHostPaths {
 				if volume.HostPath.Path == disallowedPath {
 					log.Println("Disallowed hostPath volume found:", volume.HostPath.Path)
-					return false // Return false if any disallowed hostPath volume is found
+					violations = append(violations, checks.Violation{
+						Reason:   "disallowed_host_path",
+						Field:    fmt.Sprintf("%s.volumes[%d].hostPath.path", template.SpecPath, i),
+						Value:    volume.HostPath.Path,
+						Expected: "none of " + strings.Join(volumeSecurity.DisallowedHostPaths, ", "),
+						Message:  fmt.Sprintf("volume %q mounts disallowed host path %s", volume.Name, volume.HostPath.Path),
+					})
 				}
 			}
 		}
 	}
 
 	// Passes the check if no disallowed hostPath volumes are found
-	return true
-}
-
-// getVolumeSecurity loads the volume security policies from the configuration file
-func getVolumeSecurity() (*VolumeSecurity, error) {
-	configPath := os.Getenv("SECURITY_POLICIES_PATH")
-	if configPath == "" {
-		configPath = "configs/security-policies.yaml" // Default path
-	}
-
-	data, err := os.ReadFile(configPath)
-	if err != nil {
-		return nil, err
-	}
-
-	var policies struct {
-		VolumeSecurity VolumeSecurity `yaml:"volumeSecurity"`
-	}
-
-	err = yaml.Unmarshal(data, &policies)
-	if err != nil {
-		return nil, err
-	}
-
-	return &policies.VolumeSecurity, nil
+	return violations
 }
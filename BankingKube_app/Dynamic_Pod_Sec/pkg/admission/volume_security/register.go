@@ -0,0 +1,13 @@
+package volume_security
+
+import (
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/checks"
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/workload"
+)
+
+// Group is the check group served on /validate/volumes
+const Group = "volumes"
+
+func init() {
+	checks.Register(Group, checks.NewCheck("host_path", workload.Kinds, checks.WithoutContext(CheckHostPath)))
+}
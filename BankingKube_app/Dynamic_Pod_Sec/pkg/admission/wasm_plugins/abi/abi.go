@@ -0,0 +1,79 @@
+// Package abi defines the interface between the webhook and its WebAssembly plugins.
+//
+// A plugin is a WebAssembly module (wasip1 or freestanding) that exports its linear memory and:
+//
+//	bankingkube_abi_version() -> i32         the ABI version the plugin implements, Version
+//	bankingkube_allocate(size i32) -> i32    a buffer of size bytes in linear memory
+//	bankingkube_evaluate(ptr, len i32) -> i64 evaluates the Input encoded as JSON at ptr
+//
+// bankingkube_evaluate returns the Output encoded as JSON, as the offset of the buffer in the high
+// 32 bits and its length in the low 32 bits. Every call runs in a fresh instance of the module, so a
+// plugin does not need to free what it allocates. The host provides WASI without files,
+// environment variables or network access.
+//
+// The package has no dependencies beyond the standard library and jsonpatch, so plugins written in Go
+// can import it.
+package abi
+
+import (
+	"encoding/json"
+
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/jsonpatch"
+)
+
+// Version is the ABI version the host implements. It changes whenever Input, Output or the exported
+// functions change incompatibly; the host refuses plugins built against another version.
+const Version = 1
+
+// Names of the functions a plugin exports
+const (
+	ExportVersion  = "bankingkube_abi_version"
+	ExportAllocate = "bankingkube_allocate"
+	ExportEvaluate = "bankingkube_evaluate"
+)
+
+// Phase tells a plugin why it is called
+type Phase string
+
+const (
+	// PhaseValidate asks for the violations of the object; patches are ignored
+	PhaseValidate Phase = "validate"
+	// PhaseMutate asks for the patches to apply to the object; violations are ignored
+	PhaseMutate Phase = "mutate"
+)
+
+// Input is what a plugin receives
+type Input struct {
+	Version int   `json:"version"`
+	Phase   Phase `json:"phase"`
+	// Request is the AdmissionRequest (admission.k8s.io/v1) as the API server sent it, except that
+	// in the mutate phase object is the pod with the baseline defaults and earlier plugins applied
+	Request json.RawMessage `json:"request"`
+}
+
+// Output is what a plugin returns
+type Output struct {
+	Violations []Violation `json:"violations,omitempty"`
+	// Patch is an RFC 6902 JSON Patch (add, replace and remove) against the object of the request
+	Patch []jsonpatch.Operation `json:"patch,omitempty"`
+}
+
+// Violation is a single problem a plugin found. Only Message is required; Reason defaults to plugin_deny.
+type Violation struct {
+	Reason    string `json:"reason,omitempty"`
+	Message   string `json:"message"`
+	Field     string `json:"field,omitempty"`
+	Container string `json:"container,omitempty"`
+	Value     string `json:"value,omitempty"`
+	Expected  string `json:"expected,omitempty"`
+}
+
+// Pack combines the offset and length of a buffer into the result of bankingkube_evaluate
+func Pack(ptr, size uint32) uint64 {
+	return uint64(ptr)<<32 | uint64(size)
+}
+
+// Unpack splits the result of bankingkube_evaluate into the offset and length of the buffer
+func Unpack(packed uint64) (ptr, size uint32) {
+	return uint32(packed >> 32), uint32(packed)
+}
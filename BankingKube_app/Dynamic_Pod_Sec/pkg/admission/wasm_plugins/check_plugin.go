@@ -0,0 +1,166 @@
+package wasm_plugins
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/checks"
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/jsonpatch"
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/wasm_plugins/abi"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+
+	admissionv1 "k8s.io/api/admission/v1"
+)
+
+var (
+	pluginTracer  = otel.Tracer("bankingkube/dynamicpodsec")
+	pluginMeter   = otel.Meter("bankingkube/dynamicpodsec")
+	pluginDenied  metric.Int64Counter
+	pluginAllowed metric.Int64Counter
+	pluginErrors  metric.Int64Counter
+)
+
+func init() {
+	var err error
+	pluginDenied, err = pluginMeter.Int64Counter("wasm.denied")
+	if err != nil {
+		log.Println("Failed to create metric: wasm.denied")
+	}
+	pluginAllowed, err = pluginMeter.Int64Counter("wasm.allowed")
+	if err != nil {
+		log.Println("Failed to create metric: wasm.allowed")
+	}
+	pluginErrors, err = pluginMeter.Int64Counter("wasm.errors")
+	if err != nil {
+		log.Println("Failed to create metric: wasm.errors")
+	}
+}
+
+// Plugins returns a check for every plugin in WASM_PLUGIN_DIR. When the plugins failed to load it
+// returns a single wasm check that reports the error, so the request is not silently admitted.
+func Plugins(eval *checks.Evaluation) []checks.Check {
+	host, err := DefaultHost()
+	if err != nil {
+		log.Println("Failed to load wasm plugins:", err)
+		return []checks.Check{checks.NewCheck("wasm", nil, checks.WithoutContext(func(*checks.Evaluation) []checks.Violation {
+			return []checks.Violation{checks.ErrorViolation("failed_to_load_plugins", err)}
+		}))}
+	}
+	if host == nil {
+		return nil
+	}
+	return host.Checks()
+}
+
+// Checks returns a check for every plugin of the host
+func (h *Host) Checks() []checks.Check {
+	list := make([]checks.Check, 0, len(h.plugins))
+	for _, p := range h.plugins {
+		list = append(list, checks.NewCheck(p.Name, nil, p.Check))
+	}
+	return list
+}
+
+// Check passes the request to the plugin in the validate phase and returns its violations.
+// A plugin that traps, runs out of memory or time, or returns invalid output is an internal error.
+func (p *Plugin) Check(ctx context.Context, eval *checks.Evaluation) []checks.Violation {
+	ctx, span := pluginTracer.Start(ctx, "CheckWasmPlugin", trace.WithAttributes(
+		attribute.String("plugin", p.Name),
+		attribute.String("operation", string(eval.Request.Operation)),
+		attribute.String("resource", eval.Request.Resource.Resource),
+	))
+	defer span.End()
+
+	request, err := json.Marshal(eval.Request)
+	if err != nil {
+		span.RecordError(err)
+		return []checks.Violation{checks.ErrorViolation("failed_to_encode_input", err)}
+	}
+	output, err := p.Evaluate(ctx, abi.PhaseValidate, request)
+	if err != nil {
+		log.Printf("Failed to run wasm plugin %s: %v\n", p.Name, err)
+		span.RecordError(err)
+		return []checks.Violation{checks.ErrorViolation("failed_to_run_plugin", err)}
+	}
+
+	attributes := metric.WithAttributes(
+		attribute.String("plugin", p.Name),
+		attribute.String("kind", eval.Request.Kind.Kind),
+		attribute.String("namespace", eval.Request.Namespace),
+	)
+	if len(output.Violations) == 0 {
+		pluginAllowed.Add(ctx, 1, attributes)
+		span.SetAttributes(attribute.String("result", "allowed"))
+		return nil
+	}
+
+	violations := make([]checks.Violation, 0, len(output.Violations))
+	for _, v := range output.Violations {
+		reason := v.Reason
+		if reason == "" {
+			reason = "plugin_deny"
+		}
+		violations = append(violations, checks.Violation{
+			Reason:    reason,
+			Field:     v.Field,
+			Container: v.Container,
+			Value:     v.Value,
+			Expected:  v.Expected,
+			Message:   v.Message,
+		})
+	}
+
+	log.Printf("%s %s/%s violates wasm plugin %s\n", eval.Request.Kind.Kind, eval.Request.Namespace, eval.Request.Name, p.Name)
+	pluginDenied.Add(ctx, int64(len(violations)), attributes)
+	span.SetAttributes(
+		attribute.String("result", "denied"),
+		attribute.Int("violation_count", len(violations)),
+	)
+	return violations
+}
+
+// Mutate passes the object to every plugin in turn in the mutate phase and applies the patch each
+// returns, so a plugin sees the changes of the plugins before it. A plugin that fails or returns a
+// patch that does not apply is logged and skipped; it never blocks the request.
+func (h *Host) Mutate(ctx context.Context, request *admissionv1.AdmissionRequest, object []byte) []byte {
+	for _, p := range h.plugins {
+		patched, err := p.mutate(ctx, request, object)
+		if err != nil {
+			log.Printf("Skipping the patch of wasm plugin %s: %v\n", p.Name, err)
+			pluginErrors.Add(ctx, 1, metric.WithAttributes(
+				attribute.String("plugin", p.Name),
+				attribute.String("phase", string(abi.PhaseMutate)),
+			))
+			continue
+		}
+		object = patched
+	}
+	return object
+}
+
+// mutate returns object with the patch of the plugin applied
+func (p *Plugin) mutate(ctx context.Context, request *admissionv1.AdmissionRequest, object []byte) ([]byte, error) {
+	current := *request
+	current.Object.Raw = object
+	current.Object.Object = nil
+	encoded, err := json.Marshal(&current)
+	if err != nil {
+		return nil, err
+	}
+	output, err := p.Evaluate(ctx, abi.PhaseMutate, encoded)
+	if err != nil {
+		return nil, err
+	}
+	if len(output.Patch) == 0 {
+		return object, nil
+	}
+	patch, err := json.Marshal(output.Patch)
+	if err != nil {
+		return nil, err
+	}
+	return jsonpatch.Apply(object, patch)
+}
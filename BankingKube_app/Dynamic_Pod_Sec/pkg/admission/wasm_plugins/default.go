@@ -0,0 +1,60 @@
+package wasm_plugins
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+var (
+	defaultHost     *Host
+	defaultHostErr  error
+	defaultHostOnce sync.Once
+)
+
+// Dir returns the plugin directory configured through WASM_PLUGIN_DIR, or "" when plugins are not used
+func Dir() string {
+	return os.Getenv("WASM_PLUGIN_DIR")
+}
+
+// ConfiguredLimits returns DefaultLimits with WASM_PLUGIN_MEMORY_LIMIT (a quantity, e.g. 32Mi) and
+// WASM_PLUGIN_TIMEOUT (a duration, e.g. 200ms) applied
+func ConfiguredLimits() (Limits, error) {
+	limits := DefaultLimits
+	if value := os.Getenv("WASM_PLUGIN_MEMORY_LIMIT"); value != "" {
+		quantity, err := resource.ParseQuantity(value)
+		if err != nil {
+			return limits, fmt.Errorf("invalid WASM_PLUGIN_MEMORY_LIMIT %q: %w", value, err)
+		}
+		limits.Memory = quantity.Value()
+	}
+	if value := os.Getenv("WASM_PLUGIN_TIMEOUT"); value != "" {
+		timeout, err := time.ParseDuration(value)
+		if err != nil || timeout <= 0 {
+			return limits, fmt.Errorf("invalid WASM_PLUGIN_TIMEOUT %q", value)
+		}
+		limits.Timeout = timeout
+	}
+	return limits, nil
+}
+
+// DefaultHost returns the host of the plugins in WASM_PLUGIN_DIR, compiling them on first use.
+// It returns nil and no error when WASM_PLUGIN_DIR is not set.
+func DefaultHost() (*Host, error) {
+	defaultHostOnce.Do(func() {
+		if Dir() == "" {
+			return
+		}
+		limits, err := ConfiguredLimits()
+		if err != nil {
+			defaultHostErr = err
+			return
+		}
+		defaultHost, defaultHostErr = LoadDir(context.Background(), Dir(), limits)
+	})
+	return defaultHost, defaultHostErr
+}
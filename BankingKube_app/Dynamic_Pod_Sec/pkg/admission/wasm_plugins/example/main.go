@@ -0,0 +1,64 @@
+//go:build wasip1
+
+// Command example is a plugin that requires pods to carry a team label and turns off the automatic
+// mounting of the service account token unless the pod asks for it. Build it with:
+//
+//	GOOS=wasip1 GOARCH=wasm go build -buildmode=c-shared -o require_team.wasm ./pkg/admission/wasm_plugins/example
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/jsonpatch"
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/wasm_plugins/abi"
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/wasm_plugins/guest"
+)
+
+// teamLabel is the label every pod must carry
+const teamLabel = "team"
+
+// pod holds the fields of the admitted pod the plugin reads
+type pod struct {
+	Metadata struct {
+		Labels map[string]string `json:"labels"`
+	} `json:"metadata"`
+	Spec struct {
+		AutomountServiceAccountToken *bool `json:"automountServiceAccountToken"`
+	} `json:"spec"`
+}
+
+func init() {
+	guest.Handle(evaluate)
+}
+
+func evaluate(input abi.Input) abi.Output {
+	var request struct {
+		Object pod `json:"object"`
+	}
+	if err := json.Unmarshal(input.Request, &request); err != nil {
+		return abi.Output{Violations: []abi.Violation{{Reason: "failed_to_decode_pod", Message: err.Error()}}}
+	}
+
+	switch input.Phase {
+	case abi.PhaseValidate:
+		if request.Object.Metadata.Labels[teamLabel] == "" {
+			return abi.Output{Violations: []abi.Violation{{
+				Reason:   "missing_team_label",
+				Field:    "metadata.labels." + teamLabel,
+				Message:  "pods must carry a team label",
+				Expected: "a team name",
+			}}}
+		}
+	case abi.PhaseMutate:
+		if request.Object.Spec.AutomountServiceAccountToken == nil {
+			return abi.Output{Patch: []jsonpatch.Operation{{
+				Op:    jsonpatch.OpAdd,
+				Path:  "/spec/automountServiceAccountToken",
+				Value: false,
+			}}}
+		}
+	}
+	return abi.Output{}
+}
+
+func main() {}
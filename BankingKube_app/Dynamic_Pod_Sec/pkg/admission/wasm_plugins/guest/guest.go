@@ -0,0 +1,60 @@
+//go:build wasip1
+
+// Package guest implements the plugin side of the ABI for plugins written in Go. A plugin calls
+// Handle from init with its evaluate function and is built as a WASI reactor:
+//
+//	GOOS=wasip1 GOARCH=wasm go build -buildmode=c-shared -o my_check.wasm ./my_check
+package guest
+
+import (
+	"encoding/json"
+	"unsafe"
+
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/wasm_plugins/abi"
+)
+
+var (
+	evaluate func(abi.Input) abi.Output
+	// buffers keeps what the host writes into reachable until the instance is discarded
+	buffers [][]byte
+)
+
+// Handle sets the function that evaluates every request
+func Handle(fn func(abi.Input) abi.Output) {
+	evaluate = fn
+}
+
+//go:wasmexport bankingkube_abi_version
+func abiVersion() int32 {
+	return abi.Version
+}
+
+//go:wasmexport bankingkube_allocate
+func allocate(size uint32) uint32 {
+	return pin(make([]byte, size))
+}
+
+//go:wasmexport bankingkube_evaluate
+func evaluateInput(ptr unsafe.Pointer, size uint32) uint64 {
+	var input abi.Input
+	var output abi.Output
+	if err := json.Unmarshal(unsafe.Slice((*byte)(ptr), size), &input); err != nil {
+		output.Violations = []abi.Violation{{Reason: "failed_to_decode_input", Message: err.Error()}}
+	} else if evaluate != nil {
+		output = evaluate(input)
+	}
+	encoded, err := json.Marshal(output)
+	if err != nil {
+		encoded = []byte(`{"violations":[{"reason":"failed_to_encode_output","message":"failed to encode output"}]}`)
+	}
+	return abi.Pack(pin(encoded), uint32(len(encoded)))
+}
+
+// pin keeps buf reachable and returns its offset in linear memory
+func pin(buf []byte) uint32 {
+	if len(buf) == 0 {
+		buf = make([]byte, 1)
+	}
+	buffers = append(buffers, buf)
+	return uint32(uintptr(unsafe.Pointer(unsafe.SliceData(buf))))
+}
@@ -0,0 +1,201 @@
+package wasm_plugins
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/wasm_plugins/abi"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+)
+
+// wasmPageSize is the size of a page of WebAssembly linear memory
+const wasmPageSize = 64 << 10
+
+// Limits bound what a single plugin call may use
+type Limits struct {
+	Memory  int64         // Bytes of linear memory, rounded down to 64KiB pages
+	Timeout time.Duration // Wall time of a call, instantiation included
+}
+
+// DefaultLimits fit a plugin built with the Go toolchain; a TinyGo or Rust plugin needs far less
+var DefaultLimits = Limits{Memory: 64 << 20, Timeout: 500 * time.Millisecond}
+
+// Host compiles the plugins of a directory once and runs every call in a fresh instance
+type Host struct {
+	Dir     string
+	limits  Limits
+	runtime wazero.Runtime
+	plugins []*Plugin
+}
+
+// Plugin is one module of the plugin directory
+type Plugin struct {
+	Name   string // File name without .wasm
+	host   *Host
+	module wazero.CompiledModule
+}
+
+// LoadDir compiles every .wasm file of dir and checks that it implements abi.Version.
+// Dot files are left out, like the directories a ConfigMap volume publishes its files through.
+func LoadDir(ctx context.Context, dir string, limits Limits) (*Host, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	pages := uint32(limits.Memory / wasmPageSize)
+	if pages == 0 {
+		return nil, fmt.Errorf("memory limit %d is less than a page", limits.Memory)
+	}
+	runtime := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().
+		WithMemoryLimitPages(pages).
+		WithCloseOnContextDone(true))
+	if _, err := wasi_snapshot_preview1.Instantiate(ctx, runtime); err != nil {
+		runtime.Close(ctx)
+		return nil, err
+	}
+
+	host := &Host{Dir: dir, limits: limits, runtime: runtime}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".") || filepath.Ext(name) != ".wasm" {
+			continue
+		}
+		plugin, err := host.compile(ctx, strings.TrimSuffix(name, ".wasm"), filepath.Join(dir, name))
+		if err != nil {
+			runtime.Close(ctx)
+			return nil, fmt.Errorf("plugin %s: %w", name, err)
+		}
+		host.plugins = append(host.plugins, plugin)
+	}
+	sort.Slice(host.plugins, func(i, j int) bool { return host.plugins[i].Name < host.plugins[j].Name })
+	return host, nil
+}
+
+// compile compiles a module, checks its exports and asks it for its ABI version
+func (h *Host) compile(ctx context.Context, name, path string) (*Plugin, error) {
+	code, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	module, err := h.runtime.CompileModule(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	exports := module.ExportedFunctions()
+	for _, export := range []string{abi.ExportVersion, abi.ExportAllocate, abi.ExportEvaluate} {
+		if _, ok := exports[export]; !ok {
+			return nil, fmt.Errorf("does not export %s", export)
+		}
+	}
+
+	plugin := &Plugin{Name: name, host: h, module: module}
+	err = plugin.instantiate(ctx, func(ctx context.Context, instance api.Module) error {
+		results, err := instance.ExportedFunction(abi.ExportVersion).Call(ctx)
+		if err != nil {
+			return err
+		}
+		if version := int32(results[0]); version != abi.Version {
+			return fmt.Errorf("implements ABI version %d, the webhook implements %d", version, abi.Version)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return plugin, nil
+}
+
+// Plugins returns the plugins of the directory, sorted by name
+func (h *Host) Plugins() []*Plugin {
+	return h.plugins
+}
+
+// Close releases the compiled plugins
+func (h *Host) Close(ctx context.Context) error {
+	return h.runtime.Close(ctx)
+}
+
+// Evaluate passes the request to the plugin and returns what it found. The call runs within the
+// time limit of the host and the deadline of ctx, whichever comes first.
+func (p *Plugin) Evaluate(ctx context.Context, phase abi.Phase, request []byte) (*abi.Output, error) {
+	input, err := json.Marshal(abi.Input{Version: abi.Version, Phase: phase, Request: request})
+	if err != nil {
+		return nil, err
+	}
+
+	var output abi.Output
+	err = p.instantiate(ctx, func(ctx context.Context, instance api.Module) error {
+		results, err := instance.ExportedFunction(abi.ExportAllocate).Call(ctx, uint64(len(input)))
+		if err != nil {
+			return err
+		}
+		ptr := uint32(results[0])
+		if !instance.Memory().Write(ptr, input) {
+			return fmt.Errorf("allocated buffer at %d does not hold %d bytes", ptr, len(input))
+		}
+
+		results, err = instance.ExportedFunction(abi.ExportEvaluate).Call(ctx, uint64(ptr), uint64(len(input)))
+		if err != nil {
+			return err
+		}
+		ptr, size := abi.Unpack(results[0])
+		encoded, ok := instance.Memory().Read(ptr, size)
+		if !ok {
+			return fmt.Errorf("output at %d+%d is outside memory", ptr, size)
+		}
+		decoder := json.NewDecoder(bytes.NewReader(encoded))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&output); err != nil {
+			return fmt.Errorf("invalid output: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &output, nil
+}
+
+// instantiate runs fn against a fresh instance of the plugin, which is closed afterwards
+func (p *Plugin) instantiate(ctx context.Context, fn func(context.Context, api.Module) error) error {
+	ctx, cancel := context.WithTimeout(ctx, p.host.limits.Timeout)
+	defer cancel()
+
+	// No name, so concurrent calls can instantiate the same module. Go plugins are reactors
+	// initialised by _initialize; modules that do not export it are not affected.
+	instance, err := p.host.runtime.InstantiateModule(ctx, p.module, wazero.NewModuleConfig().
+		WithName("").
+		WithStartFunctions("_initialize"))
+	if err != nil {
+		return limitError(ctx, err)
+	}
+	defer instance.Close(context.Background())
+
+	return limitError(ctx, fn(ctx, instance))
+}
+
+// limitError reports a call that the time limit interrupted as such rather than as an exit code,
+// and leaves the wasm stack trace of a trap out of the message
+func limitError(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("exceeded the time limit: %w", err)
+	}
+	if message, _, trapped := strings.Cut(err.Error(), "\n"); trapped {
+		return errors.New(message)
+	}
+	return err
+}
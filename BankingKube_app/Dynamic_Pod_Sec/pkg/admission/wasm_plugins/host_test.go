@@ -0,0 +1,133 @@
+package wasm_plugins
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/checks"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// buildPlugin compiles the Go plugin in pkg into dir/name.wasm with the Go toolchain running the tests
+func buildPlugin(t *testing.T, pkg, dir, name string) {
+	t.Helper()
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("the go tool is needed to build the test plugins")
+	}
+	cmd := exec.Command(goTool, "build", "-buildmode=c-shared", "-o", filepath.Join(dir, name+".wasm"), pkg)
+	cmd.Env = append(os.Environ(), "GOOS=wasip1", "GOARCH=wasm")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("failed to build %s: %v\n%s", pkg, err, output)
+	}
+}
+
+func loadPlugins(t *testing.T, dir string, limits Limits) *Host {
+	t.Helper()
+	host, err := LoadDir(context.Background(), dir, limits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { host.Close(context.Background()) })
+	return host
+}
+
+func podRequest(name, object string) *admissionv1.AdmissionRequest {
+	return &admissionv1.AdmissionRequest{
+		Name:      name,
+		Namespace: "payments",
+		Kind:      metav1.GroupVersionKind{Version: "v1", Kind: "Pod"},
+		Operation: admissionv1.Create,
+		Object:    runtime.RawExtension{Raw: []byte(object)},
+	}
+}
+
+func TestExamplePlugin(t *testing.T) {
+	dir := t.TempDir()
+	buildPlugin(t, "./example", dir, "require_team")
+	// Left out like the files a ConfigMap volume publishes a second time
+	if err := os.WriteFile(filepath.Join(dir, ".hidden.wasm"), []byte("not a module"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	host := loadPlugins(t, dir, DefaultLimits)
+	if len(host.Plugins()) != 1 || host.Plugins()[0].Name != "require_team" {
+		t.Fatalf("plugins = %v, want require_team", host.Plugins())
+	}
+	plugin := host.Plugins()[0]
+
+	got := plugin.Check(context.Background(), checks.NewEvaluation(podRequest("web", `{"metadata":{"name":"web"},"spec":{"containers":[{"name":"app"}]}}`), nil))
+	want := []checks.Violation{{
+		Reason:   "missing_team_label",
+		Field:    "metadata.labels.team",
+		Expected: "a team name",
+		Message:  "pods must carry a team label",
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("violations = %+v, want %+v", got, want)
+	}
+	if got := plugin.Check(context.Background(), checks.NewEvaluation(podRequest("web", `{"metadata":{"labels":{"team":"payments"}}}`), nil)); len(got) != 0 {
+		t.Errorf("violations = %+v for a labelled pod", got)
+	}
+
+	object := `{"metadata":{"name":"web"},"spec":{"containers":[{"name":"app"}]}}`
+	patched := host.Mutate(context.Background(), podRequest("web", object), []byte(object))
+	if want := `{"metadata":{"name":"web"},"spec":{"automountServiceAccountToken":false,"containers":[{"name":"app"}]}}`; string(patched) != want {
+		t.Errorf("Mutate() = %s, want %s", patched, want)
+	}
+	object = `{"spec":{"automountServiceAccountToken":true}}`
+	if patched := host.Mutate(context.Background(), podRequest("web", object), []byte(object)); string(patched) != object {
+		t.Errorf("Mutate() = %s, want the object unchanged", patched)
+	}
+}
+
+func TestPluginLimits(t *testing.T) {
+	dir := t.TempDir()
+	buildPlugin(t, "./testdata/misbehaving", dir, "misbehaving")
+	host := loadPlugins(t, dir, Limits{Memory: 64 << 20, Timeout: time.Second})
+	plugin := host.Plugins()[0]
+
+	t.Run("loop", func(t *testing.T) {
+		start := time.Now()
+		got := plugin.Check(context.Background(), checks.NewEvaluation(podRequest("loop", `{}`), nil))
+		if len(got) != 1 || !got[0].Internal || !strings.Contains(got[0].Message, "time limit") {
+			t.Errorf("violations = %+v, want an internal error mentioning the time limit", got)
+		}
+		if elapsed := time.Since(start); elapsed > 2*time.Second {
+			t.Errorf("the call took %s", elapsed)
+		}
+	})
+
+	t.Run("oom", func(t *testing.T) {
+		// A small memory limit and a generous timeout, so only the memory limit can stop the plugin,
+		// however slowly the heap grows (under -race, say)
+		host := loadPlugins(t, dir, Limits{Memory: 16 << 20, Timeout: 30 * time.Second})
+		// The Go runtime aborts when the memory limit refuses to grow the heap
+		got := host.Plugins()[0].Check(context.Background(), checks.NewEvaluation(podRequest("oom", `{}`), nil))
+		if len(got) != 1 || !got[0].Internal || !strings.Contains(got[0].Message, "wasm error: unreachable") {
+			t.Errorf("violations = %+v, want an internal error mentioning %q", got, "wasm error: unreachable")
+		}
+	})
+
+	// A patch that does not apply is skipped rather than failing the mutation
+	object := `{"spec":{}}`
+	if patched := host.Mutate(context.Background(), podRequest("bad_patch", object), []byte(object)); string(patched) != object {
+		t.Errorf("Mutate() = %s, want the object unchanged", patched)
+	}
+}
+
+func TestLoadDirRejectsOtherABIVersion(t *testing.T) {
+	dir := t.TempDir()
+	buildPlugin(t, "./testdata/other_abi", dir, "other_abi")
+	_, err := LoadDir(context.Background(), dir, DefaultLimits)
+	if err == nil || !strings.Contains(err.Error(), "ABI version 0") {
+		t.Errorf("LoadDir() = %v, want the ABI version mismatch", err)
+	}
+}
@@ -0,0 +1,12 @@
+package wasm_plugins
+
+import (
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/checks"
+)
+
+// Group is the check group served on /validate/wasm
+const Group = "wasm"
+
+func init() {
+	checks.RegisterProvider(Group, Plugins)
+}
@@ -0,0 +1,38 @@
+//go:build wasip1
+
+// Command misbehaving is a test plugin that breaks the limits of the host, depending on the name of the request
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/jsonpatch"
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/wasm_plugins/abi"
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/wasm_plugins/guest"
+)
+
+var sink [][]byte
+
+func init() {
+	guest.Handle(func(input abi.Input) abi.Output {
+		var request struct {
+			Name string `json:"name"`
+		}
+		json.Unmarshal(input.Request, &request)
+		switch request.Name {
+		case "loop":
+			for {
+				sink = nil
+			}
+		case "oom":
+			for {
+				sink = append(sink, make([]byte, 1<<20))
+			}
+		case "bad_patch":
+			return abi.Output{Patch: []jsonpatch.Operation{{Op: jsonpatch.OpReplace, Path: "/spec/missing/field", Value: 1}}}
+		}
+		return abi.Output{}
+	})
+}
+
+func main() {}
@@ -0,0 +1,15 @@
+//go:build wasip1
+
+// Command other_abi is a test plugin built against an ABI version the host does not implement
+package main
+
+//go:wasmexport bankingkube_abi_version
+func abiVersion() int32 { return 0 }
+
+//go:wasmexport bankingkube_allocate
+func allocate(size uint32) uint32 { return 0 }
+
+//go:wasmexport bankingkube_evaluate
+func evaluate(ptr, size uint32) uint64 { return 0 }
+
+func main() {}
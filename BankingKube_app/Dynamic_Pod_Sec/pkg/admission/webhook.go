@@ -38,23 +38,67 @@ package admission
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"log"
 	"net/http"
+	"strings"
+	"time"
 
-	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/api_restrictions"
-	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/context_capabilities"
-	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/image_security"
-	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/network_security"
-	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/rbac_checks"
-	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/resource_limits"
-	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/volume_security"
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/checks"
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/jsonpatch"
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/wasm_plugins"
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/policy"
 
 	admissionv1 "k8s.io/api/admission/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+const (
+	// ValidatePathPrefix is the route prefix under which every check group is served
+	ValidatePathPrefix = "/validate/"
+	// MutatePodPath is the route of the mutating webhook
+	MutatePodPath = "/mutate/pod"
+
+	// defaultWebhookTimeout is the API server default when the webhook configuration sets no timeoutSeconds
+	defaultWebhookTimeout = 10 * time.Second
+	// maxResponseMargin is the part of the webhook timeout kept for encoding and sending the response
+	maxResponseMargin = time.Second
+)
+
+// PolicySource returns the policies that apply to requests in a namespace ("" for cluster-scoped objects)
+type PolicySource func(namespace string) (*policy.SecurityPolicies, error)
+
+// policySource serves the policy file unless UsePolicySource replaces it
+var policySource PolicySource = func(string) (*policy.SecurityPolicies, error) { return policy.LoadDefault() }
+
+// UsePolicySource makes the webhook read its policies from source. Call it before serving requests.
+func UsePolicySource(source PolicySource) {
+	policySource = source
+}
+
+// namespaceLookup serves the namespace labels custom rules read; nil until UseNamespaceLookup is called
+var namespaceLookup checks.NamespaceLookup
+
+// UseNamespaceLookup makes namespace labels available to the checks. Call it before serving requests.
+func UseNamespaceLookup(lookup checks.NamespaceLookup) {
+	namespaceLookup = lookup
+}
+
 // HandleAdmissionRequest handles incoming admission requests based on the URL path
 func HandleAdmissionRequest(w http.ResponseWriter, r *http.Request) {
+	handleAdmissionRequest(w, r, policySource, namespaceLookup)
+}
+
+// Handler serves admission requests like HandleAdmissionRequest, but reads the policies from source
+// and the namespace labels from namespaces rather than from what the webhook is configured with
+func Handler(source PolicySource, namespaces checks.NamespaceLookup) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handleAdmissionRequest(w, r, source, namespaces)
+	}
+}
+
+func handleAdmissionRequest(w http.ResponseWriter, r *http.Request, source PolicySource, namespaces checks.NamespaceLookup) {
 	var admissionReview admissionv1.AdmissionReview
 	err := json.NewDecoder(r.Body).Decode(&admissionReview)
 	if err != nil {
@@ -62,25 +106,23 @@ func HandleAdmissionRequest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Determine which validation to perform based on the request path
+	// Determine which check group to run based on the request path
 	var response *admissionv1.AdmissionResponse
-	switch r.URL.Path {
-	case "/validate/context":
-		response = validateContext(admissionReview.Request)
-	case "/validate/volumes":
-		response = validateVolumes(admissionReview.Request)
-	case "/validate/network":
-		response = validateNetwork(admissionReview.Request)
-	case "/validate/api":
-		response = validateAPI(admissionReview.Request)
-	case "/validate/image":
-		response = validateImage(admissionReview.Request)
-	case "/validate/rbac":
-		response = validateRBAC(admissionReview.Request)
-	case "/validate/resources":
-		response = validateResources(admissionReview.Request)
-	case "/mutate/pod":
-		response = mutatePod(admissionReview.Request)
+	switch {
+	case r.URL.Path == MutatePodPath:
+		response = mutatePod(admissionReview.Request, source)
+	case strings.HasPrefix(r.URL.Path, ValidatePathPrefix):
+		group := strings.TrimPrefix(r.URL.Path, ValidatePathPrefix)
+		if !checks.Default.HasGroup(group) {
+			http.Error(w, "Invalid validation path", http.StatusNotFound)
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), checkDeadline(r))
+		defer cancel()
+		namespace := admissionReview.Request.Namespace
+		eval := checks.NewEvaluation(admissionReview.Request, func() (*policy.SecurityPolicies, error) { return source(namespace) })
+		eval.Namespaces = namespaces
+		response = validate(ctx, checks.Default, group, eval)
 	default:
 		http.Error(w, "Invalid validation path", http.StatusNotFound)
 		return
@@ -100,154 +142,198 @@ func HandleAdmissionRequest(w http.ResponseWriter, r *http.Request) {
 	w.Write(resp)
 }
 
-// validateContext handles context and capabilities checks
-func validateContext(request *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
-	allowed := true
-	result := &metav1.Status{Message: "Pod context validation passed"}
-
-	if !context_capabilities.CheckPodSecurityContext(context.Background(), request) {
-		allowed = false
-		result = &metav1.Status{Message: "Pod contains privileged containers, which is not allowed."}
-	}
-	if !context_capabilities.CheckCapabilities(context.Background(), request) {
-		allowed = false
-		result = &metav1.Status{Message: "Pod contains containers with disallowed capabilities."}
+// checkDeadline returns how long the checks of a request may run. The API server passes the
+// timeoutSeconds of the webhook configuration as the timeout query parameter (e.g. ?timeout=5s);
+// part of it is kept back so the response reaches the API server before it gives up.
+func checkDeadline(r *http.Request) time.Duration {
+	timeout := defaultWebhookTimeout
+	if value := r.URL.Query().Get("timeout"); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil && parsed > 0 {
+			timeout = parsed
+		} else {
+			log.Printf("Ignoring invalid webhook timeout %q\n", value)
+		}
 	}
 
-	return &admissionv1.AdmissionResponse{Allowed: allowed, Result: result}
-}
-
-// validateVolumes handles volume security checks
-func validateVolumes(request *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
-	allowed := true
-	result := &metav1.Status{Message: "Pod volume validation passed"}
-
-	if !volume_security.CheckHostPath(request) {
-		allowed = false
-		result = &metav1.Status{Message: "Pod contains disallowed host paths."}
+	margin := timeout / 5
+	if margin > maxResponseMargin {
+		margin = maxResponseMargin
 	}
-
-	return &admissionv1.AdmissionResponse{Allowed: allowed, Result: result}
+	return timeout - margin
 }
 
-// validateNetwork handles network security checks
-func validateNetwork(request *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
-	allowed := true
-	result := &metav1.Status{Message: "Pod network validation passed"}
-
-	if !network_security.CheckPolicyConsistency() {
-		allowed = false
-		result = &metav1.Status{Message: "Security policies are inconsistent."}
-	}
-	if !network_security.CheckNetworkPolicy(request) {
-		allowed = false
-		result = &metav1.Status{Message: "Pod does not comply with network policies."}
-	}
-	if !network_security.CheckHostNetwork(request) {
-		allowed = false
-		result = &metav1.Status{Message: "Pod is using the host network, which is disallowed."}
-	}
-	if !network_security.CheckEgress(context.Background(),request) {
-		allowed = false
-		result = &metav1.Status{Message: "Pod has an egress route that violates policy."}
-	}
-	if !network_security.CheckIngress(request) {
-		allowed = false
-		result = &metav1.Status{Message: "Pod has an ingress route that violates policy."}
+// validate runs every check of a group that applies to the requested kind and
+// handles their violations according to the enforcement mode of each check.
+// Only violations of enforced checks deny the request; all of them are reported in a single response.
+// The checks share the evaluation, so the object is decoded and the policies are loaded once.
+// They run concurrently, each within its own time budget and the deadline of ctx; the failure
+// policy of a check that does not finish in time decides whether that denies the request, and
+// the error policy of a check that fails with an internal error decides the same for the error.
+// On UPDATE only the violations in fields the update changes count, unless the policies ask to recheck
+// everything; DELETE and CONNECT requests are allowed without running any check. Violations lifted
+// by an exemption are recorded in the exemptions audit annotation instead.
+func validate(ctx context.Context, registry *checks.Registry, group string, eval *checks.Evaluation) *admissionv1.AdmissionResponse {
+	request := eval.Request
+	if checks.Skipped(request) {
+		return &admissionv1.AdmissionResponse{
+			Allowed: true,
+			Result:  &metav1.Status{Message: fmt.Sprintf("%s validation skipped for %s", group, request.Operation)},
+		}
 	}
 
-	return &admissionv1.AdmissionResponse{Allowed: allowed, Result: result}
-}
+	enforcement := &policy.Enforcement{}
+	execution := &policy.Execution{}
+	updates := &policy.Updates{}
+	var exemptions []policy.Exemption
+	if policies, err := eval.Policies(); err != nil {
+		// Enforce every check rather than letting a broken file relax the policies
+		log.Println("Failed to load enforcement modes, enforcing all checks:", err)
+	} else {
+		enforcement = &policies.Enforcement
+		execution = &policies.Execution
+		updates = &policies.Updates
+		exemptions = policies.Exemptions
+	}
 
-// validateAPI handles API access and service account checks
-func validateAPI(request *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
-	allowed := true
-	result := &metav1.Status{Message: "Pod API access validation passed"}
+	budget := func(check checks.Check) time.Duration { return execution.TimeoutFor(check.Name()) }
 
-	if !api_restrictions.CheckAPIAccess(context.Background(), request) {
-		allowed = false
-		result = &metav1.Status{Message: "Pod is attempting to access restricted API paths."}
+	var d decision
+	for _, outcome := range checks.Run(ctx, eval, registry.Resolve(group, eval), budget) {
+		name := outcome.Check.Name()
+		mode := enforcement.ModeForDeclared(name, checks.DeclaredMode(outcome.Check))
+		if outcome.Unfinished {
+			d.unfinished(ctx, group, outcome, mode, execution.FailurePolicyFor(name), request)
+			continue
+		}
+		violations := outcome.Result.Violations
+		if request.Operation == admissionv1.Update && !updates.RecheckAllFor(name) {
+			violations = introduced(eval, violations)
+		}
+		violations = d.applyExemptions(ctx, group, outcome.Check, eval, exemptions, violations)
+		if len(violations) > 0 {
+			d.record(ctx, group, outcome.Check, mode, request, violations)
+		}
+		if errs := outcome.Result.Errors; len(errs) > 0 {
+			errorPolicy := execution.ErrorPolicyFor(name, checks.DeclaredErrorPolicy(outcome.Check))
+			d.internalErrors(ctx, group, outcome.Check, mode, errorPolicy, request, errs)
+		}
 	}
-	if !api_restrictions.CheckServiceAccount(context.Background(), request) {
-		allowed = false
-		result = &metav1.Status{Message: "Pod is using a restricted service account."}
+	d.finish()
+
+	if len(d.denied) == 0 {
+		return &admissionv1.AdmissionResponse{
+			Allowed:          true,
+			Result:           &metav1.Status{Message: fmt.Sprintf("%s validation passed", group)},
+			Warnings:         d.warnings,
+			AuditAnnotations: d.auditAnnotations,
+		}
 	}
 
-	return &admissionv1.AdmissionResponse{Allowed: allowed, Result: result}
+	return &admissionv1.AdmissionResponse{
+		Allowed: false,
+		Result: &metav1.Status{
+			Status:  metav1.StatusFailure,
+			Code:    http.StatusForbidden,
+			Reason:  metav1.StatusReasonForbidden,
+			Message: checks.Summarize(d.denied),
+			Details: &metav1.StatusDetails{
+				Name:   request.Name,
+				Kind:   request.Kind.Kind,
+				Causes: checks.Causes(d.denied),
+			},
+		},
+		Warnings:         d.warnings,
+		AuditAnnotations: d.auditAnnotations,
+	}
 }
 
-// validateImage handles image security checks
-func validateImage(request *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
-	allowed := true
-	result := &metav1.Status{Message: "Pod image validation passed"}
-
-	if !image_security.CheckImageRegistry(context.Background(),request) {
-		allowed = false
-		result = &metav1.Status{Message: "Pod is using an image from a disallowed registry."}
-	}
-	if !image_security.CheckImageSigning(context.Background(),request) {
-		allowed = false
-		result = &metav1.Status{Message: "Pod is using an unsigned image."}
+// introduced keeps the violations an UPDATE introduces, leaving out those the object already had
+func introduced(eval *checks.Evaluation, violations []checks.Violation) []checks.Violation {
+	if _, err := eval.Changes(); err != nil {
+		log.Println("Failed to diff the updated object, rechecking every field:", err)
+		return violations
 	}
-	if !image_security.CheckImageTags(context.Background(),request) {
-		allowed = false
-		result = &metav1.Status{Message: "Pod is using an image with a disallowed tag."}
+	var kept []checks.Violation
+	for _, v := range violations {
+		if eval.Introduced(v) {
+			kept = append(kept, v)
+		}
 	}
-
-	return &admissionv1.AdmissionResponse{Allowed: allowed, Result: result}
+	return kept
 }
 
-// validateRBAC handles RBAC-related checks
-func validateRBAC(request *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
-	allowed := true
-	result := &metav1.Status{Message: "RBAC validation passed"}
-
-	if !rbac_checks.CheckRBACBinding(request) {
-		allowed = false
-		result = &metav1.Status{Message: "ClusterRoleBinding uses a restricted ClusterRole."}
-	}
-	if !rbac_checks.CheckPermissionLevels(request) {
-		allowed = false
-		result = &metav1.Status{Message: "Role or ClusterRole has restricted permissions."}
+// mutatePod applies baseline security configurations and the ID defaults of the namespace's policies,
+// and answers with the RFC 6902 JSON Patch that turns the admitted pod into the mutated one
+func mutatePod(request *admissionv1.AdmissionRequest, source PolicySource) *admissionv1.AdmissionResponse {
+	if checks.Skipped(request) {
+		return &admissionv1.AdmissionResponse{Allowed: true}
 	}
-	if !rbac_checks.CheckRoleScope(request) {
-		allowed = false
-		result = &metav1.Status{Message: "Role or ClusterRole has restricted scope."}
+
+	pod := &corev1.Pod{}
+	if err := json.Unmarshal(request.Object.Raw, pod); err != nil {
+		return &admissionv1.AdmissionResponse{
+			Allowed: false,
+			Result:  &metav1.Status{Message: "Failed to parse Pod object"},
+		}
 	}
 
-	return &admissionv1.AdmissionResponse{Allowed: allowed, Result: result}
-}
+	// The defaults are applied to the typed pod, so the typed object before and after mutation gives the
+	// changes. They are then applied to the raw request, which keeps every field the typed model does not
+	// know, and the patch is the diff of the raw request against that
+	original, err := json.Marshal(pod)
+	if err != nil {
+		return &admissionv1.AdmissionResponse{
+			Allowed: false,
+			Result:  &metav1.Status{Message: "Failed to generate patch"},
+		}
+	}
 
-// validateResources handles resource limit and request checks
-func validateResources(request *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
-	allowed := true
-	result := &metav1.Status{Message: "Pod resource validation passed"}
+	ApplyBaselineSecurity(pod)
+	if policies, err := source(request.Namespace); err != nil {
+		// The validating webhooks deny what the defaults would have fixed, so never block the pod here
+		log.Println("Failed to load policies, skipping the ID defaults:", err)
+	} else {
+		ApplyIDDefaults(pod, &policies.PodSecurityContext)
+	}
 
-	if !resource_limits.CheckResourceLimits(request) {
-		allowed = false
-		result = &metav1.Status{Message: "Pod contains containers without resource limits."}
+	mutated, err := json.Marshal(pod)
+	if err != nil {
+		return &admissionv1.AdmissionResponse{
+			Allowed: false,
+			Result:  &metav1.Status{Message: "Failed to generate patch"},
+		}
 	}
-	if !resource_limits.CheckResourceRequests(request) {
-		allowed = false
-		result = &metav1.Status{Message: "Pod contains containers without resource requests."}
+	defaults, err := jsonpatch.CreatePatch(original, mutated)
+	if err != nil {
+		return &admissionv1.AdmissionResponse{
+			Allowed: false,
+			Result:  &metav1.Status{Message: "Failed to generate patch"},
+		}
 	}
+	patched, err := jsonpatch.Apply(request.Object.Raw, defaults)
+	if err != nil {
+		log.Println("Failed to apply the baseline defaults to the admitted pod:", err)
+		return &admissionv1.AdmissionResponse{
+			Allowed: false,
+			Result:  &metav1.Status{Message: "Failed to generate patch"},
+		}
+	}
+	patched = applyPluginPatches(request, patched)
 
-	return &admissionv1.AdmissionResponse{Allowed: allowed, Result: result}
-}
-
-// mutatePod applies baseline security configurations
-func mutatePod(request *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
-	pod := &corev1.Pod{}
-	if err := json.Unmarshal(request.Object.Raw, pod); err != nil {
+	ops, err := jsonpatch.Diff(request.Object.Raw, patched)
+	if err != nil {
 		return &admissionv1.AdmissionResponse{
 			Allowed: false,
-			Result:  &metav1.Status{Message: "Failed to parse Pod object"},
+			Result:  &metav1.Status{Message: "Failed to generate patch"},
 		}
 	}
 
-	applyBaselineSecurity(pod)
+	// Nothing to change, admit the pod as is
+	if len(ops) == 0 {
+		return &admissionv1.AdmissionResponse{Allowed: true}
+	}
 
-	patchBytes, err := json.Marshal(pod)
+	patchBytes, err := json.Marshal(ops)
 	if err != nil {
 		return &admissionv1.AdmissionResponse{
 			Allowed: false,
@@ -265,8 +351,27 @@ func mutatePod(request *admissionv1.AdmissionRequest) *admissionv1.AdmissionResp
 	}
 }
 
-// applyBaselineSecurity applies essential security defaults
-func applyBaselineSecurity(pod *corev1.Pod) {
+// applyPluginPatches applies the patches of the WebAssembly plugins on top of the baseline defaults.
+// The result must still decode as a pod; otherwise the plugin patches are dropped. It is returned as
+// the plugins left it, so their patches to fields the typed model does not know are kept.
+func applyPluginPatches(request *admissionv1.AdmissionRequest, mutated []byte) []byte {
+	host, err := wasm_plugins.DefaultHost()
+	if err != nil || host == nil {
+		return mutated
+	}
+
+	patched := host.Mutate(context.Background(), request, mutated)
+	pod := &corev1.Pod{}
+	if err := json.Unmarshal(patched, pod); err != nil {
+		log.Println("Dropping the wasm plugin patches, the patched object is not a pod:", err)
+		return mutated
+	}
+	return patched
+}
+
+// ApplyBaselineSecurity applies essential security defaults. The mutating webhook calls it for every
+// admitted pod and scan -fix writes the same defaults into manifests.
+func ApplyBaselineSecurity(pod *corev1.Pod) {
 	for i := range pod.Spec.Containers {
 		if pod.Spec.Containers[i].SecurityContext == nil {
 			pod.Spec.Containers[i].SecurityContext = &corev1.SecurityContext{}
@@ -277,11 +382,75 @@ func applyBaselineSecurity(pod *corev1.Pod) {
 		pod.Spec.Containers[i].SecurityContext.ReadOnlyRootFilesystem = boolPtr(true)
 		pod.Spec.Containers[i].SecurityContext.AllowPrivilegeEscalation = boolPtr(false)
 
-		// Drop disallowed capabilities
-		pod.Spec.Containers[i].SecurityContext.Capabilities = &corev1.Capabilities{
-			Drop: []corev1.Capability{"CAP_SYS_ADMIN", "CAP_NET_ADMIN"},
+		// Drop disallowed capabilities, keeping the container's own add and drop lists
+		if pod.Spec.Containers[i].SecurityContext.Capabilities == nil {
+			pod.Spec.Containers[i].SecurityContext.Capabilities = &corev1.Capabilities{}
+		}
+		dropBaselineCapabilities(pod.Spec.Containers[i].SecurityContext.Capabilities)
+	}
+
+	// Default the seccomp profile of every container that sets none to RuntimeDefault; Windows pods have no seccomp
+	if pod.Spec.OS == nil || pod.Spec.OS.Name != corev1.Windows {
+		if pod.Spec.SecurityContext == nil {
+			pod.Spec.SecurityContext = &corev1.PodSecurityContext{}
+		}
+		if pod.Spec.SecurityContext.SeccompProfile == nil {
+			pod.Spec.SecurityContext.SeccompProfile = &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault}
+		}
+	}
+}
+
+// baselineDrops are the capabilities ApplyBaselineSecurity drops, in the spelling Kubernetes expects
+var baselineDrops = []corev1.Capability{"SYS_ADMIN", "NET_ADMIN"}
+
+// dropBaselineCapabilities adds the baseline drops the container does not drop yet in either spelling.
+// Dropping ALL already covers them.
+func dropBaselineCapabilities(capabilities *corev1.Capabilities) {
+	dropped := make([]string, len(capabilities.Drop))
+	for i, capability := range capabilities.Drop {
+		dropped[i] = string(capability)
+	}
+	for _, capability := range baselineDrops {
+		if !policy.HasCapability(dropped, string(capability)) {
+			capabilities.Drop = append(capabilities.Drop, capability)
+		}
+	}
+}
+
+// ApplyIDDefaults sets the user and group IDs the policies require and the pod leaves unset, when the
+// policies ask for it with defaultIDs. MustRunAs IDs default to the minimum of their first range on the
+// pod, so every container that does not set its own inherits them; MustRunAsNonRoot sets runAsNonRoot.
+func ApplyIDDefaults(pod *corev1.Pod, rules *policy.PodSecurityContext) {
+	if !rules.DefaultIDs {
+		return
+	}
+	sc := pod.Spec.SecurityContext
+	if sc == nil {
+		sc = &corev1.PodSecurityContext{}
+	}
+	changed := false
+
+	defaultID := func(id **int64, rule *policy.IDPolicy) {
+		if value, ok := rule.Default(); ok && *id == nil {
+			*id = &value
+			changed = true
 		}
 	}
+	defaultID(&sc.RunAsUser, &rules.RunAsUser)
+	defaultID(&sc.RunAsGroup, &rules.RunAsGroup)
+	defaultID(&sc.FSGroup, &rules.FSGroup)
+	if value, ok := rules.SupplementalGroups.Default(); ok && len(sc.SupplementalGroups) == 0 {
+		sc.SupplementalGroups = []int64{value}
+		changed = true
+	}
+	if rules.RunAsUser.Rule == policy.IDRuleMustRunAsNonRoot && sc.RunAsUser == nil && sc.RunAsNonRoot == nil {
+		sc.RunAsNonRoot = boolPtr(true)
+		changed = true
+	}
+
+	if changed {
+		pod.Spec.SecurityContext = sc
+	}
 }
 
 // Helper function to create boolean pointers
@@ -0,0 +1,129 @@
+// Package workload extracts the pod template of the built-in workload kinds so that the
+// pod checks can evaluate a Deployment, StatefulSet, DaemonSet, Job or CronJob at apply time,
+// instead of only when its controller creates the pods.
+package workload
+
+import (
+	"encoding/json"
+	"fmt"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Kinds lists every kind the pod checks apply to
+var Kinds = []string{"Pod", "Deployment", "ReplicaSet", "StatefulSet", "DaemonSet", "Job", "CronJob", "ReplicationController"}
+
+// PodTemplate is the pod, or pod template, carried by an admitted object
+type PodTemplate struct {
+	// Kind of the admitted object (e.g. Deployment)
+	Kind string
+	// Pod holds the template metadata and spec. For workloads its Name and Namespace are
+	// those of the workload, so log lines and metrics identify the object the user applied.
+	Pod *corev1.Pod
+	// MetadataPath is the field path of the pod metadata inside the admitted object (e.g. spec.template.metadata)
+	MetadataPath string
+	// SpecPath is the field path of the pod spec inside the admitted object (e.g. spec.template.spec)
+	SpecPath string
+}
+
+// Supported reports whether pod templates can be extracted from objects of the kind
+func Supported(kind string) bool {
+	for _, k := range Kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// Extract decodes the object of an admission request and returns its pod template
+func Extract(request *admissionv1.AdmissionRequest) (*PodTemplate, error) {
+	template, err := FromObject(request.Kind.Kind, request.Object.Raw)
+	if err != nil {
+		return nil, err
+	}
+	if template.Pod.Namespace == "" {
+		template.Pod.Namespace = request.Namespace
+	}
+	return template, nil
+}
+
+// FromObject decodes a serialized object of the given kind and returns its pod template
+func FromObject(kind string, raw []byte) (*PodTemplate, error) {
+	switch kind {
+	case "Pod", "":
+		pod := &corev1.Pod{}
+		if err := json.Unmarshal(raw, pod); err != nil {
+			return nil, err
+		}
+		return &PodTemplate{Kind: "Pod", Pod: pod, MetadataPath: "metadata", SpecPath: "spec"}, nil
+	case "Deployment":
+		obj := &appsv1.Deployment{}
+		if err := json.Unmarshal(raw, obj); err != nil {
+			return nil, err
+		}
+		return fromTemplate(kind, obj.ObjectMeta, &obj.Spec.Template, "spec.template"), nil
+	case "ReplicaSet":
+		obj := &appsv1.ReplicaSet{}
+		if err := json.Unmarshal(raw, obj); err != nil {
+			return nil, err
+		}
+		return fromTemplate(kind, obj.ObjectMeta, &obj.Spec.Template, "spec.template"), nil
+	case "StatefulSet":
+		obj := &appsv1.StatefulSet{}
+		if err := json.Unmarshal(raw, obj); err != nil {
+			return nil, err
+		}
+		return fromTemplate(kind, obj.ObjectMeta, &obj.Spec.Template, "spec.template"), nil
+	case "DaemonSet":
+		obj := &appsv1.DaemonSet{}
+		if err := json.Unmarshal(raw, obj); err != nil {
+			return nil, err
+		}
+		return fromTemplate(kind, obj.ObjectMeta, &obj.Spec.Template, "spec.template"), nil
+	case "Job":
+		obj := &batchv1.Job{}
+		if err := json.Unmarshal(raw, obj); err != nil {
+			return nil, err
+		}
+		return fromTemplate(kind, obj.ObjectMeta, &obj.Spec.Template, "spec.template"), nil
+	case "CronJob":
+		obj := &batchv1.CronJob{}
+		if err := json.Unmarshal(raw, obj); err != nil {
+			return nil, err
+		}
+		return fromTemplate(kind, obj.ObjectMeta, &obj.Spec.JobTemplate.Spec.Template, "spec.jobTemplate.spec.template"), nil
+	case "ReplicationController":
+		obj := &corev1.ReplicationController{}
+		if err := json.Unmarshal(raw, obj); err != nil {
+			return nil, err
+		}
+		template := obj.Spec.Template
+		if template == nil {
+			template = &corev1.PodTemplateSpec{}
+		}
+		return fromTemplate(kind, obj.ObjectMeta, template, "spec.template"), nil
+	}
+	return nil, fmt.Errorf("kind %q does not carry a pod template", kind)
+}
+
+// fromTemplate builds the PodTemplate of a workload
+func fromTemplate(kind string, owner metav1.ObjectMeta, template *corev1.PodTemplateSpec, templatePath string) *PodTemplate {
+	pod := &corev1.Pod{ObjectMeta: template.ObjectMeta, Spec: template.Spec}
+	pod.Name = owner.Name
+	if pod.Name == "" {
+		pod.Name = owner.GenerateName
+	}
+	pod.Namespace = owner.Namespace
+
+	return &PodTemplate{
+		Kind:         kind,
+		Pod:          pod,
+		MetadataPath: templatePath + ".metadata",
+		SpecPath:     templatePath + ".spec",
+	}
+}
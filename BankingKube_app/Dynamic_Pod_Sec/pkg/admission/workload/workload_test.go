@@ -0,0 +1,66 @@
+package workload
+
+import (
+	"testing"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestExtract(t *testing.T) {
+	const podSpec = `{"serviceAccountName":"payments","containers":[{"name":"app","image":"myregistry.com/app:1.0"}]}`
+	const template = `{"metadata":{"labels":{"app":"web"},"annotations":{"egressIPs":"10.0.0.1"}},"spec":` + podSpec + `}`
+
+	tests := []struct {
+		kind         string
+		object       string
+		metadataPath string
+		specPath     string
+	}{
+		{"Pod", `{"metadata":{"name":"web","labels":{"app":"web"},"annotations":{"egressIPs":"10.0.0.1"}},"spec":` + podSpec + `}`, "metadata", "spec"},
+		{"Deployment", `{"metadata":{"name":"web"},"spec":{"template":` + template + `}}`, "spec.template.metadata", "spec.template.spec"},
+		{"ReplicaSet", `{"metadata":{"name":"web"},"spec":{"template":` + template + `}}`, "spec.template.metadata", "spec.template.spec"},
+		{"StatefulSet", `{"metadata":{"name":"web"},"spec":{"template":` + template + `}}`, "spec.template.metadata", "spec.template.spec"},
+		{"DaemonSet", `{"metadata":{"name":"web"},"spec":{"template":` + template + `}}`, "spec.template.metadata", "spec.template.spec"},
+		{"Job", `{"metadata":{"name":"web"},"spec":{"template":` + template + `}}`, "spec.template.metadata", "spec.template.spec"},
+		{"CronJob", `{"metadata":{"name":"web"},"spec":{"jobTemplate":{"spec":{"template":` + template + `}}}}`, "spec.jobTemplate.spec.template.metadata", "spec.jobTemplate.spec.template.spec"},
+		{"ReplicationController", `{"metadata":{"name":"web"},"spec":{"template":` + template + `}}`, "spec.template.metadata", "spec.template.spec"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.kind, func(t *testing.T) {
+			request := &admissionv1.AdmissionRequest{
+				Namespace: "payments",
+				Kind:      metav1.GroupVersionKind{Kind: tt.kind},
+				Object:    runtime.RawExtension{Raw: []byte(tt.object)},
+			}
+
+			got, err := Extract(request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Kind != tt.kind || got.MetadataPath != tt.metadataPath || got.SpecPath != tt.specPath {
+				t.Errorf("got kind %q, paths %q and %q", got.Kind, got.MetadataPath, got.SpecPath)
+			}
+			if got.Pod.Name != "web" || got.Pod.Namespace != "payments" {
+				t.Errorf("pod is named %s/%s, want payments/web", got.Pod.Namespace, got.Pod.Name)
+			}
+			if got.Pod.Labels["app"] != "web" || got.Pod.Annotations["egressIPs"] != "10.0.0.1" {
+				t.Errorf("template metadata was not carried over: %+v", got.Pod.ObjectMeta)
+			}
+			if len(got.Pod.Spec.Containers) != 1 || got.Pod.Spec.ServiceAccountName != "payments" {
+				t.Errorf("template spec was not carried over: %+v", got.Pod.Spec)
+			}
+		})
+	}
+}
+
+func TestExtractUnsupportedKind(t *testing.T) {
+	if Supported("ConfigMap") {
+		t.Error("ConfigMap reported as supported")
+	}
+	if _, err := FromObject("ConfigMap", []byte(`{}`)); err == nil {
+		t.Error("FromObject accepted a kind without a pod template")
+	}
+}
@@ -0,0 +1,61 @@
+package admission
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/checks"
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/policy"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// TestValidateWorkloadTemplates checks that a workload is denied on apply, with field paths
+// pointing into its pod template, rather than only when its controller creates the pods
+func TestValidateWorkloadTemplates(t *testing.T) {
+	policies, err := policy.Load("../../configs/security-policies.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const podSpec = `{"serviceAccountName":"default","containers":[{"name":"app","image":"myregistry.com/app:1.0"}]}`
+	tests := []struct {
+		kind   string
+		object string
+		field  string
+	}{
+		{"Pod", `{"metadata":{"name":"web"},"spec":` + podSpec + `}`, "spec.serviceAccountName"},
+		{"Deployment", `{"metadata":{"name":"web"},"spec":{"template":{"spec":` + podSpec + `}}}`, "spec.template.spec.serviceAccountName"},
+		{"StatefulSet", `{"metadata":{"name":"web"},"spec":{"template":{"spec":` + podSpec + `}}}`, "spec.template.spec.serviceAccountName"},
+		{"DaemonSet", `{"metadata":{"name":"web"},"spec":{"template":{"spec":` + podSpec + `}}}`, "spec.template.spec.serviceAccountName"},
+		{"Job", `{"metadata":{"name":"web"},"spec":{"template":{"spec":` + podSpec + `}}}`, "spec.template.spec.serviceAccountName"},
+		{"CronJob", `{"metadata":{"name":"web"},"spec":{"jobTemplate":{"spec":{"template":{"spec":` + podSpec + `}}}}}`, "spec.jobTemplate.spec.template.spec.serviceAccountName"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.kind, func(t *testing.T) {
+			request := &admissionv1.AdmissionRequest{
+				Name:      "web",
+				Namespace: "payments",
+				Kind:      metav1.GroupVersionKind{Kind: tt.kind},
+				Object:    runtime.RawExtension{Raw: []byte(tt.object)},
+			}
+
+			response := validate(context.Background(), checks.Default, "api", checks.NewEvaluationWithPolicies(request, policies))
+			if response.Allowed {
+				t.Fatal("workload running as a restricted service account was allowed")
+			}
+			found := false
+			for _, cause := range response.Result.Details.Causes {
+				found = found || cause.Field == tt.field
+			}
+			if !found {
+				t.Errorf("got causes %+v, want one on %s", response.Result.Details.Causes, tt.field)
+			}
+			if response.Result.Details.Kind != tt.kind {
+				t.Errorf("denial reported on kind %q, want %q", response.Result.Details.Kind, tt.kind)
+			}
+		})
+	}
+}
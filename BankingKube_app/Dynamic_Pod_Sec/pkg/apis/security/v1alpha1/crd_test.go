@@ -0,0 +1,80 @@
+package v1alpha1
+
+import (
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/policy"
+
+	"sigs.k8s.io/yaml"
+)
+
+// TestCRDSchemasMatchTypes keeps the OpenAPI schemas in k8s/crds in step with the policy model:
+// a field missing from the schema would be pruned by the API server, and a field missing from
+// the model would be silently ignored by the webhook
+func TestCRDSchemasMatchTypes(t *testing.T) {
+	for _, file := range []string{"clustersecuritypolicies.yaml", "securitypolicies.yaml"} {
+		data, err := os.ReadFile("../../../../k8s/crds/" + file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var crd map[string]interface{}
+		if err := yaml.Unmarshal(data, &crd); err != nil {
+			t.Fatal(err)
+		}
+		versions := crd["spec"].(map[string]interface{})["versions"].([]interface{})
+		schema := versions[0].(map[string]interface{})["schema"].(map[string]interface{})["openAPIV3Schema"].(map[string]interface{})
+		spec := schema["properties"].(map[string]interface{})["spec"].(map[string]interface{})
+
+		compareSchema(t, file+": spec", reflect.TypeOf(policy.SecurityPolicies{}), spec)
+	}
+}
+
+func compareSchema(t *testing.T, path string, typ reflect.Type, schema map[string]interface{}) {
+	t.Helper()
+	switch typ.Kind() {
+	case reflect.Ptr:
+		compareSchema(t, path, typ.Elem(), schema)
+	case reflect.Slice:
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			compareSchema(t, path+"[]", typ.Elem(), items)
+		} else {
+			t.Errorf("%s: schema is not an array", path)
+		}
+	case reflect.Map:
+		if values, ok := schema["additionalProperties"].(map[string]interface{}); ok {
+			compareSchema(t, path+"{}", typ.Elem(), values)
+		} else {
+			t.Errorf("%s: schema is not a map", path)
+		}
+	case reflect.Struct:
+		if typ == reflect.TypeOf(policy.Date{}) {
+			return
+		}
+		properties, _ := schema["properties"].(map[string]interface{})
+		fields := map[string]bool{}
+		for i := 0; i < typ.NumField(); i++ {
+			name := strings.Split(typ.Field(i).Tag.Get("json"), ",")[0]
+			fields[name] = true
+			property, ok := properties[name].(map[string]interface{})
+			if !ok {
+				t.Errorf("%s.%s: missing from the schema", path, name)
+				continue
+			}
+			compareSchema(t, path+"."+name, typ.Field(i).Type, property)
+		}
+		var extra []string
+		for name := range properties {
+			if !fields[name] {
+				extra = append(extra, name)
+			}
+		}
+		sort.Strings(extra)
+		for _, name := range extra {
+			t.Errorf("%s.%s: in the schema but not in the policy model", path, name)
+		}
+	}
+}
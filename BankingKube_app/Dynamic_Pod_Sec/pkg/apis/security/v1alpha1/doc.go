@@ -0,0 +1,6 @@
+// Package v1alpha1 holds the ClusterSecurityPolicy and SecurityPolicy resources of the
+// security.bankingkube.io API group. Their spec mirrors the policies section of security-policies.yaml.
+//
+// +k8s:deepcopy-gen=package
+// +groupName=security.bankingkube.io
+package v1alpha1
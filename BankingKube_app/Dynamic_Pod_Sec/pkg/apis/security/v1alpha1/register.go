@@ -0,0 +1,37 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// GroupName is the API group of the security policy resources
+const GroupName = "security.bankingkube.io"
+
+// SchemeGroupVersion is the group version of this package
+var SchemeGroupVersion = schema.GroupVersion{Group: GroupName, Version: "v1alpha1"}
+
+// Resources served by the API group
+var (
+	ClusterSecurityPolicyResource = SchemeGroupVersion.WithResource("clustersecuritypolicies")
+	SecurityPolicyResource        = SchemeGroupVersion.WithResource("securitypolicies")
+)
+
+var (
+	// SchemeBuilder registers the types of this package with a scheme
+	SchemeBuilder = runtime.NewSchemeBuilder(addKnownTypes)
+	// AddToScheme adds the types of this package to a scheme
+	AddToScheme = SchemeBuilder.AddToScheme
+)
+
+func addKnownTypes(scheme *runtime.Scheme) error {
+	scheme.AddKnownTypes(SchemeGroupVersion,
+		&ClusterSecurityPolicy{},
+		&ClusterSecurityPolicyList{},
+		&SecurityPolicy{},
+		&SecurityPolicyList{},
+	)
+	metav1.AddToGroupVersion(scheme, SchemeGroupVersion)
+	return nil
+}
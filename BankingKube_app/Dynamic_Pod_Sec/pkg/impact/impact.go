@@ -0,0 +1,142 @@
+// Package impact shows what a policy change would do to the objects already running in a cluster.
+// Every object of an export (e.g. kubectl get -A -o yaml) is evaluated against the old and the new
+// policy file the way pkg/scan evaluates manifests, and the outcomes are compared.
+package impact
+
+import (
+	"context"
+	"sort"
+
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/offline"
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/policy"
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/scan"
+)
+
+// Entry is an object listed under a check, with the violation it has
+type Entry struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Kind    string `json:"kind"`
+	Name    string `json:"name"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// Group holds the entries of one namespace and check. Cluster-scoped objects have no namespace.
+type Group struct {
+	Namespace string  `json:"namespace"`
+	Check     string  `json:"check"`
+	Entries   []Entry `json:"entries"`
+}
+
+// Summary counts objects by how the change affects them
+type Summary struct {
+	Objects      int `json:"objects"`
+	NewlyDenied  int `json:"newlyDenied"`
+	NewlyAllowed int `json:"newlyAllowed"`
+	StillDenied  int `json:"stillDenied"`
+}
+
+// Report is the impact of a policy change
+type Report struct {
+	OldPolicy string `json:"oldPolicy,omitempty"`
+	NewPolicy string `json:"newPolicy,omitempty"`
+
+	// NewlyDenied lists objects the old policy admits and the new one denies, under every check that denies them
+	NewlyDenied []*Group `json:"newlyDenied"`
+	// NewlyAllowed lists objects the old policy denies and the new one admits, under every check that denied them
+	NewlyAllowed []*Group `json:"newlyAllowed"`
+	// Unchanged lists the violations both policies report
+	Unchanged []*Group `json:"unchanged"`
+	// Added lists the violations only the new policy reports on objects both policies deny
+	Added []*Group `json:"added"`
+
+	Summary Summary `json:"summary"`
+}
+
+// Analyze evaluates every object against both policies. namespace applies to objects that do not set
+// one; groups selects the check groups to run, all when empty.
+func Analyze(ctx context.Context, objects []*scan.Object, namespace string, oldPolicies, newPolicies *policy.SecurityPolicies, groups []string) (*Report, error) {
+	before, err := scan.Scan(ctx, objects, namespace, offline.Options{Policies: oldPolicies, Groups: groups})
+	if err != nil {
+		return nil, err
+	}
+	after, err := scan.Scan(ctx, objects, namespace, offline.Options{Policies: newPolicies, Groups: groups})
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{Summary: Summary{Objects: len(objects)}}
+	newlyDenied, newlyAllowed, unchanged, added := grouper{}, grouper{}, grouper{}, grouper{}
+	for i := range objects {
+		old, current := before[i], after[i]
+		switch {
+		case old.Allowed && !current.Allowed:
+			report.Summary.NewlyDenied++
+			newlyDenied.add(current, current.Violations)
+		case !old.Allowed && current.Allowed:
+			report.Summary.NewlyAllowed++
+			newlyAllowed.add(old, old.Violations)
+		case !old.Allowed && !current.Allowed:
+			report.Summary.StillDenied++
+			known := make(map[string]bool)
+			for _, v := range old.Violations {
+				known[key(v)] = true
+			}
+			for _, v := range current.Violations {
+				if known[key(v)] {
+					unchanged.add(current, []offline.Finding{v})
+				} else {
+					added.add(current, []offline.Finding{v})
+				}
+			}
+		}
+	}
+	report.NewlyDenied = newlyDenied.sorted()
+	report.NewlyAllowed = newlyAllowed.sorted()
+	report.Unchanged = unchanged.sorted()
+	report.Added = added.sorted()
+	return report, nil
+}
+
+// key identifies a violation across policies; the message is left out because it quotes the policy
+func key(f offline.Finding) string {
+	return f.Check + "/" + f.Reason + "@" + f.Field
+}
+
+// grouper collects entries by namespace and check
+type grouper map[[2]string]*Group
+
+func (g grouper) add(r *scan.Result, findings []offline.Finding) {
+	for _, f := range findings {
+		k := [2]string{r.Namespace, f.Check}
+		group, ok := g[k]
+		if !ok {
+			group = &Group{Namespace: r.Namespace, Check: f.Check}
+			g[k] = group
+		}
+		group.Entries = append(group.Entries, Entry{
+			File:    r.File,
+			Line:    r.Line,
+			Kind:    r.Kind,
+			Name:    r.Name,
+			Field:   f.Field,
+			Message: f.Message,
+		})
+	}
+}
+
+// sorted returns the groups ordered by namespace and check
+func (g grouper) sorted() []*Group {
+	groups := make([]*Group, 0, len(g))
+	for _, group := range g {
+		groups = append(groups, group)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Namespace != groups[j].Namespace {
+			return groups[i].Namespace < groups[j].Namespace
+		}
+		return groups[i].Check < groups[j].Check
+	})
+	return groups
+}
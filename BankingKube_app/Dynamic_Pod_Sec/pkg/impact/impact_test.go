@@ -0,0 +1,102 @@
+package impact
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/policy"
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/scan"
+)
+
+const export = `apiVersion: v1
+kind: List
+items:
+  - apiVersion: v1
+    kind: Pod
+    metadata: {name: api, namespace: payments}
+    spec:
+      containers:
+        - name: app
+          image: nginx
+          securityContext: {allowPrivilegeEscalation: false, readOnlyRootFilesystem: false}
+  - apiVersion: v1
+    kind: Pod
+    metadata: {name: batch, namespace: payments}
+    spec:
+      containers:
+        - name: app
+          image: nginx
+          securityContext: {allowPrivilegeEscalation: true, readOnlyRootFilesystem: false}
+  - apiVersion: v1
+    kind: Pod
+    metadata: {name: web, namespace: shop}
+    spec:
+      containers:
+        - name: app
+          image: nginx
+          securityContext: {allowPrivilegeEscalation: false, readOnlyRootFilesystem: true}
+`
+
+func TestAnalyze(t *testing.T) {
+	objects, err := scan.Read("export.yaml", strings.NewReader(export))
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldPolicies := &policy.SecurityPolicies{}
+	newPolicies := &policy.SecurityPolicies{PodSecurityContext: policy.PodSecurityContext{ReadOnlyRootFilesystem: true}}
+	report, err := Analyze(context.Background(), objects, "", oldPolicies, newPolicies, []string{"context"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := (Summary{Objects: 3, NewlyDenied: 1, NewlyAllowed: 1, StillDenied: 1}); report.Summary != want {
+		t.Errorf("summary = %+v, want %+v", report.Summary, want)
+	}
+	for name, section := range map[string]struct {
+		groups []*Group
+		want   string
+	}{
+		"newly denied":  {report.NewlyDenied, "payments/pod_security_context: api"},
+		"newly allowed": {report.NewlyAllowed, "shop/pod_security_context: web"},
+		"unchanged":     {report.Unchanged, "payments/pod_security_context: batch"},
+		"added":         {report.Added, "payments/pod_security_context: batch"},
+	} {
+		var got []string
+		for _, group := range section.groups {
+			for _, e := range group.Entries {
+				got = append(got, group.Namespace+"/"+group.Check+": "+e.Name)
+			}
+		}
+		if strings.Join(got, ", ") != section.want {
+			t.Errorf("%s = %v, want %s", name, got, section.want)
+		}
+	}
+	if field := report.Added[0].Entries[0].Field; field != "spec.containers[0].securityContext.readOnlyRootFilesystem" {
+		t.Errorf("added violation on %s, want readOnlyRootFilesystem", field)
+	}
+
+	var out bytes.Buffer
+	if err := WriteMarkdown(&out, report); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"| Newly denied | 1 |", "## Newly allowed", "### payments: pod_security_context", "| Pod api | export.yaml:4 |"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Markdown does not contain %q:\n%s", want, out.String())
+		}
+	}
+
+	out.Reset()
+	if err := WriteJSON(&out, report); err != nil {
+		t.Fatal(err)
+	}
+	var decoded Report
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Summary != report.Summary || len(decoded.NewlyDenied) != 1 {
+		t.Errorf("JSON report = %s", out.String())
+	}
+}
@@ -0,0 +1,73 @@
+package impact
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Writers maps the output formats to the functions that write them
+var Writers = map[string]func(io.Writer, *Report) error{
+	"markdown": WriteMarkdown,
+	"json":     WriteJSON,
+}
+
+// WriteJSON writes the report as a single JSON document
+func WriteJSON(w io.Writer, report *Report) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+// WriteMarkdown writes the report as Markdown, e.g. for a pull request comment: a summary table and
+// a table per namespace and check in every section
+func WriteMarkdown(w io.Writer, report *Report) error {
+	var b strings.Builder
+	b.WriteString("# Policy impact\n\n")
+	if report.OldPolicy != "" || report.NewPolicy != "" {
+		fmt.Fprintf(&b, "`%s` → `%s`\n\n", report.OldPolicy, report.NewPolicy)
+	}
+	b.WriteString("| Objects | Count |\n|---|---|\n")
+	fmt.Fprintf(&b, "| Evaluated | %d |\n", report.Summary.Objects)
+	fmt.Fprintf(&b, "| Newly denied | %d |\n", report.Summary.NewlyDenied)
+	fmt.Fprintf(&b, "| Newly allowed | %d |\n", report.Summary.NewlyAllowed)
+	fmt.Fprintf(&b, "| Still denied | %d |\n", report.Summary.StillDenied)
+
+	writeSection(&b, "Newly denied", "Admitted by the old policy, denied by the new one.", report.NewlyDenied)
+	writeSection(&b, "Newly allowed", "Denied by the old policy, admitted by the new one.", report.NewlyAllowed)
+	writeSection(&b, "Unchanged violations", "Reported by both policies.", report.Unchanged)
+	writeSection(&b, "Added violations", "Reported only by the new policy, on objects both policies deny.", report.Added)
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeSection(b *strings.Builder, title, description string, groups []*Group) {
+	fmt.Fprintf(b, "\n## %s\n\n%s\n", title, description)
+	if len(groups) == 0 {
+		b.WriteString("\nNone.\n")
+		return
+	}
+	for _, group := range groups {
+		namespace := group.Namespace
+		if namespace == "" {
+			namespace = "(cluster)"
+		}
+		check := group.Check
+		if check == "" {
+			check = "(webhook)"
+		}
+		fmt.Fprintf(b, "\n### %s: %s\n\n", namespace, check)
+		b.WriteString("| Object | Source | Field | Message |\n|---|---|---|---|\n")
+		for _, e := range group.Entries {
+			fmt.Fprintf(b, "| %s %s | %s:%d | %s | %s |\n", e.Kind, cell(e.Name), cell(e.File), e.Line, cell(e.Field), cell(e.Message))
+		}
+	}
+}
+
+// cell escapes a value for a Markdown table cell
+func cell(value string) string {
+	value = strings.ReplaceAll(value, "|", `\|`)
+	return strings.ReplaceAll(value, "\n", " ")
+}
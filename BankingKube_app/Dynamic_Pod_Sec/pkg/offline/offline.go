@@ -0,0 +1,184 @@
+// Package offline evaluates admission requests against a policy file without an API server. Requests
+// go through the webhook handlers exactly as the API server would send them, one validation route per
+// check group, so enforcement modes, exemptions and error policies apply as they do in the cluster.
+package offline
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission"
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/checks"
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/policy"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Options select what a request is evaluated against
+type Options struct {
+	Policies        *policy.SecurityPolicies
+	Groups          []string          // Check groups to run; all registered groups when empty
+	NamespaceLabels map[string]string // Labels of the request's namespace, read by custom rules
+}
+
+// Finding is a violation the webhook reported, either denying the request or as a warning
+type Finding struct {
+	Check   string `json:"check"`
+	Reason  string `json:"reason,omitempty"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// Outcome is the combined answer of every group. The request is allowed only when every group allows it.
+type Outcome struct {
+	Allowed    bool      `json:"allowed"`
+	Violations []Finding `json:"violations,omitempty"`
+	Warnings   []Finding `json:"warnings,omitempty"`
+	Messages   []string  `json:"-"` // Status messages of the groups that denied the request
+}
+
+// Review sends the request to the validation route of every selected group and combines the answers
+func Review(ctx context.Context, request *admissionv1.AdmissionRequest, options Options) (*Outcome, error) {
+	groups := options.Groups
+	if len(groups) == 0 {
+		groups = checks.Groups()
+	}
+	handler := admission.Handler(
+		func(string) (*policy.SecurityPolicies, error) { return options.Policies, nil },
+		func(string) (map[string]string, error) { return options.NamespaceLabels, nil },
+	)
+	review := &admissionv1.AdmissionReview{
+		TypeMeta: metav1.TypeMeta{APIVersion: "admission.k8s.io/v1", Kind: "AdmissionReview"},
+		Request:  request,
+	}
+
+	outcome := &Outcome{Allowed: true}
+	for _, group := range groups {
+		if !checks.Default.HasGroup(group) {
+			return nil, fmt.Errorf("unknown check group %q", group)
+		}
+		response, err := send(ctx, handler, group, review)
+		if err != nil {
+			return nil, fmt.Errorf("group %s: %w", group, err)
+		}
+		for _, warning := range response.Warnings {
+			outcome.Warnings = append(outcome.Warnings, warningFinding(warning))
+		}
+		if response.Allowed {
+			continue
+		}
+		outcome.Allowed = false
+		if response.Result == nil {
+			continue
+		}
+		outcome.Messages = append(outcome.Messages, response.Result.Message)
+		if response.Result.Details != nil {
+			for _, cause := range response.Result.Details.Causes {
+				check, reason, _ := strings.Cut(string(cause.Type), "/")
+				outcome.Violations = append(outcome.Violations, Finding{Check: check, Reason: reason, Field: cause.Field, Message: cause.Message})
+			}
+		}
+	}
+	return outcome, nil
+}
+
+// send posts the review to the validation route of group and returns the response
+func send(ctx context.Context, handler http.Handler, group string, review *admissionv1.AdmissionReview) (*admissionv1.AdmissionResponse, error) {
+	body, err := json.Marshal(review)
+	if err != nil {
+		return nil, err
+	}
+	request := httptest.NewRequest(http.MethodPost, admission.ValidatePathPrefix+group, bytes.NewReader(body)).WithContext(ctx)
+	request.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusOK {
+		return nil, fmt.Errorf("webhook answered %d: %s", recorder.Code, strings.TrimSpace(recorder.Body.String()))
+	}
+
+	var answer admissionv1.AdmissionReview
+	if err := json.Unmarshal(recorder.Body.Bytes(), &answer); err != nil {
+		return nil, err
+	}
+	if answer.Response == nil {
+		return nil, errors.New("webhook answered without a response")
+	}
+	return answer.Response, nil
+}
+
+// warningFinding splits a warning into the check it comes from and its message; warnings start with [check]
+func warningFinding(warning string) Finding {
+	if strings.HasPrefix(warning, "[") {
+		if end := strings.Index(warning, "]"); end > 0 {
+			return Finding{Check: warning[1:end], Message: strings.TrimSpace(warning[end+1:])}
+		}
+	}
+	return Finding{Message: warning}
+}
+
+// ManifestRequest wraps a manifest, encoded as JSON, into the AdmissionRequest the API server would
+// send for it. namespace applies when the manifest has none and defaults to "default", except for
+// cluster-scoped kinds; operation defaults to CREATE.
+func ManifestRequest(object []byte, namespace string, operation admissionv1.Operation) (*admissionv1.AdmissionRequest, error) {
+	var meta struct {
+		metav1.TypeMeta `json:",inline"`
+		Metadata        metav1.ObjectMeta `json:"metadata"`
+	}
+	if err := json.Unmarshal(object, &meta); err != nil {
+		return nil, err
+	}
+	gvk := meta.GroupVersionKind()
+	if gvk.Kind == "" {
+		return nil, errors.New("manifest has no kind")
+	}
+	if meta.Metadata.Namespace != "" {
+		namespace = meta.Metadata.Namespace
+	}
+	if clusterScoped[gvk.Kind] {
+		namespace = ""
+	} else if namespace == "" {
+		namespace = "default"
+	}
+	if operation == "" {
+		operation = admissionv1.Create
+	}
+	return &admissionv1.AdmissionRequest{
+		UID:       types.UID("offline"),
+		Kind:      metav1.GroupVersionKind{Group: gvk.Group, Version: gvk.Version, Kind: gvk.Kind},
+		Resource:  metav1.GroupVersionResource{Group: gvk.Group, Version: gvk.Version, Resource: resourceName(gvk.Kind)},
+		Name:      meta.Metadata.Name,
+		Namespace: namespace,
+		Operation: operation,
+		Object:    runtime.RawExtension{Raw: object},
+	}, nil
+}
+
+// clusterScoped lists the cluster-scoped kinds the checks look at
+var clusterScoped = map[string]bool{
+	"ClusterRole":        true,
+	"ClusterRoleBinding": true,
+	"Namespace":          true,
+	"Node":               true,
+	"PersistentVolume":   true,
+}
+
+// resourceName returns the resource of a kind, e.g. deployments for Deployment
+func resourceName(kind string) string {
+	resource := strings.ToLower(kind)
+	switch {
+	case strings.HasSuffix(resource, "y"):
+		return strings.TrimSuffix(resource, "y") + "ies"
+	case strings.HasSuffix(resource, "s"):
+		return resource + "es"
+	}
+	return resource + "s"
+}
@@ -0,0 +1,60 @@
+package policy
+
+import (
+	"slices"
+	"strings"
+)
+
+// CapabilityAll stands for every capability in the add and drop lists of a container
+const CapabilityAll = "ALL"
+
+// CapabilityName returns the Kubernetes spelling of a capability: upper case without the CAP_ prefix the
+// container runtimes also accept, so SYS_ADMIN, CAP_SYS_ADMIN and cap_sys_admin compare equal
+func CapabilityName(capability string) string {
+	return strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(capability)), "CAP_")
+}
+
+// CapabilityNames returns the Kubernetes spelling of every capability
+func CapabilityNames(capabilities []string) []string {
+	names := make([]string, len(capabilities))
+	for i, capability := range capabilities {
+		names[i] = CapabilityName(capability)
+	}
+	return names
+}
+
+// HasCapability reports whether list names capability in either spelling. ALL in the list names every capability.
+func HasCapability(list []string, capability string) bool {
+	name := CapabilityName(capability)
+	for _, entry := range list {
+		if entry := CapabilityName(entry); entry == name || entry == CapabilityAll {
+			return true
+		}
+	}
+	return false
+}
+
+// narrowerCapability compares capabilities in either spelling
+func narrowerCapability(a, b string) string {
+	if CapabilityName(a) == CapabilityName(b) {
+		return a
+	}
+	return ""
+}
+
+// tightenAllowedCapabilities merges the allowlists of two capability policies. A list only restricts anything
+// under enforceAllowedCapabilities, where an empty list allows no capability: two enforcing lists intersect,
+// and otherwise the list of the enforcing side applies as it is.
+func tightenAllowedCapabilities(base, overlay Capabilities) []string {
+	switch {
+	case base.EnforceAllowedCapabilities && overlay.EnforceAllowedCapabilities:
+		if len(base.AllowedCapabilities) == 0 || len(overlay.AllowedCapabilities) == 0 {
+			return []string{}
+		}
+		return intersect(base.AllowedCapabilities, overlay.AllowedCapabilities, narrowerCapability)
+	case overlay.EnforceAllowedCapabilities:
+		return slices.Clone(overlay.AllowedCapabilities)
+	default:
+		return slices.Clone(base.AllowedCapabilities)
+	}
+}
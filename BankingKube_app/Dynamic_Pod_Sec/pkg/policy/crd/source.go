@@ -0,0 +1,187 @@
+// Package crd serves the policies of ClusterSecurityPolicy and SecurityPolicy resources.
+// The resources are watched through informers; each change rebuilds an immutable view that
+// requests read without locking.
+package crd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/apis/security/v1alpha1"
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/policy"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+)
+
+// FileLoader returns the policies of the policy file
+type FileLoader func() (*policy.SecurityPolicies, error)
+
+// Source merges the policy file with the security policy resources of the cluster:
+//
+//  1. The policy file is the baseline.
+//  2. Every ClusterSecurityPolicy tightens the baseline for all namespaces.
+//  3. Every SecurityPolicy tightens it further for the requests of its own namespace.
+//
+// Tightening never loosens (see policy.Tighten), so the order in which resources apply does not matter,
+// and no resource can relax the policy file. A resource that fails validation is ignored and logged.
+type Source struct {
+	file      FileLoader
+	factory   dynamicinformer.DynamicSharedInformerFactory
+	informers []cache.SharedIndexInformer
+	view      atomic.Pointer[view]
+	mu        sync.Mutex // Serialises rebuilds
+}
+
+// view is the set of valid resources at one point in time
+type view struct {
+	cluster    []*v1alpha1.ClusterSecurityPolicy
+	namespaced map[string][]*v1alpha1.SecurityPolicy
+	merged     sync.Map // namespace -> *mergedPolicies
+}
+
+// mergedPolicies caches the policies of a namespace for the baseline they were merged from
+type mergedPolicies struct {
+	baseline *policy.SecurityPolicies
+	policies *policy.SecurityPolicies
+}
+
+// NewSource creates a source that watches the security policy resources through client
+func NewSource(client dynamic.Interface, file FileLoader, resync time.Duration) *Source {
+	s := &Source{
+		file:    file,
+		factory: dynamicinformer.NewDynamicSharedInformerFactory(client, resync),
+	}
+	s.view.Store(&view{namespaced: map[string][]*v1alpha1.SecurityPolicy{}})
+
+	handler := cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(interface{}) { s.rebuild() },
+		UpdateFunc: func(interface{}, interface{}) { s.rebuild() },
+		DeleteFunc: func(interface{}) { s.rebuild() },
+	}
+	cluster := s.factory.ForResource(v1alpha1.ClusterSecurityPolicyResource).Informer()
+	namespaced := s.factory.ForResource(v1alpha1.SecurityPolicyResource).Informer()
+	for _, informer := range []cache.SharedIndexInformer{cluster, namespaced} {
+		if _, err := informer.AddEventHandler(handler); err != nil {
+			log.Println("Failed to watch security policies:", err)
+		}
+		s.informers = append(s.informers, informer)
+	}
+	return s
+}
+
+// Start runs the informers until ctx is done and waits up to syncTimeout for their first list,
+// which fails when the CustomResourceDefinitions are not installed
+func (s *Source) Start(ctx context.Context, syncTimeout time.Duration) error {
+	s.factory.Start(ctx.Done())
+
+	syncCtx, cancel := context.WithTimeout(ctx, syncTimeout)
+	defer cancel()
+	for resource, synced := range s.factory.WaitForCacheSync(syncCtx.Done()) {
+		if !synced {
+			return fmt.Errorf("security policies: %s did not sync", resource.Resource)
+		}
+	}
+	s.rebuild()
+	return nil
+}
+
+// Policies returns the policies that apply to requests in namespace; "" is for cluster-scoped objects
+func (s *Source) Policies(namespace string) (*policy.SecurityPolicies, error) {
+	v := s.view.Load()
+	baseline, err := s.file()
+	if err != nil {
+		return nil, err
+	}
+	if len(v.cluster) == 0 && len(v.namespaced[namespace]) == 0 {
+		return baseline, nil
+	}
+
+	if cached, ok := v.merged.Load(namespace); ok && cached.(*mergedPolicies).baseline == baseline {
+		return cached.(*mergedPolicies).policies, nil
+	}
+	merged := baseline
+	for _, p := range v.cluster {
+		merged = policy.Tighten(merged, &p.Spec)
+	}
+	for _, p := range v.namespaced[namespace] {
+		merged = policy.Tighten(merged, &p.Spec)
+	}
+	v.merged.Store(namespace, &mergedPolicies{baseline: baseline, policies: merged})
+	return merged, nil
+}
+
+// rebuild replaces the view with the valid resources currently in the informer caches
+func (s *Source) rebuild() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	next := &view{namespaced: map[string][]*v1alpha1.SecurityPolicy{}}
+	for _, obj := range s.informers[0].GetStore().List() {
+		p := &v1alpha1.ClusterSecurityPolicy{}
+		if !decode(obj, p, &p.Spec) {
+			continue
+		}
+		next.cluster = append(next.cluster, p)
+	}
+	for _, obj := range s.informers[1].GetStore().List() {
+		p := &v1alpha1.SecurityPolicy{}
+		if !decode(obj, p, &p.Spec) {
+			continue
+		}
+		next.namespaced[p.Namespace] = append(next.namespaced[p.Namespace], p)
+	}
+
+	// Merge in a stable order so repeated rebuilds produce the same policies
+	sort.Slice(next.cluster, func(i, j int) bool { return next.cluster[i].Name < next.cluster[j].Name })
+	for _, list := range next.namespaced {
+		sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	}
+	s.view.Store(next)
+}
+
+// decode converts an informer object into its typed form and validates its spec
+func decode(obj interface{}, into runtime.Object, spec *policy.SecurityPolicies) bool {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return false
+	}
+	name := u.GetKind() + " " + u.GetNamespace() + "/" + u.GetName()
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, into); err != nil {
+		log.Printf("Ignoring %s: %v\n", name, err)
+		return false
+	}
+	if err := spec.Validate(); err != nil {
+		log.Printf("Ignoring invalid %s: %v\n", name, err)
+		return false
+	}
+	if sections := baselineOnlySections(spec); len(sections) > 0 {
+		log.Printf("%s sets %s, which only the policy file can set; the sections are ignored\n", name, strings.Join(sections, ", "))
+	}
+	return true
+}
+
+// baselineOnlySections returns the sections of spec that policy.Tighten only takes from the policy file
+func baselineOnlySections(spec *policy.SecurityPolicies) []string {
+	var sections []string
+	if !reflect.ValueOf(spec.Execution).IsZero() {
+		sections = append(sections, "execution")
+	}
+	if len(spec.Exemptions) > 0 {
+		sections = append(sections, "exemptions")
+	}
+	if !reflect.ValueOf(spec.NetworkSecurity.ConsistencyPolicy).IsZero() {
+		sections = append(sections, "NetworkSecurity.consistencyPolicy")
+	}
+	return sections
+}
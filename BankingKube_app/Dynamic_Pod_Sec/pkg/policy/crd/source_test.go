@@ -0,0 +1,189 @@
+package crd
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/apis/security/v1alpha1"
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/policy"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/fake"
+)
+
+func newFakeClient(t *testing.T, objects ...runtime.Object) *fake.FakeDynamicClient {
+	t.Helper()
+	// The tracker keeps the objects unstructured, as the API server would serve them, when
+	// the scheme does not know the typed resources
+	scheme := runtime.NewScheme()
+	unstructuredObjects := make([]runtime.Object, 0, len(objects))
+	for _, obj := range objects {
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+		if err != nil {
+			t.Fatal(err)
+		}
+		unstructuredObjects = append(unstructuredObjects, &unstructured.Unstructured{Object: content})
+	}
+	return fake.NewSimpleDynamicClientWithCustomListKinds(scheme, map[schema.GroupVersionResource]string{
+		v1alpha1.ClusterSecurityPolicyResource: "ClusterSecurityPolicyList",
+		v1alpha1.SecurityPolicyResource:        "SecurityPolicyList",
+	}, unstructuredObjects...)
+}
+
+func clusterPolicy(name string, spec policy.SecurityPolicies) *v1alpha1.ClusterSecurityPolicy {
+	return &v1alpha1.ClusterSecurityPolicy{
+		TypeMeta:   metav1.TypeMeta{APIVersion: v1alpha1.SchemeGroupVersion.String(), Kind: "ClusterSecurityPolicy"},
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec:       spec,
+	}
+}
+
+func namespacedPolicy(namespace, name string, spec policy.SecurityPolicies) *v1alpha1.SecurityPolicy {
+	return &v1alpha1.SecurityPolicy{
+		TypeMeta:   metav1.TypeMeta{APIVersion: v1alpha1.SchemeGroupVersion.String(), Kind: "SecurityPolicy"},
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Spec:       spec,
+	}
+}
+
+func TestSourceMergesResources(t *testing.T) {
+	file := &policy.SecurityPolicies{
+		ImageSecurity:  policy.ImageSecurity{AllowedRegistries: []string{"registry.example.com"}, DisallowedTags: []string{"latest"}},
+		ResourceLimits: policy.ResourceLimits{CPULimits: policy.Range{Min: "100m", Max: "2000m"}},
+	}
+	client := newFakeClient(t,
+		clusterPolicy("require-signing", policy.SecurityPolicies{ImageSecurity: policy.ImageSecurity{RequireImageSigning: true}}),
+		namespacedPolicy("payments", "strict", policy.SecurityPolicies{
+			Enforcement:    policy.Enforcement{DefaultMode: policy.ModeWarn, Checks: map[string]policy.Mode{"image_tags": policy.ModeEnforce}},
+			ImageSecurity:  policy.ImageSecurity{AllowedRegistries: []string{"registry.example.com/payments", "docker.io"}, DisallowedTags: []string{"dev"}},
+			ResourceLimits: policy.ResourceLimits{CPULimits: policy.Range{Min: "50m", Max: "1000m"}},
+			NetworkSecurity: policy.NetworkSecurity{
+				HostNetworkPolicy: policy.HostNetworkPolicy{AllowHostNetwork: true},
+			},
+		}),
+	)
+
+	source := NewSource(client, func() (*policy.SecurityPolicies, error) { return file, nil }, 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := source.Start(ctx, 5*time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	other, err := source.Policies("default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !other.ImageSecurity.RequireImageSigning {
+		t.Error("cluster policy did not apply to every namespace")
+	}
+	if !reflect.DeepEqual(other.ImageSecurity.DisallowedTags, []string{"latest"}) {
+		t.Errorf("namespaced policy leaked into another namespace: disallowedTags = %v", other.ImageSecurity.DisallowedTags)
+	}
+
+	payments, err := source.Policies("payments")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		got  interface{}
+		want interface{}
+	}{
+		{"allowedRegistries only narrows", payments.ImageSecurity.AllowedRegistries, []string{"registry.example.com/payments"}},
+		{"disallowedTags are joined", payments.ImageSecurity.DisallowedTags, []string{"dev", "latest"}},
+		{"requireImageSigning is kept", payments.ImageSecurity.RequireImageSigning, true},
+		{"min only rises", payments.ResourceLimits.CPULimits.Min, "100m"},
+		{"max only falls", payments.ResourceLimits.CPULimits.Max, "1000m"},
+		{"hostNetwork cannot be allowed", payments.NetworkSecurity.HostNetworkPolicy.AllowHostNetwork, false},
+		{"default mode cannot relax", payments.Enforcement.ModeFor("capabilities"), policy.ModeEnforce},
+	}
+	for _, tt := range tests {
+		if !reflect.DeepEqual(tt.got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+
+	if again, _ := source.Policies("payments"); again != payments {
+		t.Error("merged policies were not cached")
+	}
+}
+
+func TestSourceDefaultClusterPolicyTightensFile(t *testing.T) {
+	file := &policy.SecurityPolicies{ImageSecurity: policy.ImageSecurity{DisallowedTags: []string{"latest"}}}
+	client := newFakeClient(t)
+	source := NewSource(client, func() (*policy.SecurityPolicies, error) { return file, nil }, 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := source.Start(ctx, 5*time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, _ := source.Policies("payments"); got != file {
+		t.Fatal("without resources the policy file did not apply")
+	}
+
+	// A ClusterSecurityPolicy named default is no different from any other: it cannot replace the file
+	baseline := clusterPolicy("default", policy.SecurityPolicies{
+		ImageSecurity:   policy.ImageSecurity{DisallowedTags: []string{"edge"}},
+		NetworkSecurity: policy.NetworkSecurity{HostNetworkPolicy: policy.HostNetworkPolicy{AllowHostNetwork: true}},
+	})
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(baseline)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Resource(v1alpha1.ClusterSecurityPolicyResource).Create(ctx, &unstructured.Unstructured{Object: content}, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		got, _ := source.Policies("payments")
+		if reflect.DeepEqual(got.ImageSecurity.DisallowedTags, []string{"edge", "latest"}) {
+			if got.NetworkSecurity.HostNetworkPolicy.AllowHostNetwork {
+				t.Error("the default ClusterSecurityPolicy allowed the host network the policy file denies")
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("disallowedTags = %v, want the file's and the default ClusterSecurityPolicy's", got.ImageSecurity.DisallowedTags)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestBaselineOnlySections(t *testing.T) {
+	spec := &policy.SecurityPolicies{
+		Execution:  policy.Execution{DefaultFailurePolicy: policy.FailOpen},
+		Exemptions: []policy.Exemption{{Name: "aws-node"}},
+	}
+	if got := baselineOnlySections(spec); !reflect.DeepEqual(got, []string{"execution", "exemptions"}) {
+		t.Errorf("baselineOnlySections = %v, want execution and exemptions", got)
+	}
+	if got := baselineOnlySections(&policy.SecurityPolicies{ImageSecurity: policy.ImageSecurity{RequireImageSigning: true}}); got != nil {
+		t.Errorf("baselineOnlySections = %v for a spec that only tightens", got)
+	}
+}
+
+func TestSourceIgnoresInvalidResources(t *testing.T) {
+	file := &policy.SecurityPolicies{}
+	client := newFakeClient(t,
+		namespacedPolicy("payments", "broken", policy.SecurityPolicies{
+			ResourceLimits: policy.ResourceLimits{CPULimits: policy.Range{Min: "2", Max: "1"}},
+		}),
+	)
+	source := NewSource(client, func() (*policy.SecurityPolicies, error) { return file, nil }, 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := source.Start(ctx, 5*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := source.Policies("payments"); got != file {
+		t.Errorf("invalid SecurityPolicy was merged: %+v", got.ResourceLimits)
+	}
+}
@@ -0,0 +1,6 @@
+// Package policy holds the typed model of security-policies.yaml and loads it.
+// The admission webhook keeps the parsed file in a Store, reloads it when the file changes,
+// and shares one snapshot across every check of a request.
+//
+// +k8s:deepcopy-gen=package
+package policy
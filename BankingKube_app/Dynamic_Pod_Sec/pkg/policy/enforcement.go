@@ -0,0 +1,80 @@
+package policy
+
+import (
+	"fmt"
+)
+
+// Mode controls what the webhook does with the violations reported by a check
+type Mode string
+
+const (
+	// ModeEnforce denies the request (default)
+	ModeEnforce Mode = "enforce"
+	// ModeWarn allows the request and returns the violations as AdmissionResponse.Warnings
+	ModeWarn Mode = "warn"
+	// ModeAudit allows the request and records the violations in the audit log and webhook log
+	ModeAudit Mode = "audit"
+	// ModeDryRun evaluates the check but only emits metrics
+	ModeDryRun Mode = "dryrun"
+)
+
+// ParseMode validates a mode read from the policy file. An empty string means enforce.
+func ParseMode(s string) (Mode, error) {
+	switch Mode(s) {
+	case "":
+		return ModeEnforce, nil
+	case ModeEnforce, ModeWarn, ModeAudit, ModeDryRun:
+		return Mode(s), nil
+	}
+	return "", fmt.Errorf("unknown enforcement mode %q (expected enforce, warn, audit or dryrun)", s)
+}
+
+// Enforcement maps check names to the mode their violations are handled in
+type Enforcement struct {
+	DefaultMode Mode            `yaml:"defaultMode" json:"defaultMode,omitempty"` // Mode of checks without an entry in Checks
+	Checks      map[string]Mode `yaml:"checks" json:"checks,omitempty"`           // Per check overrides, keyed by check name (e.g. image_tags)
+}
+
+// ModeFor returns the mode of the named check
+func (e *Enforcement) ModeFor(name string) Mode {
+	if e == nil {
+		return ModeEnforce
+	}
+	if mode, ok := e.Checks[name]; ok && mode != "" {
+		return mode
+	}
+	if e.DefaultMode != "" {
+		return e.DefaultMode
+	}
+	return ModeEnforce
+}
+
+// ModeForDeclared returns the mode of the named check when the check declares a mode of its own
+// (e.g. a custom rule): an entry in Checks wins, then the declared mode, then DefaultMode
+func (e *Enforcement) ModeForDeclared(name string, declared Mode) Mode {
+	if e != nil {
+		if mode, ok := e.Checks[name]; ok && mode != "" {
+			return mode
+		}
+	}
+	if declared != "" {
+		return declared
+	}
+	return e.ModeFor(name)
+}
+
+// Validate reports the first unknown mode in the configuration
+func (e *Enforcement) Validate() error {
+	if e == nil {
+		return nil
+	}
+	if _, err := ParseMode(string(e.DefaultMode)); err != nil {
+		return fmt.Errorf("enforcement.defaultMode: %w", err)
+	}
+	for name, mode := range e.Checks {
+		if _, err := ParseMode(string(mode)); err != nil {
+			return fmt.Errorf("enforcement.checks.%s: %w", name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// FailurePolicy decides the outcome of a check that did not finish within its time budget
+type FailurePolicy string
+
+const (
+	// FailClosed treats an unfinished check as a violation (default)
+	FailClosed FailurePolicy = "closed"
+	// FailOpen allows the request and reports the unfinished check as a warning
+	FailOpen FailurePolicy = "open"
+)
+
+// DefaultCheckTimeout is the time budget of a check without a configured timeout
+const DefaultCheckTimeout = 2 * time.Second
+
+// Duration is a time.Duration written as a Go duration string (e.g. "1500ms") in the policy file
+type Duration time.Duration
+
+// UnmarshalYAML parses a Go duration string
+func (d *Duration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	return d.parse(s)
+}
+
+// UnmarshalJSON parses a Go duration string, as written in SecurityPolicy resources
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	return d.parse(s)
+}
+
+// MarshalJSON writes the duration as a Go duration string
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) parse(s string) error {
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	if parsed < 0 {
+		return fmt.Errorf("duration %q must not be negative", s)
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// Execution controls how long each check may run, what happens when it runs out of time
+// and what happens when it fails with an internal error
+type Execution struct {
+	DefaultTimeout       Duration                  `yaml:"defaultTimeout" json:"defaultTimeout,omitempty"`             // Budget of checks without an entry in Checks
+	DefaultFailurePolicy FailurePolicy             `yaml:"defaultFailurePolicy" json:"defaultFailurePolicy,omitempty"` // Failure policy of checks without an entry in Checks
+	DefaultErrorPolicy   ErrorPolicy               `yaml:"defaultErrorPolicy" json:"defaultErrorPolicy,omitempty"`     // Error policy of checks that defer to the global default
+	Checks               map[string]CheckExecution `yaml:"checks" json:"checks,omitempty"`                             // Per check overrides, keyed by check name
+}
+
+// CheckExecution overrides the execution settings of a single check
+type CheckExecution struct {
+	Timeout       Duration      `yaml:"timeout" json:"timeout,omitempty"`
+	FailurePolicy FailurePolicy `yaml:"failurePolicy" json:"failurePolicy,omitempty"`
+	ErrorPolicy   ErrorPolicy   `yaml:"errorPolicy" json:"errorPolicy,omitempty"` // Overrides the error policy the check declares
+}
+
+// TimeoutFor returns the time budget of the named check
+func (e *Execution) TimeoutFor(name string) time.Duration {
+	if e == nil {
+		return DefaultCheckTimeout
+	}
+	if c, ok := e.Checks[name]; ok && c.Timeout > 0 {
+		return time.Duration(c.Timeout)
+	}
+	if e.DefaultTimeout > 0 {
+		return time.Duration(e.DefaultTimeout)
+	}
+	return DefaultCheckTimeout
+}
+
+// FailurePolicyFor returns the failure policy of the named check
+func (e *Execution) FailurePolicyFor(name string) FailurePolicy {
+	if e == nil {
+		return FailClosed
+	}
+	if c, ok := e.Checks[name]; ok && c.FailurePolicy != "" {
+		return c.FailurePolicy
+	}
+	if e.DefaultFailurePolicy != "" {
+		return e.DefaultFailurePolicy
+	}
+	return FailClosed
+}
+
+// ErrorPolicyFor returns the error policy of the named check. declared is the policy the check
+// declares itself, which the policy file can override. A check that defers to the global default
+// gets defaultErrorPolicy, then DEFAULT_ERROR_POLICY from the environment, then deny: the environment
+// keeps working when the policy file itself cannot be read.
+func (e *Execution) ErrorPolicyFor(name string, declared ErrorPolicy) ErrorPolicy {
+	if e != nil {
+		if c, ok := e.Checks[name]; ok && c.ErrorPolicy != ErrorPolicyDefault && c.ErrorPolicy != "default" {
+			return c.ErrorPolicy
+		}
+	}
+	if declared != ErrorPolicyDefault {
+		return declared
+	}
+	if e != nil && e.DefaultErrorPolicy != ErrorPolicyDefault && e.DefaultErrorPolicy != "default" {
+		return e.DefaultErrorPolicy
+	}
+	if p, err := ParseErrorPolicy(os.Getenv("DEFAULT_ERROR_POLICY")); err == nil && p != ErrorPolicyDefault {
+		return p
+	}
+	return ErrorPolicyDeny
+}
+
+// Validate reports the first unknown failure or error policy in the configuration
+func (e *Execution) Validate() error {
+	if e == nil {
+		return nil
+	}
+	if err := validateFailurePolicy(e.DefaultFailurePolicy); err != nil {
+		return fmt.Errorf("execution.defaultFailurePolicy: %w", err)
+	}
+	if _, err := ParseErrorPolicy(string(e.DefaultErrorPolicy)); err != nil {
+		return fmt.Errorf("execution.defaultErrorPolicy: %w", err)
+	}
+	for name, c := range e.Checks {
+		if err := validateFailurePolicy(c.FailurePolicy); err != nil {
+			return fmt.Errorf("execution.checks.%s.failurePolicy: %w", name, err)
+		}
+		if _, err := ParseErrorPolicy(string(c.ErrorPolicy)); err != nil {
+			return fmt.Errorf("execution.checks.%s.errorPolicy: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func validateFailurePolicy(p FailurePolicy) error {
+	switch p {
+	case "", FailClosed, FailOpen:
+		return nil
+	}
+	return fmt.Errorf("unknown failure policy %q (expected open or closed)", p)
+}
+
+// ErrorPolicy decides the outcome of a check that fails with an internal error, such as an
+// unreadable policy file or an object that cannot be decoded, as opposed to finding a violation
+type ErrorPolicy string
+
+const (
+	// ErrorPolicyDefault defers to the global default error policy
+	ErrorPolicyDefault ErrorPolicy = ""
+	// ErrorPolicyDeny treats the error as a violation of the check
+	ErrorPolicyDeny ErrorPolicy = "deny"
+	// ErrorPolicyWarn allows the request and returns the error as a warning
+	ErrorPolicyWarn ErrorPolicy = "warn"
+)
+
+// ParseErrorPolicy validates an error policy. An empty string and "default" defer to the global default.
+func ParseErrorPolicy(s string) (ErrorPolicy, error) {
+	switch ErrorPolicy(s) {
+	case ErrorPolicyDefault, "default":
+		return ErrorPolicyDefault, nil
+	case ErrorPolicyDeny, ErrorPolicyWarn:
+		return ErrorPolicy(s), nil
+	}
+	return "", fmt.Errorf("unknown error policy %q (expected deny, warn or default)", s)
+}
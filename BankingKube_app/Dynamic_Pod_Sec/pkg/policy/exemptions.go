@@ -0,0 +1,127 @@
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Exemption lifts the listed checks for the requests it matches, e.g. hostPath and hostNetwork
+// for a CNI DaemonSet. Every selector that is set must match; an exemption needs at least one.
+// Justification, owner and expiry are mandatory, and an expired exemption no longer applies.
+type Exemption struct {
+	Name   string   `yaml:"name" json:"name,omitempty"`
+	Checks []string `yaml:"checks" json:"checks,omitempty"` // Check IDs the exemption lifts
+
+	Namespaces      []string          `yaml:"namespaces" json:"namespaces,omitempty"`
+	Labels          map[string]string `yaml:"labels" json:"labels,omitempty"`                   // Pod labels, all of which must match
+	ServiceAccounts []string          `yaml:"serviceAccounts" json:"serviceAccounts,omitempty"` // Service account of the pod
+	Users           []string          `yaml:"users" json:"users,omitempty"`                     // request.userInfo.username
+	Groups          []string          `yaml:"groups" json:"groups,omitempty"`                   // Any of request.userInfo.groups
+	Images          []string          `yaml:"images" json:"images,omitempty"`                   // Image of the offending container; a trailing * matches any suffix
+
+	Justification string `yaml:"justification" json:"justification,omitempty"`
+	Owner         string `yaml:"owner" json:"owner,omitempty"`
+	Expires       Date   `yaml:"expires" json:"expires,omitempty"`
+}
+
+// Date is a calendar day written as YYYY-MM-DD in the policy file
+// +k8s:deepcopy-gen=false
+type Date struct {
+	time.Time
+}
+
+// UnmarshalYAML parses a YYYY-MM-DD date
+func (d *Date) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	return d.parse(s)
+}
+
+// UnmarshalJSON parses a YYYY-MM-DD date, as written in SecurityPolicy resources
+func (d *Date) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	return d.parse(s)
+}
+
+// MarshalJSON writes the date as YYYY-MM-DD
+func (d Date) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + d.Format(time.DateOnly) + `"`), nil
+}
+
+// DeepCopyInto copies the date; time.Time is a value type
+func (d *Date) DeepCopyInto(out *Date) {
+	*out = *d
+}
+
+func (d *Date) parse(s string) error {
+	parsed, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		return fmt.Errorf("date %q must be written as YYYY-MM-DD", s)
+	}
+	d.Time = parsed
+	return nil
+}
+
+// Expired reports whether the exemption has expired at now. It applies until the end of its expiry day (UTC).
+func (e *Exemption) Expired(now time.Time) bool {
+	return !now.Before(e.Expires.AddDate(0, 0, 1))
+}
+
+// Covers reports whether the exemption lifts the named check
+func (e *Exemption) Covers(check string) bool {
+	for _, c := range e.Checks {
+		if c == check {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateExemptions reports the first exemption without its mandatory fields or without a selector
+func ValidateExemptions(exemptions []Exemption) error {
+	names := make(map[string]bool, len(exemptions))
+	for i, e := range exemptions {
+		field := fmt.Sprintf("exemptions[%d]", i)
+		switch {
+		case e.Name == "":
+			return fmt.Errorf("%s.name is required", field)
+		case names[e.Name]:
+			return fmt.Errorf("%s.name %q is used twice", field, e.Name)
+		case len(e.Checks) == 0:
+			return fmt.Errorf("%s (%s).checks is required", field, e.Name)
+		case e.Justification == "":
+			return fmt.Errorf("%s (%s).justification is required", field, e.Name)
+		case e.Owner == "":
+			return fmt.Errorf("%s (%s).owner is required", field, e.Name)
+		case e.Expires.IsZero():
+			return fmt.Errorf("%s (%s).expires is required", field, e.Name)
+		case len(e.Namespaces) == 0 && len(e.Labels) == 0 && len(e.ServiceAccounts) == 0 &&
+			len(e.Users) == 0 && len(e.Groups) == 0 && len(e.Images) == 0:
+			return fmt.Errorf("%s (%s) must select requests by namespace, labels, service account, user, group or image", field, e.Name)
+		}
+		names[e.Name] = true
+	}
+	return nil
+}
+
+// MatchesAny reports whether value matches one of the patterns. A trailing * matches any suffix, as in
+// the images of exemptions and the seccomp, AppArmor and SELinux allowlists.
+func MatchesAny(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if strings.HasPrefix(value, prefix) {
+				return true
+			}
+		} else if value == pattern {
+			return true
+		}
+	}
+	return false
+}
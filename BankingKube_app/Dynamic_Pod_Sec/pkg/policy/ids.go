@@ -0,0 +1,135 @@
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// IDRule is how an IDPolicy restricts a user or group ID
+type IDRule string
+
+const (
+	// IDRuleRunAsAny allows any ID (default)
+	IDRuleRunAsAny IDRule = "RunAsAny"
+	// IDRuleMustRunAsNonRoot denies ID 0
+	IDRuleMustRunAsNonRoot IDRule = "MustRunAsNonRoot"
+	// IDRuleMustRunAs requires the ID to be set and inside one of the ranges; an empty list admits no ID
+	IDRuleMustRunAs IDRule = "MustRunAs"
+)
+
+// idRuleStrictness orders the rules from the least to the most strict; unset is RunAsAny
+var idRuleStrictness = map[IDRule]int{"": 0, IDRuleRunAsAny: 0, IDRuleMustRunAsNonRoot: 1, IDRuleMustRunAs: 2}
+
+// IDRange is an inclusive range of user or group IDs
+type IDRange struct {
+	Min int64 `yaml:"min" json:"min"`
+	Max int64 `yaml:"max" json:"max"`
+}
+
+// IDPolicy restricts one user or group ID of the security context
+type IDPolicy struct {
+	Rule   IDRule    `yaml:"rule" json:"rule,omitempty"`
+	Ranges []IDRange `yaml:"ranges" json:"ranges,omitempty"` // Only with MustRunAs
+}
+
+// Allows reports whether the rule admits id
+func (p *IDPolicy) Allows(id int64) bool {
+	switch p.Rule {
+	case IDRuleMustRunAsNonRoot:
+		return id != 0
+	case IDRuleMustRunAs:
+		for _, r := range p.Ranges {
+			if id >= r.Min && id <= r.Max {
+				return true
+			}
+		}
+		return false
+	default:
+		return true
+	}
+}
+
+// Default returns the ID the mutating webhook sets when the ID is unset: the minimum of the first range.
+// Only MustRunAs has one.
+func (p *IDPolicy) Default() (int64, bool) {
+	if p.Rule != IDRuleMustRunAs || len(p.Ranges) == 0 {
+		return 0, false
+	}
+	return p.Ranges[0].Min, true
+}
+
+// Describe renders the IDs the rule admits, for the expected value of violations
+func (p *IDPolicy) Describe() string {
+	switch p.Rule {
+	case IDRuleMustRunAsNonRoot:
+		return "a non-zero ID"
+	case IDRuleMustRunAs:
+		if len(p.Ranges) == 0 {
+			return "no ID (the ranges of the policies do not overlap)"
+		}
+		ranges := make([]string, len(p.Ranges))
+		for i, r := range p.Ranges {
+			ranges[i] = fmt.Sprintf("%d-%d", r.Min, r.Max)
+		}
+		return "an ID in " + strings.Join(ranges, ", ")
+	default:
+		return "any ID"
+	}
+}
+
+// Validate checks that the rule is known, that MustRunAs and only MustRunAs has ranges, and that every range
+// is non-negative with min <= max. MustRunAs with an empty list (`ranges: []`) denies every ID, which is
+// also what tightening two MustRunAs rules without a common ID produces; leaving ranges out is an error.
+func (p *IDPolicy) Validate(field string) error {
+	if _, ok := idRuleStrictness[p.Rule]; !ok {
+		return fmt.Errorf("%s.rule: unknown rule %q (expected RunAsAny, MustRunAsNonRoot or MustRunAs)", field, p.Rule)
+	}
+	if p.Rule == IDRuleMustRunAs && p.Ranges == nil {
+		return fmt.Errorf("%s.ranges: MustRunAs needs ranges (an empty list denies every ID)", field)
+	}
+	if p.Rule != IDRuleMustRunAs && len(p.Ranges) > 0 {
+		return fmt.Errorf("%s.ranges: only MustRunAs takes ranges", field)
+	}
+	var errs []error
+	for i, r := range p.Ranges {
+		switch {
+		case r.Min < 0:
+			errs = append(errs, fmt.Errorf("%s.ranges[%d]: min %d is negative", field, i, r.Min))
+		case r.Min > r.Max:
+			errs = append(errs, fmt.Errorf("%s.ranges[%d]: min %d is greater than max %d", field, i, r.Min, r.Max))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// tightenIDs keeps the stricter rule. Two MustRunAs rules keep the IDs in both; MustRunAs combined with
+// MustRunAsNonRoot leaves out ID 0.
+func tightenIDs(base, overlay IDPolicy) IDPolicy {
+	if idRuleStrictness[overlay.Rule] > idRuleStrictness[base.Rule] {
+		base, overlay = overlay, base
+	}
+	out := IDPolicy{Rule: base.Rule, Ranges: slices.Clone(base.Ranges)}
+	switch {
+	case base.Rule == IDRuleMustRunAs && overlay.Rule == IDRuleMustRunAs:
+		out.Ranges = intersectIDRanges(base.Ranges, overlay.Ranges)
+	case base.Rule == IDRuleMustRunAs && overlay.Rule == IDRuleMustRunAsNonRoot:
+		out.Ranges = intersectIDRanges(base.Ranges, []IDRange{{Min: 1, Max: 1<<63 - 1}})
+	}
+	return out
+}
+
+// intersectIDRanges returns the IDs inside a range of both lists. An empty result admits no ID.
+func intersectIDRanges(a, b []IDRange) []IDRange {
+	out := []IDRange{}
+	for _, x := range a {
+		for _, y := range b {
+			r := IDRange{Min: max(x.Min, y.Min), Max: min(x.Max, y.Max)}
+			if r.Min <= r.Max {
+				out = append(out, r)
+			}
+		}
+	}
+	return out
+}
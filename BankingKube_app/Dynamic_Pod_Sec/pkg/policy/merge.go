@@ -0,0 +1,262 @@
+package policy
+
+import (
+	"net"
+	"slices"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// modeStrictness orders the enforcement modes from the most to the least strict
+var modeStrictness = map[Mode]int{ModeEnforce: 3, ModeWarn: 2, ModeAudit: 1, ModeDryRun: 0}
+
+// Tighten returns base restricted further by overlay. The result is never looser than base:
+// denylists are joined, allowlists are intersected, requirements are or'ed, permissions are and'ed,
+// ranges shrink, ID rules keep the stricter rule and the common IDs, every check keeps the stricter of
+// the two enforcement modes and every namespace the stricter of its two Pod Security Standards profiles.
+// An empty allowlist or range in overlay has no opinion and leaves base as it is, except that the
+// security profile and ingress allowlists tell an empty list, which allows nothing, from an unset one.
+// Custom rules are joined too; a rule of overlay named like a rule of base cannot replace it and is dropped.
+// Execution, consistency and exemptions only come from base; an overlay cannot grant exemptions.
+func Tighten(base, overlay *SecurityPolicies) *SecurityPolicies {
+	out := base.DeepCopy()
+
+	out.Enforcement = tightenEnforcement(&base.Enforcement, &overlay.Enforcement, declaredModes(base.CustomRules), declaredModes(overlay.CustomRules))
+	out.Updates.RecheckAll = base.Updates.RecheckAll || overlay.Updates.RecheckAll
+	for name, c := range overlay.Updates.Checks {
+		if c.RecheckAll != nil && *c.RecheckAll {
+			if out.Updates.Checks == nil {
+				out.Updates.Checks = make(map[string]CheckUpdates)
+			}
+			out.Updates.Checks[name] = CheckUpdates{RecheckAll: c.RecheckAll}
+		}
+	}
+
+	out.CustomRules = unionRules(out.CustomRules, overlay.CustomRules)
+
+	out.APIRestrictions.RestrictedAPIPaths = union(out.APIRestrictions.RestrictedAPIPaths, overlay.APIRestrictions.RestrictedAPIPaths)
+	out.ServiceAccountRestrictions.RestrictedServiceAccounts = union(out.ServiceAccountRestrictions.RestrictedServiceAccounts, overlay.ServiceAccountRestrictions.RestrictedServiceAccounts)
+
+	out.PodSecurityStandards = tightenStandards(&base.PodSecurityStandards, &overlay.PodSecurityStandards)
+
+	out.PodSecurityContext.AllowPrivilegeEscalation = base.PodSecurityContext.AllowPrivilegeEscalation && overlay.PodSecurityContext.AllowPrivilegeEscalation
+	out.PodSecurityContext.RunAsNonRoot = base.PodSecurityContext.RunAsNonRoot || overlay.PodSecurityContext.RunAsNonRoot
+	out.PodSecurityContext.ReadOnlyRootFilesystem = base.PodSecurityContext.ReadOnlyRootFilesystem || overlay.PodSecurityContext.ReadOnlyRootFilesystem
+	out.PodSecurityContext.RunAsUser = tightenIDs(base.PodSecurityContext.RunAsUser, overlay.PodSecurityContext.RunAsUser)
+	out.PodSecurityContext.RunAsGroup = tightenIDs(base.PodSecurityContext.RunAsGroup, overlay.PodSecurityContext.RunAsGroup)
+	out.PodSecurityContext.FSGroup = tightenIDs(base.PodSecurityContext.FSGroup, overlay.PodSecurityContext.FSGroup)
+	out.PodSecurityContext.SupplementalGroups = tightenIDs(base.PodSecurityContext.SupplementalGroups, overlay.PodSecurityContext.SupplementalGroups)
+	out.PodSecurityContext.DefaultIDs = base.PodSecurityContext.DefaultIDs || overlay.PodSecurityContext.DefaultIDs
+	out.PodSecurityContext.Seccomp.AllowedProfiles = tightenPatterns(base.PodSecurityContext.Seccomp.AllowedProfiles, overlay.PodSecurityContext.Seccomp.AllowedProfiles)
+	out.PodSecurityContext.AppArmor.AllowedProfiles = tightenPatterns(base.PodSecurityContext.AppArmor.AllowedProfiles, overlay.PodSecurityContext.AppArmor.AllowedProfiles)
+	out.PodSecurityContext.SELinux.AllowedTypes = tightenPatterns(base.PodSecurityContext.SELinux.AllowedTypes, overlay.PodSecurityContext.SELinux.AllowedTypes)
+	out.PodSecurityContext.SELinux.AllowedLevels = tightenPatterns(base.PodSecurityContext.SELinux.AllowedLevels, overlay.PodSecurityContext.SELinux.AllowedLevels)
+
+	out.Capabilities.AllowedCapabilities = tightenAllowedCapabilities(base.Capabilities, overlay.Capabilities)
+	out.Capabilities.EnforceAllowedCapabilities = base.Capabilities.EnforceAllowedCapabilities || overlay.Capabilities.EnforceAllowedCapabilities
+	out.Capabilities.DisallowedCapabilities = union(out.Capabilities.DisallowedCapabilities, overlay.Capabilities.DisallowedCapabilities)
+	out.Capabilities.RequiredDrops = union(out.Capabilities.RequiredDrops, overlay.Capabilities.RequiredDrops)
+
+	out.ImageSecurity.AllowedRegistries = intersect(out.ImageSecurity.AllowedRegistries, overlay.ImageSecurity.AllowedRegistries, narrowerPrefix)
+	out.ImageSecurity.RequireImageSigning = base.ImageSecurity.RequireImageSigning || overlay.ImageSecurity.RequireImageSigning
+	out.ImageSecurity.DisallowedTags = union(out.ImageSecurity.DisallowedTags, overlay.ImageSecurity.DisallowedTags)
+
+	network, overlayNetwork := &out.NetworkSecurity, &overlay.NetworkSecurity
+	network.HostNetworkPolicy.AllowHostNetwork = base.NetworkSecurity.HostNetworkPolicy.AllowHostNetwork && overlayNetwork.HostNetworkPolicy.AllowHostNetwork
+	network.NetworkPolicy.RequiredNetworkPolicies = union(out.NetworkSecurity.NetworkPolicy.RequiredNetworkPolicies, overlayNetwork.NetworkPolicy.RequiredNetworkPolicies)
+	network.EgressPolicy.AllowedEgressCIDRs = intersect(out.NetworkSecurity.EgressPolicy.AllowedEgressCIDRs, overlayNetwork.EgressPolicy.AllowedEgressCIDRs, narrowerCIDR)
+	network.IngressPolicy.AllowedIngressCIDRs = tightenAllowlist(base.NetworkSecurity.IngressPolicy.AllowedIngressCIDRs, overlayNetwork.IngressPolicy.AllowedIngressCIDRs, narrowerCIDR)
+
+	out.CheckRoleBindingsPolicy.RestrictedClusterRoles = union(out.CheckRoleBindingsPolicy.RestrictedClusterRoles, overlay.CheckRoleBindingsPolicy.RestrictedClusterRoles)
+	out.CheckRoleBindingsPolicy.RestrictedRoleBindings = union(out.CheckRoleBindingsPolicy.RestrictedRoleBindings, overlay.CheckRoleBindingsPolicy.RestrictedRoleBindings)
+	out.PermissionLevelsPolicy.RestrictedVerbs = union(out.PermissionLevelsPolicy.RestrictedVerbs, overlay.PermissionLevelsPolicy.RestrictedVerbs)
+	out.PermissionLevelsPolicy.RestrictedResources = union(out.PermissionLevelsPolicy.RestrictedResources, overlay.PermissionLevelsPolicy.RestrictedResources)
+	out.RoleScopePolicy.RestrictedNamespaces = union(out.RoleScopePolicy.RestrictedNamespaces, overlay.RoleScopePolicy.RestrictedNamespaces)
+
+	out.ResourceLimits.CPULimits = tightenRange(out.ResourceLimits.CPULimits, overlay.ResourceLimits.CPULimits)
+	out.ResourceLimits.MemoryLimits = tightenRange(out.ResourceLimits.MemoryLimits, overlay.ResourceLimits.MemoryLimits)
+	out.ResourceLimits.EnforceResourceLimits = base.ResourceLimits.EnforceResourceLimits || overlay.ResourceLimits.EnforceResourceLimits
+	out.EnforceResourceRequests = base.EnforceResourceRequests || overlay.EnforceResourceRequests
+
+	out.VolumeSecurity.DisallowedHostPaths = union(out.VolumeSecurity.DisallowedHostPaths, overlay.VolumeSecurity.DisallowedHostPaths)
+	out.VolumeSecurity.RestrictedStorageClasses = union(out.VolumeSecurity.RestrictedStorageClasses, overlay.VolumeSecurity.RestrictedStorageClasses)
+
+	return out
+}
+
+// stricter returns the stricter of two modes
+func stricter(a, b Mode) Mode {
+	if modeStrictness[b] > modeStrictness[a] {
+		return b
+	}
+	return a
+}
+
+// tightenEnforcement gives every check the stricter of its two modes. Only the modes overlay sets
+// explicitly count, since an unset mode would otherwise read as enforce. The declared modes of custom
+// rules count as the modes of their policy, so an overlay cannot loosen a rule below its declared mode.
+func tightenEnforcement(base, overlay *Enforcement, baseDeclared, overlayDeclared map[string]Mode) Enforcement {
+	out := Enforcement{DefaultMode: base.ModeFor("")}
+	if overlay.DefaultMode != "" {
+		out.DefaultMode = stricter(out.DefaultMode, overlay.DefaultMode)
+	}
+
+	names := make(map[string]bool)
+	for name := range base.Checks {
+		names[name] = true
+	}
+	for name := range overlay.Checks {
+		names[name] = true
+	}
+	for name := range baseDeclared {
+		names[name] = true
+	}
+	for name := range overlayDeclared {
+		names[name] = true
+	}
+	if len(names) > 0 {
+		out.Checks = make(map[string]Mode, len(names))
+	}
+	for name := range names {
+		mode := base.ModeForDeclared(name, baseDeclared[name])
+		if m, ok := overlay.Checks[name]; ok && m != "" {
+			mode = stricter(mode, m)
+		} else if declared := overlayDeclared[name]; declared != "" {
+			mode = stricter(mode, declared)
+		} else if overlay.DefaultMode != "" {
+			mode = stricter(mode, overlay.DefaultMode)
+		}
+		out.Checks[name] = mode
+	}
+	return out
+}
+
+// declaredModes returns the modes the custom rules declare by rule name
+func declaredModes(rules []CustomRule) map[string]Mode {
+	modes := make(map[string]Mode)
+	for _, rule := range rules {
+		if rule.Mode != "" {
+			modes[rule.Name] = rule.Mode
+		}
+	}
+	return modes
+}
+
+// union returns the sorted values of a and b without duplicates
+func union(a, b []string) []string {
+	if len(b) == 0 {
+		return a
+	}
+	seen := make(map[string]bool, len(a)+len(b))
+	var out []string
+	for _, v := range append(append([]string{}, a...), b...) {
+		if !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// unionRules appends the rules of overlay whose names base does not use
+func unionRules(base, overlay []CustomRule) []CustomRule {
+	names := make(map[string]bool, len(base))
+	for _, rule := range base {
+		names[rule.Name] = true
+	}
+	out := base
+	for _, rule := range overlay {
+		if !names[rule.Name] {
+			names[rule.Name] = true
+			out = append(out, *rule.DeepCopy())
+		}
+	}
+	return out
+}
+
+// intersect keeps what both allowlists allow. narrower returns the narrower of two entries when
+// one contains the other and "" when they are disjoint. An empty overlay has no opinion.
+func intersect(base, overlay []string, narrower func(a, b string) string) []string {
+	if len(overlay) == 0 {
+		return base
+	}
+	seen := make(map[string]bool)
+	out := []string{}
+	for _, a := range base {
+		for _, b := range overlay {
+			if v := narrower(a, b); v != "" && !seen[v] {
+				seen[v] = true
+				out = append(out, v)
+			}
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// tightenAllowlist intersects two allowlists in which nil allows anything and an empty list allows
+// nothing. Disjoint allowlists leave an empty one that allows nothing.
+func tightenAllowlist(base, overlay []string, narrower func(a, b string) string) []string {
+	switch {
+	case overlay == nil:
+		return slices.Clone(base)
+	case base == nil:
+		return slices.Clone(overlay)
+	case len(overlay) == 0:
+		return []string{}
+	}
+	return intersect(base, overlay, narrower)
+}
+
+// narrowerPrefix compares registry prefixes
+func narrowerPrefix(a, b string) string {
+	switch {
+	case strings.HasPrefix(b, a):
+		return b
+	case strings.HasPrefix(a, b):
+		return a
+	}
+	return ""
+}
+
+// narrowerCIDR compares networks
+func narrowerCIDR(a, b string) string {
+	_, netA, errA := net.ParseCIDR(a)
+	_, netB, errB := net.ParseCIDR(b)
+	if errA != nil || errB != nil {
+		return ""
+	}
+	sizeA, _ := netA.Mask.Size()
+	sizeB, _ := netB.Mask.Size()
+	switch {
+	case sizeB >= sizeA && netA.Contains(netB.IP):
+		return b
+	case sizeA >= sizeB && netB.Contains(netA.IP):
+		return a
+	}
+	return ""
+}
+
+// tightenRange keeps the higher min and the lower max. An empty end has no opinion.
+func tightenRange(base, overlay Range) Range {
+	out := base
+	if overlay.Min != "" && (base.Min == "" || compareQuantities(overlay.Min, base.Min) > 0) {
+		out.Min = overlay.Min
+	}
+	if overlay.Max != "" && (base.Max == "" || compareQuantities(overlay.Max, base.Max) < 0) {
+		out.Max = overlay.Max
+	}
+	return out
+}
+
+func compareQuantities(a, b string) int {
+	qa, errA := resource.ParseQuantity(a)
+	qb, errB := resource.ParseQuantity(b)
+	if errA != nil || errB != nil {
+		return 0
+	}
+	return qa.Cmp(qb)
+}
@@ -0,0 +1,221 @@
+package policy
+
+import (
+	"os"
+
+	"gopkg.in/yaml.v2"
+)
+
+// DefaultPath is the policy file used when SECURITY_POLICIES_PATH is not set
+const DefaultPath = "configs/security-policies.yaml"
+
+// Path returns the location of the policy file
+func Path() string {
+	configPath := os.Getenv("SECURITY_POLICIES_PATH")
+	if configPath == "" {
+		configPath = DefaultPath // Default path
+	}
+	return configPath
+}
+
+// document is the root of the policy file
+// +k8s:deepcopy-gen=false
+type document struct {
+	Policies SecurityPolicies `yaml:"policies"`
+}
+
+// SecurityPolicies represents the policies section of the security-policies.yaml file
+type SecurityPolicies struct {
+	Enforcement Enforcement `yaml:"enforcement" json:"enforcement,omitempty"`
+	Execution   Execution   `yaml:"execution" json:"execution,omitempty"`
+	Updates     Updates     `yaml:"updates" json:"updates,omitempty"`
+	Exemptions  []Exemption `yaml:"exemptions" json:"exemptions,omitempty"`
+
+	// Rules written as CEL expressions, served on /validate/custom
+	CustomRules []CustomRule `yaml:"customRules" json:"customRules,omitempty"`
+
+	// Api & service account restrictions
+	APIRestrictions            APIRestrictions            `yaml:"apiRestrictions" json:"apiRestrictions,omitempty"`
+	ServiceAccountRestrictions ServiceAccountRestrictions `yaml:"serviceAccountRestrictions" json:"serviceAccountRestrictions,omitempty"`
+
+	// Pod Security Standards profiles, served on /validate/pss
+	PodSecurityStandards PodSecurityStandards `yaml:"podSecurityStandards" json:"podSecurityStandards,omitempty"`
+
+	// Context & Capabilities Policies
+	PodSecurityContext PodSecurityContext `yaml:"podSecurityContext" json:"podSecurityContext,omitempty"`
+	Capabilities       Capabilities       `yaml:"capabilities" json:"capabilities,omitempty"`
+
+	// Image Security Policies
+	ImageSecurity ImageSecurity `yaml:"imageSecurity" json:"imageSecurity,omitempty"`
+
+	// Network Security Policies
+	NetworkSecurity NetworkSecurity `yaml:"NetworkSecurity" json:"NetworkSecurity,omitempty"`
+
+	// RBAC Policies
+	CheckRoleBindingsPolicy CheckRoleBindingsPolicy `yaml:"checkRoleBindingsPolicy" json:"checkRoleBindingsPolicy,omitempty"`
+	PermissionLevelsPolicy  PermissionLevelsPolicy  `yaml:"permissionLevelsPolicy" json:"permissionLevelsPolicy,omitempty"`
+	RoleScopePolicy         RoleScopePolicy         `yaml:"roleScopePolicy" json:"roleScopePolicy,omitempty"`
+
+	// Resource Limits Policies
+	ResourceLimits          ResourceLimits `yaml:"resourceLimits" json:"resourceLimits,omitempty"`
+	EnforceResourceRequests bool           `yaml:"enforceResourceRequests" json:"enforceResourceRequests,omitempty"`
+
+	// Volume Security Policies
+	VolumeSecurity VolumeSecurity `yaml:"volumeSecurity" json:"volumeSecurity,omitempty"`
+}
+
+// APIRestrictions defines a structure for API restrictions
+type APIRestrictions struct {
+	RestrictedAPIPaths []string `yaml:"restrictedAPIPaths" json:"restrictedAPIPaths,omitempty"`
+}
+
+// ServiceAccountRestrictions defines a structure for service account restrictions
+type ServiceAccountRestrictions struct {
+	RestrictedServiceAccounts []string `yaml:"restrictedServiceAccounts" json:"restrictedServiceAccounts,omitempty"`
+}
+
+// PodSecurityContext defines a structure for pod security context policies
+type PodSecurityContext struct {
+	AllowPrivilegeEscalation bool `yaml:"allowPrivilegeEscalation" json:"allowPrivilegeEscalation,omitempty"`
+	RunAsNonRoot             bool `yaml:"runAsNonRoot" json:"runAsNonRoot,omitempty"`
+	ReadOnlyRootFilesystem   bool `yaml:"readOnlyRootFilesystem" json:"readOnlyRootFilesystem,omitempty"`
+
+	// User and group IDs, checked on the effective security context of every container
+	RunAsUser          IDPolicy `yaml:"runAsUser" json:"runAsUser,omitempty"`
+	RunAsGroup         IDPolicy `yaml:"runAsGroup" json:"runAsGroup,omitempty"`
+	FSGroup            IDPolicy `yaml:"fsGroup" json:"fsGroup,omitempty"`
+	SupplementalGroups IDPolicy `yaml:"supplementalGroups" json:"supplementalGroups,omitempty"`
+	// DefaultIDs makes the mutating webhook set unset IDs governed by MustRunAs to the minimum of their first range
+	DefaultIDs bool `yaml:"defaultIDs" json:"defaultIDs,omitempty"`
+
+	// Linux security modules, checked on the effective security context of every container
+	Seccomp  SeccompPolicy  `yaml:"seccomp" json:"seccomp,omitempty"`
+	AppArmor AppArmorPolicy `yaml:"appArmor" json:"appArmor,omitempty"`
+	SELinux  SELinuxPolicy  `yaml:"seLinux" json:"seLinux,omitempty"`
+}
+
+// Capabilities defines a structure for capabilities policies. Names may be written with or without
+// the CAP_ prefix; ALL stands for every capability.
+type Capabilities struct {
+	AllowedCapabilities    []string `yaml:"allowedCapabilities" json:"allowedCapabilities,omitempty"`
+	DisallowedCapabilities []string `yaml:"disallowedCapabilities" json:"disallowedCapabilities,omitempty"`
+	RequiredDrops          []string `yaml:"requiredDrops" json:"requiredDrops,omitempty"`
+	// EnforceAllowedCapabilities only admits containers that drop ALL and add nothing but allowedCapabilities
+	EnforceAllowedCapabilities bool `yaml:"enforceAllowedCapabilities" json:"enforceAllowedCapabilities,omitempty"`
+}
+
+// ImageSecurity defines a structure for image security policies
+type ImageSecurity struct {
+	AllowedRegistries   []string `yaml:"allowedRegistries" json:"allowedRegistries,omitempty"`
+	RequireImageSigning bool     `yaml:"requireImageSigning" json:"requireImageSigning,omitempty"`
+	DisallowedTags      []string `yaml:"disallowedTags" json:"disallowedTags,omitempty"`
+}
+
+// NetworkSecurity groups the network security policies
+type NetworkSecurity struct {
+	HostNetworkPolicy HostNetworkPolicy `yaml:"hostNetworkPolicy" json:"hostNetworkPolicy,omitempty"`
+	NetworkPolicy     NetworkPolicy     `yaml:"networkPolicy" json:"networkPolicy,omitempty"`
+	EgressPolicy      EgressPolicy      `yaml:"egressPolicy" json:"egressPolicy,omitempty"`
+	IngressPolicy     IngressPolicy     `yaml:"ingressPolicy" json:"ingressPolicy,omitempty"`
+	ConsistencyPolicy ConsistencyPolicy `yaml:"consistencyPolicy" json:"consistencyPolicy,omitempty"`
+}
+
+// HostNetworkPolicy defines a structure for host network policies
+type HostNetworkPolicy struct {
+	AllowHostNetwork bool `yaml:"allowHostNetwork" json:"allowHostNetwork,omitempty"`
+}
+
+// NetworkPolicy defines a structure for network policies
+type NetworkPolicy struct {
+	RequiredNetworkPolicies []string `yaml:"requiredNetworkPolicies" json:"requiredNetworkPolicies,omitempty"`
+}
+
+// EgressPolicy defines a structure for egress network policies
+type EgressPolicy struct {
+	AllowedEgressCIDRs []string `yaml:"allowedEgressCIDRs" json:"allowedEgressCIDRs,omitempty"`
+}
+
+// IngressPolicy defines a structure for ingress network policies. The ingress check allows any
+// ingress when AllowedIngressCIDRs is unset and none when it is empty.
+type IngressPolicy struct {
+	AllowedIngressCIDRs []string `yaml:"allowedIngressCIDRs" json:"allowedIngressCIDRs,omitempty"`
+}
+
+// ConsistencyPolicy defines the structure for consistency checks in network policies
+type ConsistencyPolicy struct {
+	AccessEgressPolicy             EgressPolicy  `yaml:"accessEgressPolicy" json:"accessEgressPolicy,omitempty"`
+	AccessIngressPolicy            IngressPolicy `yaml:"accessIngressPolicy" json:"accessIngressPolicy,omitempty"`
+	AllowedOverlappingEgressCIDRs  []string      `yaml:"allowedOverlappingEgressCIDRs" json:"allowedOverlappingEgressCIDRs,omitempty"`
+	AllowedOverlappingIngressCIDRs []string      `yaml:"allowedOverlappingIngressCIDRs" json:"allowedOverlappingIngressCIDRs,omitempty"`
+}
+
+// CheckRoleBindingsPolicy defines the structure for RBAC policies
+type CheckRoleBindingsPolicy struct {
+	RestrictedClusterRoles []string `yaml:"restrictedClusterRoles" json:"restrictedClusterRoles,omitempty"`
+	RestrictedRoleBindings []string `yaml:"restrictedRoleBindings" json:"restrictedRoleBindings,omitempty"`
+}
+
+// PermissionLevelsPolicy defines the structure for permission level policies
+type PermissionLevelsPolicy struct {
+	RestrictedVerbs     []string `yaml:"restrictedVerbs" json:"restrictedVerbs,omitempty"`
+	RestrictedResources []string `yaml:"restrictedResources" json:"restrictedResources,omitempty"`
+}
+
+// RoleScopePolicy defines the structure for role scope policies
+type RoleScopePolicy struct {
+	RestrictedNamespaces []string `yaml:"restrictedNamespaces" json:"restrictedNamespaces,omitempty"`
+}
+
+// ResourceLimits defines a structure for resource limits policies
+type ResourceLimits struct {
+	CPULimits             Range `yaml:"cpuLimits" json:"cpuLimits,omitempty"`
+	MemoryLimits          Range `yaml:"memoryLimits" json:"memoryLimits,omitempty"`
+	EnforceResourceLimits bool  `yaml:"enforceResourceLimits" json:"enforceResourceLimits,omitempty"`
+}
+
+// Range is an inclusive range of resource quantities (e.g. "200m" to "1000m")
+type Range struct {
+	Max string `yaml:"max" json:"max,omitempty"`
+	Min string `yaml:"min" json:"min,omitempty"`
+}
+
+// VolumeSecurity defines a structure for volume security policies
+type VolumeSecurity struct {
+	DisallowedHostPaths      []string `yaml:"disallowedHostPaths" json:"disallowedHostPaths,omitempty"`
+	RestrictedStorageClasses []string `yaml:"restrictedStorageClasses" json:"restrictedStorageClasses,omitempty"`
+}
+
+// Load reads and parses the policy file at path
+func Load(path string) (*SecurityPolicies, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Parse decodes the content of a policy file and validates it. Decoding is strict: a key the
+// typed model does not know is an error rather than a silently ignored rule.
+func Parse(data []byte) (*SecurityPolicies, error) {
+	var doc document
+	if err := yaml.UnmarshalStrict(data, &doc); err != nil {
+		return nil, err
+	}
+	if err := doc.Policies.Validate(); err != nil {
+		return nil, err
+	}
+	return &doc.Policies, nil
+}
+
+// LoadDefault returns the current snapshot of the policy file configured through SECURITY_POLICIES_PATH.
+// The file is read once and then only again when DefaultStore().Watch sees it change.
+func LoadDefault() (*SecurityPolicies, error) {
+	store := DefaultStore()
+	if _, err := store.Load(); err != nil {
+		// Nothing has loaded yet (e.g. the ConfigMap was mounted late), so try again
+		if err := store.Reload(); err != nil {
+			return nil, err
+		}
+	}
+	return store.Load()
+}
@@ -0,0 +1,459 @@
+package policy
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestLoadRepositoryPolicies checks that every section of the shipped policy file reaches the typed model
+func TestLoadRepositoryPolicies(t *testing.T) {
+	policies, err := Load("../../configs/security-policies.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		got  interface{}
+		want interface{}
+	}{
+		{"enforcement.defaultMode", policies.Enforcement.DefaultMode, ModeEnforce},
+		{"apiRestrictions", len(policies.APIRestrictions.RestrictedAPIPaths), 2},
+		{"serviceAccountRestrictions", policies.ServiceAccountRestrictions.RestrictedServiceAccounts, []string{"default", "admin"}},
+		{"podSecurityContext.runAsNonRoot", policies.PodSecurityContext.RunAsNonRoot, true},
+		{"capabilities.requiredDrops", policies.Capabilities.RequiredDrops, []string{"SYS_ADMIN", "NET_ADMIN"}},
+		{"imageSecurity.requireImageSigning", policies.ImageSecurity.RequireImageSigning, true},
+		{"imageSecurity.disallowedTags", policies.ImageSecurity.DisallowedTags, []string{"latest", "unstable", "dev"}},
+		{"NetworkSecurity.hostNetworkPolicy", policies.NetworkSecurity.HostNetworkPolicy.AllowHostNetwork, false},
+		{"NetworkSecurity.egressPolicy", policies.NetworkSecurity.EgressPolicy.AllowedEgressCIDRs, []string{"10.0.0.0/24", "192.168.1.0/24"}},
+		{"NetworkSecurity.ingressPolicy", len(policies.NetworkSecurity.IngressPolicy.AllowedIngressCIDRs), 3},
+		{"NetworkSecurity.consistencyPolicy", policies.NetworkSecurity.ConsistencyPolicy.AllowedOverlappingEgressCIDRs, []string{"10.0.0.0/24", "192.168.1.0/24"}},
+		{"checkRoleBindingsPolicy", policies.CheckRoleBindingsPolicy.RestrictedClusterRoles, []string{"cluster-admin", "admin"}},
+		{"permissionLevelsPolicy", policies.PermissionLevelsPolicy.RestrictedVerbs, []string{"delete", "update"}},
+		{"roleScopePolicy", policies.RoleScopePolicy.RestrictedNamespaces, []string{"kube-system", "default"}},
+		{"resourceLimits.cpuLimits", policies.ResourceLimits.CPULimits, Range{Max: "1000m", Min: "200m"}},
+		{"enforceResourceRequests", policies.EnforceResourceRequests, true},
+		{"volumeSecurity", policies.VolumeSecurity.DisallowedHostPaths, []string{"/var/run/docker.sock", "/root"}},
+	}
+	for _, tt := range tests {
+		if !reflect.DeepEqual(tt.got, tt.want) {
+			t.Errorf("%s = %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestParseRejectsUnknownMode(t *testing.T) {
+	_, err := Parse([]byte("policies:\n  enforcement:\n    checks:\n      image_tags: block\n"))
+	if err == nil {
+		t.Fatal("Parse accepted an unknown enforcement mode")
+	}
+
+	policies, err := Parse([]byte("policies:\n  enforcement:\n    defaultMode: audit\n    checks:\n      image_tags: warn\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := policies.Enforcement.ModeFor("image_tags"); got != ModeWarn {
+		t.Errorf("image_tags mode = %q, want warn", got)
+	}
+	if got := policies.Enforcement.ModeFor("capabilities"); got != ModeAudit {
+		t.Errorf("capabilities mode = %q, want audit", got)
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	if mode, err := ParseMode(""); err != nil || mode != ModeEnforce {
+		t.Errorf("ParseMode(\"\") = %q, %v; want enforce", mode, err)
+	}
+	if _, err := ParseMode("block"); err == nil {
+		t.Error("ParseMode accepted an unknown mode")
+	}
+}
+
+func TestErrorPolicyFor(t *testing.T) {
+	t.Setenv("DEFAULT_ERROR_POLICY", "")
+
+	var missing *Execution
+	if got := missing.ErrorPolicyFor("capabilities", ErrorPolicyDefault); got != ErrorPolicyDeny {
+		t.Errorf("without a policy file = %q, want deny", got)
+	}
+	if got := missing.ErrorPolicyFor("capabilities", ErrorPolicyWarn); got != ErrorPolicyWarn {
+		t.Errorf("declared warn without a policy file = %q, want warn", got)
+	}
+
+	t.Setenv("DEFAULT_ERROR_POLICY", "warn")
+	if got := missing.ErrorPolicyFor("capabilities", ErrorPolicyDefault); got != ErrorPolicyWarn {
+		t.Errorf("with DEFAULT_ERROR_POLICY=warn = %q, want warn", got)
+	}
+
+	policies, err := Parse([]byte("policies:\n  execution:\n    defaultErrorPolicy: deny\n    checks:\n      image_signing:\n        errorPolicy: warn\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := policies.Execution.ErrorPolicyFor("image_signing", ErrorPolicyDeny); got != ErrorPolicyWarn {
+		t.Errorf("image_signing = %q, want warn", got)
+	}
+	if got := policies.Execution.ErrorPolicyFor("capabilities", ErrorPolicyDefault); got != ErrorPolicyDeny {
+		t.Errorf("capabilities = %q, want deny", got)
+	}
+
+	if _, err := Parse([]byte("policies:\n  execution:\n    defaultErrorPolicy: ignore\n")); err == nil {
+		t.Error("Parse accepted an unknown error policy")
+	}
+}
+
+func TestParseExemptions(t *testing.T) {
+	const valid = `policies:
+  exemptions:
+    - name: aws-node
+      checks: [host_network, host_path]
+      namespaces: [kube-system]
+      labels:
+        k8s-app: aws-node
+      justification: The VPC CNI configures the node network
+      owner: platform-team
+      expires: 2026-12-31
+`
+	policies, err := Parse([]byte(valid))
+	if err != nil {
+		t.Fatal(err)
+	}
+	exemption := policies.Exemptions[0]
+	if !exemption.Covers("host_path") || exemption.Covers("capabilities") {
+		t.Errorf("exemption covers %v, want host_network and host_path", exemption.Checks)
+	}
+	if exemption.Expired(time.Date(2026, 12, 31, 23, 59, 0, 0, time.UTC)) {
+		t.Error("exemption expired before the end of its expiry day")
+	}
+	if !exemption.Expired(time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Error("exemption still applies after its expiry day")
+	}
+
+	invalid := map[string]string{
+		"missing owner":         strings.Replace(valid, "      owner: platform-team\n", "", 1),
+		"missing justification": strings.Replace(valid, "      justification: The VPC CNI configures the node network\n", "", 1),
+		"missing expiry":        strings.Replace(valid, "      expires: 2026-12-31\n", "", 1),
+		"malformed expiry":      strings.Replace(valid, "2026-12-31", "31/12/2026", 1),
+		"no selector":           strings.Replace(strings.Replace(valid, "      namespaces: [kube-system]\n", "", 1), "      labels:\n        k8s-app: aws-node\n", "", 1),
+	}
+	for name, doc := range invalid {
+		if _, err := Parse([]byte(doc)); err == nil {
+			t.Errorf("%s: Parse accepted the exemption", name)
+		}
+	}
+}
+
+func TestParseIsStrict(t *testing.T) {
+	// The keys the old per-check loaders read, which never matched the shipped file
+	for _, doc := range []string{
+		"policies:\n  checkRoleBindings:\n    restrictedClusterRoles: [cluster-admin]\n",
+		"policies:\n  hostNetworkPolicy:\n    allowHostNetwork: false\n",
+		"policies:\n  NetworkSecurity:\n    hostNetworkPolicy:\n      allowHostNetwork: false\n      allowHostPorts: true\n",
+	} {
+		if _, err := Parse([]byte(doc)); err == nil {
+			t.Errorf("Parse accepted an unknown key in:\n%s", doc)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		want []string
+	}{
+		{
+			name: "cidrs",
+			doc:  "policies:\n  NetworkSecurity:\n    egressPolicy:\n      allowedEgressCIDRs: [10.0.0.0/24, 10.0.0.300/24]\n    ingressPolicy:\n      allowedIngressCIDRs: [any]\n",
+			want: []string{`allowedEgressCIDRs[1]: "10.0.0.300/24" is not a CIDR`, `allowedIngressCIDRs[0]: "any" is not a CIDR`},
+		},
+		{
+			name: "quantities",
+			doc:  "policies:\n  resourceLimits:\n    cpuLimits:\n      min: 200m\n      max: lots\n",
+			want: []string{`resourceLimits.cpuLimits.max: "lots" is not a quantity`},
+		},
+		{
+			name: "min above max",
+			doc:  "policies:\n  resourceLimits:\n    memoryLimits:\n      min: 2Gi\n      max: 512Mi\n",
+			want: []string{"resourceLimits.memoryLimits: min 2Gi is greater than max 512Mi"},
+		},
+		{
+			name: "profiles",
+			doc:  "policies:\n  podSecurityStandards:\n    profile: strict\n    namespaces:\n      payments: hardened\n",
+			want: []string{`podSecurityStandards.profile: unknown profile "strict"`, `podSecurityStandards.namespaces.payments: unknown profile "hardened"`},
+		},
+		{
+			name: "id rules",
+			doc: "policies:\n  podSecurityContext:\n    runAsUser:\n      rule: MustRunAs\n    runAsGroup:\n      rule: RunAsRoot\n" +
+				"    fsGroup:\n      rule: MustRunAsNonRoot\n      ranges: [{min: 1, max: 2}]\n" +
+				"    supplementalGroups:\n      rule: MustRunAs\n      ranges: [{min: 10, max: 1}, {min: -1, max: 5}]\n",
+			want: []string{
+				"podSecurityContext.runAsUser.ranges: MustRunAs needs ranges",
+				`podSecurityContext.runAsGroup.rule: unknown rule "RunAsRoot"`,
+				"podSecurityContext.fsGroup.ranges: only MustRunAs takes ranges",
+				"podSecurityContext.supplementalGroups.ranges[0]: min 10 is greater than max 1",
+				"podSecurityContext.supplementalGroups.ranges[1]: min -1 is negative",
+			},
+		},
+		{
+			name: "security profiles",
+			doc:  "policies:\n  podSecurityContext:\n    seccomp:\n      allowedProfiles: [RuntimeDefault, localhost/audit.json]\n    appArmor:\n      allowedProfiles: [Localhost/]\n",
+			want: []string{
+				`podSecurityContext.seccomp.allowedProfiles[1]: "localhost/audit.json" is not a profile`,
+				`podSecurityContext.appArmor.allowedProfiles[0]: "Localhost/" is not a profile`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.doc))
+			if err == nil {
+				t.Fatal("Parse accepted the policies")
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q does not report %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseCustomRules(t *testing.T) {
+	rule := func(expression string) string {
+		return "policies:\n  customRules:\n    - name: rule\n      message: violated\n      expression: '" + expression + "'\n"
+	}
+
+	policies, err := Parse([]byte(rule(`object.metadata.name.startsWith("web-") && request.userInfo.username != ""`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := policies.CustomRules[0].Program(); err != nil {
+		t.Errorf("Program() = %v after a successful load", err)
+	}
+
+	tests := []struct {
+		name string
+		doc  string
+		want string
+	}{
+		{"syntax error", rule("object.metadata.name ==="), "customRules[0].expression"},
+		{"unknown variable", rule("pod.metadata.name == \"web\""), "undeclared reference to 'pod'"},
+		{"not a bool", rule(`"web"`), "expression evaluates to string, not bool"},
+		{"missing message", strings.Replace(rule("true"), "      message: violated\n", "", 1), "customRules[0].message is required"},
+		{"unknown mode", rule("true") + "      mode: block\n", "customRules[0].mode"},
+		{"duplicate name", rule("true") + "    - name: rule\n      message: violated\n      expression: 'false'\n", `customRules[1].name: duplicate rule "rule"`},
+		{"invalid name", strings.Replace(rule("true"), "name: rule", "name: PCI rule", 1), `customRules[0].name: "PCI rule" may only contain`},
+		{"reserved name", strings.Replace(rule("true"), "name: rule", "name: check_errors", 1), `customRules[0].name: "check_errors" is reserved`},
+		{"built-in check", strings.Replace(rule("true"), "name: rule", "name: image_tags", 1), `customRules[0].name: "image_tags" is the name of a built-in check`},
+	}
+	ReserveCheckName("image_tags")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.doc))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Parse() error = %v, want it to report %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestTightenCustomRuleModes(t *testing.T) {
+	base := &SecurityPolicies{
+		Enforcement: Enforcement{DefaultMode: ModeDryRun},
+		CustomRules: []CustomRule{{Name: "pci_memory_limits", Mode: ModeEnforce}, {Name: "team_label"}},
+	}
+	overlay := &SecurityPolicies{
+		Enforcement: Enforcement{DefaultMode: ModeWarn, Checks: map[string]Mode{"pci_memory_limits": ModeWarn}},
+		CustomRules: []CustomRule{{Name: "no_literal_passwords", Mode: ModeAudit}},
+	}
+
+	merged := Tighten(base, overlay)
+	tests := []struct {
+		rule     string
+		declared Mode
+		want     Mode
+	}{
+		{"pci_memory_limits", ModeEnforce, ModeEnforce}, // declared enforce is not loosened by the overlay's warn
+		{"team_label", "", ModeWarn},                    // no declared mode, so the stricter default applies
+		{"no_literal_passwords", ModeAudit, ModeAudit},  // the overlay's rule keeps its declared mode over base's dryrun
+	}
+	for _, tt := range tests {
+		if got := merged.Enforcement.ModeForDeclared(tt.rule, tt.declared); got != tt.want {
+			t.Errorf("mode of %s = %s, want %s", tt.rule, got, tt.want)
+		}
+	}
+}
+
+func TestTightenPodSecurityStandards(t *testing.T) {
+	base := &SecurityPolicies{PodSecurityStandards: PodSecurityStandards{
+		Profile:    ProfileBaseline,
+		Namespaces: map[string]Profile{"kube-system": ProfilePrivileged, "payments": ProfileRestricted},
+	}}
+	overlay := &SecurityPolicies{PodSecurityStandards: PodSecurityStandards{
+		Namespaces: map[string]Profile{"kube-system": ProfileBaseline, "payments": ProfilePrivileged, "shop": ProfileRestricted},
+	}}
+
+	standards := Tighten(base, overlay).PodSecurityStandards
+	for namespace, want := range map[string]Profile{
+		"kube-system": ProfileBaseline,
+		"payments":    ProfileRestricted,
+		"shop":        ProfileRestricted,
+		"default":     ProfileBaseline,
+	} {
+		if got := standards.ProfileFor(namespace); got != want {
+			t.Errorf("ProfileFor(%s) = %s, want %s", namespace, got, want)
+		}
+	}
+}
+
+func TestTightenIDs(t *testing.T) {
+	mustRunAs := func(ranges ...IDRange) IDPolicy { return IDPolicy{Rule: IDRuleMustRunAs, Ranges: ranges} }
+	nonRoot := IDPolicy{Rule: IDRuleMustRunAsNonRoot}
+
+	tests := []struct {
+		name          string
+		base, overlay IDPolicy
+		want          IDPolicy
+	}{
+		{"no opinion", IDPolicy{}, IDPolicy{}, IDPolicy{}},
+		{"stricter rule wins", IDPolicy{Rule: IDRuleRunAsAny}, nonRoot, nonRoot},
+		{"ranges intersect", mustRunAs(IDRange{1000, 1999}, IDRange{5000, 5999}), mustRunAs(IDRange{1500, 5500}), mustRunAs(IDRange{1500, 1999}, IDRange{5000, 5500})},
+		{"disjoint ranges admit nothing", mustRunAs(IDRange{1, 10}), mustRunAs(IDRange{20, 30}), mustRunAs([]IDRange{}...)},
+		{"deny all stays deny all", mustRunAs([]IDRange{}...), IDPolicy{}, mustRunAs([]IDRange{}...)},
+		{"non root leaves out 0", nonRoot, mustRunAs(IDRange{0, 100}), mustRunAs(IDRange{1, 100})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Tighten(&SecurityPolicies{PodSecurityContext: PodSecurityContext{RunAsUser: tt.base}},
+				&SecurityPolicies{PodSecurityContext: PodSecurityContext{RunAsUser: tt.overlay}}).PodSecurityContext.RunAsUser
+			if got.Rule != tt.want.Rule || len(got.Ranges) != len(tt.want.Ranges) {
+				t.Fatalf("Tighten = %+v, want %+v", got, tt.want)
+			}
+			for i := range got.Ranges {
+				if got.Ranges[i] != tt.want.Ranges[i] {
+					t.Errorf("Tighten = %+v, want %+v", got, tt.want)
+				}
+			}
+			if got.Rule == IDRuleMustRunAs && got.Allows(0) {
+				t.Errorf("%+v allows 0", got)
+			}
+			if err := got.Validate("runAsUser"); err != nil {
+				t.Errorf("the tightened rule does not validate: %v", err)
+			}
+		})
+	}
+}
+
+func TestTightenSecurityProfiles(t *testing.T) {
+	base := &SecurityPolicies{PodSecurityContext: PodSecurityContext{
+		Seccomp: SeccompPolicy{AllowedProfiles: []string{"RuntimeDefault", "Localhost/profiles/*"}},
+	}}
+	overlay := &SecurityPolicies{PodSecurityContext: PodSecurityContext{
+		Seccomp:  SeccompPolicy{AllowedProfiles: []string{"Localhost/profiles/payments.json", "Unconfined"}},
+		AppArmor: AppArmorPolicy{AllowedProfiles: []string{"RuntimeDefault"}},
+		SELinux:  SELinuxPolicy{AllowedTypes: []string{"container_t"}},
+	}}
+
+	psc := Tighten(base, overlay).PodSecurityContext
+	if got := strings.Join(psc.Seccomp.AllowedProfiles, ","); got != "Localhost/profiles/payments.json" {
+		t.Errorf("seccomp profiles = %s, want only the payments profile", got)
+	}
+	// base allows any AppArmor profile and SELinux type, so the overlay's lists apply as they are
+	if !psc.AppArmor.Allows("RuntimeDefault") || psc.AppArmor.Allows("Unconfined") || !psc.SELinux.AllowsType("container_t") || psc.SELinux.AllowsType("spc_t") {
+		t.Errorf("Tighten = %+v, want the overlay's AppArmor and SELinux lists", psc)
+	}
+	if psc.SELinux.AllowedLevels != nil || !psc.SELinux.AllowsLevel("s0:c1") {
+		t.Errorf("SELinux levels = %v, want no opinion", psc.SELinux.AllowedLevels)
+	}
+
+	disjoint := Tighten(overlay, &SecurityPolicies{PodSecurityContext: PodSecurityContext{AppArmor: AppArmorPolicy{AllowedProfiles: []string{"Localhost/k8s-nginx"}}}})
+	if disjoint.PodSecurityContext.AppArmor.Allows("RuntimeDefault") || disjoint.PodSecurityContext.AppArmor.Allows("Localhost/k8s-nginx") {
+		t.Errorf("disjoint AppArmor lists allow %v", disjoint.PodSecurityContext.AppArmor.AllowedProfiles)
+	}
+
+	// An empty list allows nothing, so it tightens rather than being no opinion
+	denyAll := Tighten(base, &SecurityPolicies{PodSecurityContext: PodSecurityContext{Seccomp: SeccompPolicy{AllowedProfiles: []string{}}}})
+	if profiles := denyAll.PodSecurityContext.Seccomp.AllowedProfiles; profiles == nil || denyAll.PodSecurityContext.Seccomp.Allows("RuntimeDefault") {
+		t.Errorf("seccomp profiles = %#v, want an empty list that allows nothing", profiles)
+	}
+}
+
+func TestTightenIngressCIDRs(t *testing.T) {
+	ingress := func(cidrs ...string) *SecurityPolicies {
+		return &SecurityPolicies{NetworkSecurity: NetworkSecurity{IngressPolicy: IngressPolicy{AllowedIngressCIDRs: cidrs}}}
+	}
+
+	// base allows any ingress, so the overlay's list applies as it is
+	if got := Tighten(ingress(), ingress("10.0.0.0/16")).NetworkSecurity.IngressPolicy.AllowedIngressCIDRs; strings.Join(got, ",") != "10.0.0.0/16" {
+		t.Errorf("ingress CIDRs = %v, want the overlay's 10.0.0.0/16", got)
+	}
+	if got := Tighten(ingress("10.0.0.0/16"), ingress()).NetworkSecurity.IngressPolicy.AllowedIngressCIDRs; strings.Join(got, ",") != "10.0.0.0/16" {
+		t.Errorf("ingress CIDRs = %v, want the base's 10.0.0.0/16", got)
+	}
+	if got := Tighten(ingress("10.0.0.0/16"), ingress("10.0.1.0/24", "192.168.0.0/24")).NetworkSecurity.IngressPolicy.AllowedIngressCIDRs; strings.Join(got, ",") != "10.0.1.0/24" {
+		t.Errorf("ingress CIDRs = %v, want 10.0.1.0/24", got)
+	}
+
+	// Disjoint lists and an empty overlay leave an empty list, which allows no ingress
+	for name, overlay := range map[string]*SecurityPolicies{"disjoint": ingress("192.168.0.0/24"), "empty": {NetworkSecurity: NetworkSecurity{IngressPolicy: IngressPolicy{AllowedIngressCIDRs: []string{}}}}} {
+		if got := Tighten(ingress("10.0.0.0/16"), overlay).NetworkSecurity.IngressPolicy.AllowedIngressCIDRs; got == nil || len(got) != 0 {
+			t.Errorf("%s: ingress CIDRs = %#v, want an empty list that allows nothing", name, got)
+		}
+	}
+}
+
+func TestCapabilityNames(t *testing.T) {
+	for _, name := range []string{"SYS_ADMIN", "CAP_SYS_ADMIN", "cap_sys_admin", " SYS_ADMIN "} {
+		if got := CapabilityName(name); got != "SYS_ADMIN" {
+			t.Errorf("CapabilityName(%q) = %q, want SYS_ADMIN", name, got)
+		}
+	}
+	if !HasCapability([]string{"CAP_NET_ADMIN"}, "NET_ADMIN") || !HasCapability([]string{"NET_ADMIN"}, "CAP_NET_ADMIN") {
+		t.Error("HasCapability does not match the two spellings")
+	}
+	if !HasCapability([]string{"ALL"}, "SYS_ADMIN") || HasCapability([]string{"NET_ADMIN"}, "SYS_ADMIN") {
+		t.Error("HasCapability does not treat ALL as every capability")
+	}
+}
+
+func TestTightenCapabilities(t *testing.T) {
+	enforcing := func(allowed ...string) *SecurityPolicies {
+		return &SecurityPolicies{Capabilities: Capabilities{AllowedCapabilities: allowed, EnforceAllowedCapabilities: true}}
+	}
+
+	tests := []struct {
+		name          string
+		base, overlay *SecurityPolicies
+		want          string
+	}{
+		{"both enforce", enforcing("CAP_NET_BIND_SERVICE", "CAP_CHOWN"), enforcing("NET_BIND_SERVICE"), "CAP_NET_BIND_SERVICE"},
+		{"both enforce, one allows nothing", enforcing("NET_BIND_SERVICE"), enforcing(), ""},
+		// The base list is not enforced, so the overlay's applies as it is
+		{"overlay enforces", &SecurityPolicies{Capabilities: Capabilities{AllowedCapabilities: []string{"CHOWN"}}}, enforcing("NET_BIND_SERVICE"), "NET_BIND_SERVICE"},
+		{"empty base, overlay enforces", &SecurityPolicies{}, enforcing("NET_BIND_SERVICE"), "NET_BIND_SERVICE"},
+		{"base enforces", enforcing("A"), &SecurityPolicies{Capabilities: Capabilities{AllowedCapabilities: []string{"B"}}}, "A"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			capabilities := Tighten(tt.base, tt.overlay).Capabilities
+			if got := strings.Join(capabilities.AllowedCapabilities, ","); got != tt.want {
+				t.Errorf("allowed capabilities = %q, want %q", got, tt.want)
+			}
+			if !capabilities.EnforceAllowedCapabilities {
+				t.Error("Tighten dropped enforceAllowedCapabilities")
+			}
+		})
+	}
+}
+
+func TestParseDenyAllIDs(t *testing.T) {
+	p, err := Parse([]byte("policies:\n  podSecurityContext:\n    runAsUser:\n      rule: MustRunAs\n      ranges: []\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rule := p.PodSecurityContext.RunAsUser; rule.Allows(1000) || rule.Allows(0) {
+		t.Errorf("%+v admits an ID", rule)
+	}
+}
@@ -0,0 +1,86 @@
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Profiles of the seccomp and AppArmor allowlists. A Localhost profile is written Localhost/<profile>;
+// a trailing * matches any suffix, e.g. Localhost/profiles/*
+const (
+	ProfileRuntimeDefault  = "RuntimeDefault"
+	ProfileUnconfined      = "Unconfined"
+	ProfileLocalhostPrefix = "Localhost/"
+)
+
+// SeccompPolicy restricts the seccomp profile of every container. A container without a profile runs
+// Unconfined.
+type SeccompPolicy struct {
+	AllowedProfiles []string `yaml:"allowedProfiles" json:"allowedProfiles,omitempty"` // Unset allows any profile
+}
+
+// AppArmorPolicy restricts the AppArmor profile of every container. A container without a profile runs
+// with RuntimeDefault.
+type AppArmorPolicy struct {
+	AllowedProfiles []string `yaml:"allowedProfiles" json:"allowedProfiles,omitempty"` // Unset allows any profile
+}
+
+// SELinuxPolicy restricts the SELinux options of every container. An unset type or level is left to the
+// container runtime and always allowed.
+type SELinuxPolicy struct {
+	AllowedTypes  []string `yaml:"allowedTypes" json:"allowedTypes,omitempty"`   // Unset allows any type
+	AllowedLevels []string `yaml:"allowedLevels" json:"allowedLevels,omitempty"` // Unset allows any level
+}
+
+// Allows reports whether profile, e.g. Localhost/profiles/audit.json, is allowed
+func (p *SeccompPolicy) Allows(profile string) bool {
+	return p.AllowedProfiles == nil || MatchesAny(p.AllowedProfiles, profile)
+}
+
+// Allows reports whether profile, e.g. Localhost/k8s-nginx, is allowed
+func (p *AppArmorPolicy) Allows(profile string) bool {
+	return p.AllowedProfiles == nil || MatchesAny(p.AllowedProfiles, profile)
+}
+
+// AllowsType reports whether the SELinux type is allowed
+func (p *SELinuxPolicy) AllowsType(seLinuxType string) bool {
+	return seLinuxType == "" || p.AllowedTypes == nil || MatchesAny(p.AllowedTypes, seLinuxType)
+}
+
+// AllowsLevel reports whether the SELinux level is allowed
+func (p *SELinuxPolicy) AllowsLevel(level string) bool {
+	return level == "" || p.AllowedLevels == nil || MatchesAny(p.AllowedLevels, level)
+}
+
+// validateProfiles checks that every entry is RuntimeDefault, Unconfined or Localhost/<profile>
+func validateProfiles(field string, profiles []string) error {
+	var errs []error
+	for i, profile := range profiles {
+		if profile == ProfileRuntimeDefault || profile == ProfileUnconfined {
+			continue
+		}
+		if name, ok := strings.CutPrefix(profile, ProfileLocalhostPrefix); !ok || name == "" {
+			errs = append(errs, fmt.Errorf("%s[%d]: %q is not a profile (expected RuntimeDefault, Unconfined or Localhost/<profile>)", field, i, profile))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// narrowerPattern compares patterns with an optional trailing *
+func narrowerPattern(a, b string) string {
+	switch {
+	case a == b:
+		return a
+	case strings.HasSuffix(a, "*") && MatchesAny([]string{a}, strings.TrimSuffix(b, "*")):
+		return b
+	case strings.HasSuffix(b, "*") && MatchesAny([]string{b}, strings.TrimSuffix(a, "*")):
+		return a
+	}
+	return ""
+}
+
+// tightenPatterns intersects two allowlists of patterns
+func tightenPatterns(base, overlay []string) []string {
+	return tightenAllowlist(base, overlay, narrowerPattern)
+}
@@ -0,0 +1,124 @@
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"sync"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/ext"
+)
+
+// CustomRule is a rule of the customRules section, written as a CEL expression over the admitted
+// object. The object is admitted when the expression evaluates to true; otherwise the rule reports
+// a violation with its message. Each rule is a check named after the rule, so enforcement,
+// execution, updates and exemptions refer to it by name like to any built-in check.
+type CustomRule struct {
+	Name       string   `yaml:"name" json:"name,omitempty"`
+	Kinds      []string `yaml:"kinds" json:"kinds,omitempty"`           // Kinds the rule applies to; empty matches every kind sent to /validate/custom
+	Expression string   `yaml:"expression" json:"expression,omitempty"` // CEL expression that must evaluate to true
+	Message    string   `yaml:"message" json:"message,omitempty"`       // Message of the violation when the expression is false
+	Mode       Mode     `yaml:"mode" json:"mode,omitempty"`             // Mode of the rule; an entry in enforcement.checks still overrides it
+}
+
+// ruleName is the form of a rule name, which is also the key of its audit annotation
+var ruleName = regexp.MustCompile(`^[a-z0-9._-]+$`)
+
+// reservedRuleNames are the audit annotation keys the webhook writes itself
+var reservedRuleNames = []string{"exemptions", "check_errors", "unfinished_checks"}
+
+// builtinChecks holds the names of the checks compiled into the webhook
+var builtinChecks sync.Map
+
+// ReserveCheckName keeps custom rules from taking the name of a built-in check
+func ReserveCheckName(name string) {
+	builtinChecks.Store(name, true)
+}
+
+// ruleCostLimit bounds the work a single evaluation of a rule may do
+const ruleCostLimit = 1000000
+
+// ruleEnv declares the variables a rule can read:
+//
+//	object           the admitted object
+//	oldObject        the object before an UPDATE, null otherwise
+//	request          the admission request without the objects (operation, userInfo, namespace, ...)
+//	namespaceLabels  the labels of the namespace of the request
+var ruleEnv = sync.OnceValues(func() (*cel.Env, error) {
+	return cel.NewEnv(
+		cel.Variable("object", cel.DynType),
+		cel.Variable("oldObject", cel.DynType),
+		cel.Variable("request", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable("namespaceLabels", cel.MapType(cel.StringType, cel.StringType)),
+		cel.OptionalTypes(),
+		ext.Strings(),
+	)
+})
+
+// rulePrograms caches the compiled programs by expression. Programs are immutable and safe
+// for concurrent use, so every snapshot of the policies containing a rule shares its program.
+var rulePrograms sync.Map
+
+// Program returns the compiled program of the rule's expression
+func (r *CustomRule) Program() (cel.Program, error) {
+	if program, ok := rulePrograms.Load(r.Expression); ok {
+		return program.(cel.Program), nil
+	}
+	env, err := ruleEnv()
+	if err != nil {
+		return nil, err
+	}
+	ast, issues := env.Compile(r.Expression)
+	if issues.Err() != nil {
+		return nil, issues.Err()
+	}
+	if !ast.OutputType().IsExactType(cel.BoolType) && !ast.OutputType().IsExactType(cel.DynType) {
+		return nil, fmt.Errorf("expression evaluates to %s, not bool", ast.OutputType())
+	}
+	program, err := env.Program(ast, cel.CostLimit(ruleCostLimit), cel.InterruptCheckFrequency(100))
+	if err != nil {
+		return nil, err
+	}
+	rulePrograms.Store(r.Expression, program)
+	return program, nil
+}
+
+// ValidateCustomRules checks that every rule has a unique name that no built-in check or audit
+// annotation uses, a message and a known mode, and that its expression compiles and type-checks
+func ValidateCustomRules(rules []CustomRule) error {
+	var errs []error
+	names := make(map[string]bool)
+	for i := range rules {
+		rule := &rules[i]
+		field := fmt.Sprintf("customRules[%d]", i)
+		_, builtin := builtinChecks.Load(rule.Name)
+		switch {
+		case rule.Name == "":
+			errs = append(errs, fmt.Errorf("%s.name is required", field))
+		case !ruleName.MatchString(rule.Name):
+			errs = append(errs, fmt.Errorf("%s.name: %q may only contain lowercase letters, digits, '.', '_' and '-'", field, rule.Name))
+		case slices.Contains(reservedRuleNames, rule.Name):
+			errs = append(errs, fmt.Errorf("%s.name: %q is reserved", field, rule.Name))
+		case builtin:
+			errs = append(errs, fmt.Errorf("%s.name: %q is the name of a built-in check", field, rule.Name))
+		case names[rule.Name]:
+			errs = append(errs, fmt.Errorf("%s.name: duplicate rule %q", field, rule.Name))
+		}
+		names[rule.Name] = true
+
+		if rule.Message == "" {
+			errs = append(errs, fmt.Errorf("%s.message is required", field))
+		}
+		if _, err := ParseMode(string(rule.Mode)); err != nil {
+			errs = append(errs, fmt.Errorf("%s.mode: %w", field, err))
+		}
+		if rule.Expression == "" {
+			errs = append(errs, fmt.Errorf("%s.expression is required", field))
+		} else if _, err := rule.Program(); err != nil {
+			errs = append(errs, fmt.Errorf("%s.expression: %w", field, err))
+		}
+	}
+	return errors.Join(errs...)
+}
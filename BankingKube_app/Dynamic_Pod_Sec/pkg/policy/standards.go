@@ -0,0 +1,92 @@
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// Profile is a level of the Kubernetes Pod Security Standards
+type Profile string
+
+const (
+	// ProfilePrivileged places no restrictions on pods (default)
+	ProfilePrivileged Profile = "privileged"
+	// ProfileBaseline prevents known privilege escalations
+	ProfileBaseline Profile = "baseline"
+	// ProfileRestricted enforces current pod hardening best practices on top of baseline
+	ProfileRestricted Profile = "restricted"
+)
+
+// profileStrictness orders the profiles from the least to the most strict; unset is privileged
+var profileStrictness = map[Profile]int{"": 0, ProfilePrivileged: 0, ProfileBaseline: 1, ProfileRestricted: 2}
+
+// Includes reports whether the controls of level are part of profile p; restricted includes baseline
+func (p Profile) Includes(level Profile) bool {
+	return profileStrictness[p] >= profileStrictness[level]
+}
+
+// stricterProfile returns the stricter of two profiles
+func stricterProfile(a, b Profile) Profile {
+	if profileStrictness[b] > profileStrictness[a] {
+		return b
+	}
+	return a
+}
+
+// PodSecurityStandards selects the Pod Security Standards profile the pss checks enforce. The
+// policies of the other sections apply on top of it.
+type PodSecurityStandards struct {
+	Profile    Profile            `yaml:"profile" json:"profile,omitempty"`       // Profile of every namespace without an entry in namespaces
+	Namespaces map[string]Profile `yaml:"namespaces" json:"namespaces,omitempty"` // Profile per namespace
+}
+
+// ProfileFor returns the profile of the named namespace
+func (s *PodSecurityStandards) ProfileFor(namespace string) Profile {
+	if s == nil {
+		return ProfilePrivileged
+	}
+	profile, ok := s.Namespaces[namespace]
+	if !ok {
+		profile = s.Profile
+	}
+	if profile == "" {
+		return ProfilePrivileged
+	}
+	return profile
+}
+
+// Validate checks that every profile is known
+func (s *PodSecurityStandards) Validate() error {
+	var errs []error
+	check := func(field string, profile Profile) {
+		if _, ok := profileStrictness[profile]; !ok {
+			errs = append(errs, fmt.Errorf("%s: unknown profile %q (expected privileged, baseline or restricted)", field, profile))
+		}
+	}
+	check("podSecurityStandards.profile", s.Profile)
+	namespaces := make([]string, 0, len(s.Namespaces))
+	for namespace := range s.Namespaces {
+		namespaces = append(namespaces, namespace)
+	}
+	sort.Strings(namespaces)
+	for _, namespace := range namespaces {
+		check("podSecurityStandards.namespaces."+namespace, s.Namespaces[namespace])
+	}
+	return errors.Join(errs...)
+}
+
+// tightenStandards gives every namespace the stricter of its two profiles
+func tightenStandards(base, overlay *PodSecurityStandards) PodSecurityStandards {
+	out := PodSecurityStandards{Profile: stricterProfile(base.Profile, overlay.Profile)}
+	if len(base.Namespaces)+len(overlay.Namespaces) == 0 {
+		return out
+	}
+	out.Namespaces = make(map[string]Profile)
+	for _, namespaces := range []map[string]Profile{base.Namespaces, overlay.Namespaces} {
+		for namespace := range namespaces {
+			out.Namespaces[namespace] = stricterProfile(base.ProfileFor(namespace), overlay.ProfileFor(namespace))
+		}
+	}
+	return out
+}
@@ -0,0 +1,164 @@
+package policy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// reloadDebounce groups the burst of events a single ConfigMap update or editor save produces
+const reloadDebounce = 100 * time.Millisecond
+
+var (
+	storeMeter    = otel.Meter("bankingkube/dynamicpodsec")
+	policyReloads metric.Int64Counter
+)
+
+func init() {
+	var err error
+	policyReloads, err = storeMeter.Int64Counter("policy.reloads")
+	if err != nil {
+		log.Println("Failed to create metric: policy.reloads")
+	}
+}
+
+// Store holds the current policy snapshot in memory and swaps it atomically on reload.
+// Requests keep the snapshot they started with; a file that fails to parse or validate
+// leaves the last good snapshot in use.
+// +k8s:deepcopy-gen=false
+type Store struct {
+	path     string
+	mu       sync.Mutex // Serialises reloads
+	snapshot atomic.Pointer[SecurityPolicies]
+	content  []byte // Content of the current snapshot, guarded by mu
+	lastErr  atomic.Pointer[error]
+}
+
+// NewStore creates a store for the policy file at path. It holds no snapshot until the first Reload.
+func NewStore(path string) *Store {
+	return &Store{path: path}
+}
+
+// Path returns the policy file of the store
+func (s *Store) Path() string {
+	return s.path
+}
+
+// Load returns the current snapshot. It only fails when no version of the file has loaded yet.
+func (s *Store) Load() (*SecurityPolicies, error) {
+	if policies := s.snapshot.Load(); policies != nil {
+		return policies, nil
+	}
+	if err := s.lastErr.Load(); err != nil {
+		return nil, *err
+	}
+	return nil, fmt.Errorf("policy file %s has not been loaded", s.path)
+}
+
+// Reload reads the policy file and swaps in the new snapshot. On failure the previous snapshot stays
+// in use and the error is returned. An unchanged file is not parsed again.
+func (s *Store) Reload() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := os.ReadFile(s.path)
+	if err == nil && s.snapshot.Load() != nil && bytes.Equal(data, s.content) {
+		return nil
+	}
+	var policies *SecurityPolicies
+	if err == nil {
+		policies, err = Parse(data)
+	}
+	if err != nil {
+		err = fmt.Errorf("failed to load policy file %s: %w", s.path, err)
+		s.lastErr.Store(&err)
+		policyReloads.Add(context.Background(), 1, metric.WithAttributes(attribute.String("result", "failed")))
+		return err
+	}
+
+	s.snapshot.Store(policies)
+	s.content = data
+	s.lastErr.Store(nil)
+	policyReloads.Add(context.Background(), 1, metric.WithAttributes(attribute.String("result", "loaded")))
+	return nil
+}
+
+// Watch reloads the policy file whenever it changes until ctx is done. It watches the directory
+// rather than the file, because a ConfigMap volume updates by swapping the ..data symlink to a new
+// directory, which replaces the file without ever writing to it.
+func (s *Store) Watch(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	if err := watcher.Add(filepath.Dir(s.path)); err != nil {
+		return err
+	}
+
+	var debounce <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return errors.New("policy watcher closed")
+			}
+			// Chmod alone never changes the content
+			if event.Op == fsnotify.Chmod {
+				continue
+			}
+			debounce = time.After(reloadDebounce)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return errors.New("policy watcher closed")
+			}
+			log.Println("Policy watcher error:", err)
+		case <-debounce:
+			debounce = nil
+			if err := s.Reload(); err != nil {
+				log.Println("Keeping the last good policies:", err)
+				continue
+			}
+			log.Println("Policy file reloaded:", s.path)
+		}
+	}
+}
+
+var (
+	defaultStoresMu sync.Mutex
+	defaultStores   = map[string]*Store{}
+)
+
+// DefaultStore returns the store of the policy file configured through SECURITY_POLICIES_PATH,
+// loading it on first use. Stores are kept per path, so changing SECURITY_POLICIES_PATH switches to
+// the store of the new file rather than serving the old one.
+func DefaultStore() *Store {
+	path := Path()
+
+	defaultStoresMu.Lock()
+	defer defaultStoresMu.Unlock()
+	store, ok := defaultStores[path]
+	if !ok {
+		store = NewStore(path)
+		if err := store.Reload(); err != nil {
+			log.Println(err)
+		}
+		defaultStores[path] = store
+	}
+	return store
+}
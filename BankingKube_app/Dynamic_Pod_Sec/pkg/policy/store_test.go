@@ -0,0 +1,143 @@
+package policy
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const (
+	enforcingPolicies = "policies:\n  enforcement:\n    defaultMode: enforce\n"
+	warningPolicies   = "policies:\n  enforcement:\n    defaultMode: warn\n"
+	invalidPolicies   = "policies:\n  enforcement:\n    defaultMode: block\n"
+)
+
+func TestStoreKeepsLastGoodSnapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "security-policies.yaml")
+	store := NewStore(path)
+
+	if _, err := store.Load(); err == nil {
+		t.Fatal("Load succeeded before the first reload")
+	}
+	if err := store.Reload(); err == nil {
+		t.Fatal("Reload succeeded without a policy file")
+	}
+
+	writeFile(t, path, enforcingPolicies)
+	if err := store.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	first, _ := store.Load()
+
+	writeFile(t, path, invalidPolicies)
+	if err := store.Reload(); err == nil {
+		t.Fatal("Reload accepted an invalid policy file")
+	}
+	if current, err := store.Load(); err != nil || current != first {
+		t.Errorf("Load = %p, %v after a bad reload; want the last good snapshot %p", current, err, first)
+	}
+
+	writeFile(t, path, warningPolicies)
+	if err := store.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if current, _ := store.Load(); current.Enforcement.DefaultMode != ModeWarn {
+		t.Errorf("defaultMode = %q after reload, want warn", current.Enforcement.DefaultMode)
+	}
+}
+
+func TestDefaultStoreFollowsPath(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "enforcing.yaml"), enforcingPolicies)
+	writeFile(t, filepath.Join(dir, "warning.yaml"), warningPolicies)
+
+	t.Setenv("SECURITY_POLICIES_PATH", filepath.Join(dir, "enforcing.yaml"))
+	if policies, err := LoadDefault(); err != nil || policies.Enforcement.DefaultMode != ModeEnforce {
+		t.Fatalf("LoadDefault = %+v, %v; want the enforcing file", policies, err)
+	}
+
+	t.Setenv("SECURITY_POLICIES_PATH", filepath.Join(dir, "warning.yaml"))
+	if policies, err := LoadDefault(); err != nil || policies.Enforcement.DefaultMode != ModeWarn {
+		t.Fatalf("LoadDefault = %+v, %v after changing SECURITY_POLICIES_PATH; want the warning file", policies, err)
+	}
+	if DefaultStore().Path() != filepath.Join(dir, "warning.yaml") {
+		t.Errorf("DefaultStore path = %s, want the warning file", DefaultStore().Path())
+	}
+}
+
+// TestStoreWatchConfigMapSwap replays how the kubelet updates a ConfigMap volume: the file is a
+// symlink through ..data, and an update points ..data at a new timestamped directory
+func TestStoreWatchConfigMapSwap(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "..2026_01_01_00_00_00.1", "security-policies.yaml"), enforcingPolicies)
+	symlink(t, "..2026_01_01_00_00_00.1", filepath.Join(dir, "..data"))
+	symlink(t, filepath.Join("..data", "security-policies.yaml"), filepath.Join(dir, "security-policies.yaml"))
+
+	store := NewStore(filepath.Join(dir, "security-policies.yaml"))
+	if err := store.Reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	watching := make(chan error, 1)
+	go func() { watching <- store.Watch(ctx) }()
+	time.Sleep(50 * time.Millisecond) // Let the watcher subscribe
+
+	swap := func(version, content string) {
+		writeFile(t, filepath.Join(dir, version, "security-policies.yaml"), content)
+		symlink(t, version, filepath.Join(dir, "..data_tmp"))
+		if err := os.Rename(filepath.Join(dir, "..data_tmp"), filepath.Join(dir, "..data")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	swap("..2026_01_02_00_00_00.2", warningPolicies)
+	waitForMode(t, store, ModeWarn)
+
+	swap("..2026_01_03_00_00_00.3", invalidPolicies)
+	time.Sleep(4 * reloadDebounce)
+	waitForMode(t, store, ModeWarn)
+
+	swap("..2026_01_04_00_00_00.4", enforcingPolicies)
+	waitForMode(t, store, ModeEnforce)
+
+	cancel()
+	if err := <-watching; err != nil {
+		t.Errorf("Watch returned %v", err)
+	}
+}
+
+func waitForMode(t *testing.T, store *Store, want Mode) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		policies, err := store.Load()
+		if err == nil && policies.Enforcement.DefaultMode == want {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("defaultMode = %q, want %q", policies.Enforcement.DefaultMode, want)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func symlink(t *testing.T, target, link string) {
+	t.Helper()
+	if err := os.Symlink(target, link); err != nil {
+		t.Fatal(err)
+	}
+}
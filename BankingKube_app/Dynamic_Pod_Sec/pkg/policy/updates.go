@@ -0,0 +1,25 @@
+package policy
+
+// Updates controls how UPDATE requests are evaluated. By default a check only reports the
+// violations in fields the update changes, so an object admitted before a policy change can
+// still be relabelled or scaled; recheckAll evaluates the whole object again.
+type Updates struct {
+	RecheckAll bool                    `yaml:"recheckAll" json:"recheckAll,omitempty"` // Recheck every field of updated objects
+	Checks     map[string]CheckUpdates `yaml:"checks" json:"checks,omitempty"`         // Per check overrides, keyed by check name
+}
+
+// CheckUpdates overrides the update settings of a single check
+type CheckUpdates struct {
+	RecheckAll *bool `yaml:"recheckAll" json:"recheckAll,omitempty"`
+}
+
+// RecheckAllFor reports whether the named check evaluates every field of an updated object
+func (u *Updates) RecheckAllFor(name string) bool {
+	if u == nil {
+		return false
+	}
+	if c, ok := u.Checks[name]; ok && c.RecheckAll != nil {
+		return *c.RecheckAll
+	}
+	return u.RecheckAll
+}
@@ -0,0 +1,81 @@
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"net"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// Validate checks the values the schema cannot express: modes, failure policies, profiles and ID rules are known,
+// seccomp and AppArmor profiles, CIDRs and resource quantities parse, every range has min <= max,
+// exemptions are complete and the expressions of custom rules compile.
+// It reports every problem it finds, one per line.
+func (p *SecurityPolicies) Validate() error {
+	var errs []error
+	add := func(err error) {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	add(p.Enforcement.Validate())
+	add(p.Execution.Validate())
+	add(ValidateExemptions(p.Exemptions))
+	add(ValidateCustomRules(p.CustomRules))
+	add(p.PodSecurityStandards.Validate())
+
+	psc := &p.PodSecurityContext
+	add(psc.RunAsUser.Validate("podSecurityContext.runAsUser"))
+	add(psc.RunAsGroup.Validate("podSecurityContext.runAsGroup"))
+	add(psc.FSGroup.Validate("podSecurityContext.fsGroup"))
+	add(psc.SupplementalGroups.Validate("podSecurityContext.supplementalGroups"))
+	add(validateProfiles("podSecurityContext.seccomp.allowedProfiles", psc.Seccomp.AllowedProfiles))
+	add(validateProfiles("podSecurityContext.appArmor.allowedProfiles", psc.AppArmor.AllowedProfiles))
+
+	network := &p.NetworkSecurity
+	add(validateCIDRs("NetworkSecurity.egressPolicy.allowedEgressCIDRs", network.EgressPolicy.AllowedEgressCIDRs))
+	add(validateCIDRs("NetworkSecurity.ingressPolicy.allowedIngressCIDRs", network.IngressPolicy.AllowedIngressCIDRs))
+	consistency := &network.ConsistencyPolicy
+	add(validateCIDRs("NetworkSecurity.consistencyPolicy.accessEgressPolicy.allowedEgressCIDRs", consistency.AccessEgressPolicy.AllowedEgressCIDRs))
+	add(validateCIDRs("NetworkSecurity.consistencyPolicy.accessIngressPolicy.allowedIngressCIDRs", consistency.AccessIngressPolicy.AllowedIngressCIDRs))
+	add(validateCIDRs("NetworkSecurity.consistencyPolicy.allowedOverlappingEgressCIDRs", consistency.AllowedOverlappingEgressCIDRs))
+	add(validateCIDRs("NetworkSecurity.consistencyPolicy.allowedOverlappingIngressCIDRs", consistency.AllowedOverlappingIngressCIDRs))
+
+	add(p.ResourceLimits.CPULimits.Validate("resourceLimits.cpuLimits"))
+	add(p.ResourceLimits.MemoryLimits.Validate("resourceLimits.memoryLimits"))
+
+	return errors.Join(errs...)
+}
+
+// Validate checks that both ends of the range parse as quantities and that min <= max.
+// Either end may be left empty.
+func (r Range) Validate(field string) error {
+	var min, max resource.Quantity
+	var err error
+	if r.Min != "" {
+		if min, err = resource.ParseQuantity(r.Min); err != nil {
+			return fmt.Errorf("%s.min: %q is not a quantity", field, r.Min)
+		}
+	}
+	if r.Max != "" {
+		if max, err = resource.ParseQuantity(r.Max); err != nil {
+			return fmt.Errorf("%s.max: %q is not a quantity", field, r.Max)
+		}
+	}
+	if r.Min != "" && r.Max != "" && min.Cmp(max) > 0 {
+		return fmt.Errorf("%s: min %s is greater than max %s", field, r.Min, r.Max)
+	}
+	return nil
+}
+
+func validateCIDRs(field string, cidrs []string) error {
+	var errs []error
+	for i, cidr := range cidrs {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			errs = append(errs, fmt.Errorf("%s[%d]: %q is not a CIDR", field, i, cidr))
+		}
+	}
+	return errors.Join(errs...)
+}
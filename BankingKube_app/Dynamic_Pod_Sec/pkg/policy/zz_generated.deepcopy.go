@@ -0,0 +1,743 @@
+//go:build !ignore_autogenerated
+// +build !ignore_autogenerated
+
+// Code generated by deepcopy-gen. DO NOT EDIT.
+
+package policy
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *APIRestrictions) DeepCopyInto(out *APIRestrictions) {
+	*out = *in
+	if in.RestrictedAPIPaths != nil {
+		in, out := &in.RestrictedAPIPaths, &out.RestrictedAPIPaths
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new APIRestrictions.
+func (in *APIRestrictions) DeepCopy() *APIRestrictions {
+	if in == nil {
+		return nil
+	}
+	out := new(APIRestrictions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AppArmorPolicy) DeepCopyInto(out *AppArmorPolicy) {
+	*out = *in
+	if in.AllowedProfiles != nil {
+		in, out := &in.AllowedProfiles, &out.AllowedProfiles
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AppArmorPolicy.
+func (in *AppArmorPolicy) DeepCopy() *AppArmorPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(AppArmorPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Capabilities) DeepCopyInto(out *Capabilities) {
+	*out = *in
+	if in.AllowedCapabilities != nil {
+		in, out := &in.AllowedCapabilities, &out.AllowedCapabilities
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.DisallowedCapabilities != nil {
+		in, out := &in.DisallowedCapabilities, &out.DisallowedCapabilities
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.RequiredDrops != nil {
+		in, out := &in.RequiredDrops, &out.RequiredDrops
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Capabilities.
+func (in *Capabilities) DeepCopy() *Capabilities {
+	if in == nil {
+		return nil
+	}
+	out := new(Capabilities)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CheckExecution) DeepCopyInto(out *CheckExecution) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CheckExecution.
+func (in *CheckExecution) DeepCopy() *CheckExecution {
+	if in == nil {
+		return nil
+	}
+	out := new(CheckExecution)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CheckRoleBindingsPolicy) DeepCopyInto(out *CheckRoleBindingsPolicy) {
+	*out = *in
+	if in.RestrictedClusterRoles != nil {
+		in, out := &in.RestrictedClusterRoles, &out.RestrictedClusterRoles
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.RestrictedRoleBindings != nil {
+		in, out := &in.RestrictedRoleBindings, &out.RestrictedRoleBindings
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CheckRoleBindingsPolicy.
+func (in *CheckRoleBindingsPolicy) DeepCopy() *CheckRoleBindingsPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(CheckRoleBindingsPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CheckUpdates) DeepCopyInto(out *CheckUpdates) {
+	*out = *in
+	if in.RecheckAll != nil {
+		in, out := &in.RecheckAll, &out.RecheckAll
+		*out = new(bool)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CheckUpdates.
+func (in *CheckUpdates) DeepCopy() *CheckUpdates {
+	if in == nil {
+		return nil
+	}
+	out := new(CheckUpdates)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConsistencyPolicy) DeepCopyInto(out *ConsistencyPolicy) {
+	*out = *in
+	in.AccessEgressPolicy.DeepCopyInto(&out.AccessEgressPolicy)
+	in.AccessIngressPolicy.DeepCopyInto(&out.AccessIngressPolicy)
+	if in.AllowedOverlappingEgressCIDRs != nil {
+		in, out := &in.AllowedOverlappingEgressCIDRs, &out.AllowedOverlappingEgressCIDRs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AllowedOverlappingIngressCIDRs != nil {
+		in, out := &in.AllowedOverlappingIngressCIDRs, &out.AllowedOverlappingIngressCIDRs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConsistencyPolicy.
+func (in *ConsistencyPolicy) DeepCopy() *ConsistencyPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(ConsistencyPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CustomRule) DeepCopyInto(out *CustomRule) {
+	*out = *in
+	if in.Kinds != nil {
+		in, out := &in.Kinds, &out.Kinds
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CustomRule.
+func (in *CustomRule) DeepCopy() *CustomRule {
+	if in == nil {
+		return nil
+	}
+	out := new(CustomRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EgressPolicy) DeepCopyInto(out *EgressPolicy) {
+	*out = *in
+	if in.AllowedEgressCIDRs != nil {
+		in, out := &in.AllowedEgressCIDRs, &out.AllowedEgressCIDRs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EgressPolicy.
+func (in *EgressPolicy) DeepCopy() *EgressPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(EgressPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Enforcement) DeepCopyInto(out *Enforcement) {
+	*out = *in
+	if in.Checks != nil {
+		in, out := &in.Checks, &out.Checks
+		*out = make(map[string]Mode, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Enforcement.
+func (in *Enforcement) DeepCopy() *Enforcement {
+	if in == nil {
+		return nil
+	}
+	out := new(Enforcement)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Execution) DeepCopyInto(out *Execution) {
+	*out = *in
+	if in.Checks != nil {
+		in, out := &in.Checks, &out.Checks
+		*out = make(map[string]CheckExecution, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Execution.
+func (in *Execution) DeepCopy() *Execution {
+	if in == nil {
+		return nil
+	}
+	out := new(Execution)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Exemption) DeepCopyInto(out *Exemption) {
+	*out = *in
+	if in.Checks != nil {
+		in, out := &in.Checks, &out.Checks
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.ServiceAccounts != nil {
+		in, out := &in.ServiceAccounts, &out.ServiceAccounts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Users != nil {
+		in, out := &in.Users, &out.Users
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Groups != nil {
+		in, out := &in.Groups, &out.Groups
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Images != nil {
+		in, out := &in.Images, &out.Images
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.Expires.DeepCopyInto(&out.Expires)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Exemption.
+func (in *Exemption) DeepCopy() *Exemption {
+	if in == nil {
+		return nil
+	}
+	out := new(Exemption)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HostNetworkPolicy) DeepCopyInto(out *HostNetworkPolicy) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HostNetworkPolicy.
+func (in *HostNetworkPolicy) DeepCopy() *HostNetworkPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(HostNetworkPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IDPolicy) DeepCopyInto(out *IDPolicy) {
+	*out = *in
+	if in.Ranges != nil {
+		in, out := &in.Ranges, &out.Ranges
+		*out = make([]IDRange, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IDPolicy.
+func (in *IDPolicy) DeepCopy() *IDPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(IDPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IDRange) DeepCopyInto(out *IDRange) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IDRange.
+func (in *IDRange) DeepCopy() *IDRange {
+	if in == nil {
+		return nil
+	}
+	out := new(IDRange)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageSecurity) DeepCopyInto(out *ImageSecurity) {
+	*out = *in
+	if in.AllowedRegistries != nil {
+		in, out := &in.AllowedRegistries, &out.AllowedRegistries
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.DisallowedTags != nil {
+		in, out := &in.DisallowedTags, &out.DisallowedTags
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageSecurity.
+func (in *ImageSecurity) DeepCopy() *ImageSecurity {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageSecurity)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IngressPolicy) DeepCopyInto(out *IngressPolicy) {
+	*out = *in
+	if in.AllowedIngressCIDRs != nil {
+		in, out := &in.AllowedIngressCIDRs, &out.AllowedIngressCIDRs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IngressPolicy.
+func (in *IngressPolicy) DeepCopy() *IngressPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(IngressPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NetworkPolicy) DeepCopyInto(out *NetworkPolicy) {
+	*out = *in
+	if in.RequiredNetworkPolicies != nil {
+		in, out := &in.RequiredNetworkPolicies, &out.RequiredNetworkPolicies
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NetworkPolicy.
+func (in *NetworkPolicy) DeepCopy() *NetworkPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(NetworkPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NetworkSecurity) DeepCopyInto(out *NetworkSecurity) {
+	*out = *in
+	out.HostNetworkPolicy = in.HostNetworkPolicy
+	in.NetworkPolicy.DeepCopyInto(&out.NetworkPolicy)
+	in.EgressPolicy.DeepCopyInto(&out.EgressPolicy)
+	in.IngressPolicy.DeepCopyInto(&out.IngressPolicy)
+	in.ConsistencyPolicy.DeepCopyInto(&out.ConsistencyPolicy)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NetworkSecurity.
+func (in *NetworkSecurity) DeepCopy() *NetworkSecurity {
+	if in == nil {
+		return nil
+	}
+	out := new(NetworkSecurity)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PermissionLevelsPolicy) DeepCopyInto(out *PermissionLevelsPolicy) {
+	*out = *in
+	if in.RestrictedVerbs != nil {
+		in, out := &in.RestrictedVerbs, &out.RestrictedVerbs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.RestrictedResources != nil {
+		in, out := &in.RestrictedResources, &out.RestrictedResources
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PermissionLevelsPolicy.
+func (in *PermissionLevelsPolicy) DeepCopy() *PermissionLevelsPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(PermissionLevelsPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PodSecurityContext) DeepCopyInto(out *PodSecurityContext) {
+	*out = *in
+	in.RunAsUser.DeepCopyInto(&out.RunAsUser)
+	in.RunAsGroup.DeepCopyInto(&out.RunAsGroup)
+	in.FSGroup.DeepCopyInto(&out.FSGroup)
+	in.SupplementalGroups.DeepCopyInto(&out.SupplementalGroups)
+	in.Seccomp.DeepCopyInto(&out.Seccomp)
+	in.AppArmor.DeepCopyInto(&out.AppArmor)
+	in.SELinux.DeepCopyInto(&out.SELinux)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PodSecurityContext.
+func (in *PodSecurityContext) DeepCopy() *PodSecurityContext {
+	if in == nil {
+		return nil
+	}
+	out := new(PodSecurityContext)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PodSecurityStandards) DeepCopyInto(out *PodSecurityStandards) {
+	*out = *in
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make(map[string]Profile, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PodSecurityStandards.
+func (in *PodSecurityStandards) DeepCopy() *PodSecurityStandards {
+	if in == nil {
+		return nil
+	}
+	out := new(PodSecurityStandards)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Range) DeepCopyInto(out *Range) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Range.
+func (in *Range) DeepCopy() *Range {
+	if in == nil {
+		return nil
+	}
+	out := new(Range)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResourceLimits) DeepCopyInto(out *ResourceLimits) {
+	*out = *in
+	out.CPULimits = in.CPULimits
+	out.MemoryLimits = in.MemoryLimits
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceLimits.
+func (in *ResourceLimits) DeepCopy() *ResourceLimits {
+	if in == nil {
+		return nil
+	}
+	out := new(ResourceLimits)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RoleScopePolicy) DeepCopyInto(out *RoleScopePolicy) {
+	*out = *in
+	if in.RestrictedNamespaces != nil {
+		in, out := &in.RestrictedNamespaces, &out.RestrictedNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RoleScopePolicy.
+func (in *RoleScopePolicy) DeepCopy() *RoleScopePolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(RoleScopePolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SELinuxPolicy) DeepCopyInto(out *SELinuxPolicy) {
+	*out = *in
+	if in.AllowedTypes != nil {
+		in, out := &in.AllowedTypes, &out.AllowedTypes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AllowedLevels != nil {
+		in, out := &in.AllowedLevels, &out.AllowedLevels
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SELinuxPolicy.
+func (in *SELinuxPolicy) DeepCopy() *SELinuxPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(SELinuxPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SeccompPolicy) DeepCopyInto(out *SeccompPolicy) {
+	*out = *in
+	if in.AllowedProfiles != nil {
+		in, out := &in.AllowedProfiles, &out.AllowedProfiles
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SeccompPolicy.
+func (in *SeccompPolicy) DeepCopy() *SeccompPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(SeccompPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecurityPolicies) DeepCopyInto(out *SecurityPolicies) {
+	*out = *in
+	in.Enforcement.DeepCopyInto(&out.Enforcement)
+	in.Execution.DeepCopyInto(&out.Execution)
+	in.Updates.DeepCopyInto(&out.Updates)
+	if in.Exemptions != nil {
+		in, out := &in.Exemptions, &out.Exemptions
+		*out = make([]Exemption, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.CustomRules != nil {
+		in, out := &in.CustomRules, &out.CustomRules
+		*out = make([]CustomRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	in.APIRestrictions.DeepCopyInto(&out.APIRestrictions)
+	in.ServiceAccountRestrictions.DeepCopyInto(&out.ServiceAccountRestrictions)
+	in.PodSecurityStandards.DeepCopyInto(&out.PodSecurityStandards)
+	in.PodSecurityContext.DeepCopyInto(&out.PodSecurityContext)
+	in.Capabilities.DeepCopyInto(&out.Capabilities)
+	in.ImageSecurity.DeepCopyInto(&out.ImageSecurity)
+	in.NetworkSecurity.DeepCopyInto(&out.NetworkSecurity)
+	in.CheckRoleBindingsPolicy.DeepCopyInto(&out.CheckRoleBindingsPolicy)
+	in.PermissionLevelsPolicy.DeepCopyInto(&out.PermissionLevelsPolicy)
+	in.RoleScopePolicy.DeepCopyInto(&out.RoleScopePolicy)
+	out.ResourceLimits = in.ResourceLimits
+	in.VolumeSecurity.DeepCopyInto(&out.VolumeSecurity)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecurityPolicies.
+func (in *SecurityPolicies) DeepCopy() *SecurityPolicies {
+	if in == nil {
+		return nil
+	}
+	out := new(SecurityPolicies)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServiceAccountRestrictions) DeepCopyInto(out *ServiceAccountRestrictions) {
+	*out = *in
+	if in.RestrictedServiceAccounts != nil {
+		in, out := &in.RestrictedServiceAccounts, &out.RestrictedServiceAccounts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServiceAccountRestrictions.
+func (in *ServiceAccountRestrictions) DeepCopy() *ServiceAccountRestrictions {
+	if in == nil {
+		return nil
+	}
+	out := new(ServiceAccountRestrictions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Updates) DeepCopyInto(out *Updates) {
+	*out = *in
+	if in.Checks != nil {
+		in, out := &in.Checks, &out.Checks
+		*out = make(map[string]CheckUpdates, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Updates.
+func (in *Updates) DeepCopy() *Updates {
+	if in == nil {
+		return nil
+	}
+	out := new(Updates)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VolumeSecurity) DeepCopyInto(out *VolumeSecurity) {
+	*out = *in
+	if in.DisallowedHostPaths != nil {
+		in, out := &in.DisallowedHostPaths, &out.DisallowedHostPaths
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.RestrictedStorageClasses != nil {
+		in, out := &in.RestrictedStorageClasses, &out.RestrictedStorageClasses
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VolumeSecurity.
+func (in *VolumeSecurity) DeepCopy() *VolumeSecurity {
+	if in == nil {
+		return nil
+	}
+	out := new(VolumeSecurity)
+	in.DeepCopyInto(out)
+	return out
+}
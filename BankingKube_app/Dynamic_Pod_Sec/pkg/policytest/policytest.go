@@ -0,0 +1,263 @@
+// Package policytest runs fixture admission requests through the webhook handlers against a policy
+// file and compares the outcome with what each test case expects, so a policy change can be tested
+// before it reaches a cluster.
+//
+// A test case is a YAML file named *.case.yaml:
+//
+//	name: privileged pod is denied
+//	input: pods/privileged.yaml          # an AdmissionReview or a manifest, relative to the case file
+//	policy: ../configs/security-policies.yaml
+//	groups: [context]                    # the check groups to run; all when left out
+//	expect:
+//	  allowed: false
+//	  violations: [pod_security_context] # IDs of the checks that deny the request
+//	  warnings: []                       # IDs of the checks that only warn
+//
+// A manifest is wrapped into a CREATE request in namespace (default "default") unless operation says
+// otherwise. Violations and warnings are compared as sets and only when the case lists them.
+package policytest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/offline"
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/policy"
+	"gopkg.in/yaml.v2"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	sigsyaml "sigs.k8s.io/yaml"
+)
+
+// CaseSuffix is the file name suffix of test cases; other files of the directory are fixtures
+const CaseSuffix = ".case.yaml"
+
+// Case is a single test case
+type Case struct {
+	Name            string            `yaml:"name"`
+	Input           string            `yaml:"input"`
+	Policy          string            `yaml:"policy"`
+	Groups          []string          `yaml:"groups"`
+	Namespace       string            `yaml:"namespace"`
+	Operation       string            `yaml:"operation"`
+	NamespaceLabels map[string]string `yaml:"namespaceLabels"`
+	Expect          Expectation       `yaml:"expect"`
+
+	// Path is the case file; Input and Policy are relative to its directory
+	Path string `yaml:"-"`
+}
+
+// Expectation is the outcome a case expects
+type Expectation struct {
+	Allowed    *bool    `yaml:"allowed"`
+	Violations []string `yaml:"violations"`
+	Warnings   []string `yaml:"warnings"`
+}
+
+// Result is the outcome of a case
+type Result struct {
+	Case       *Case
+	Allowed    bool
+	Violations []string // IDs of the checks that denied the request
+	Warnings   []string // IDs of the checks that only warned
+	Message    string   // Status message of a denied request
+	Failures   []string // Every way the outcome differs from the expectation
+	Err        error    // Set when the case could not run
+}
+
+// Passed reports whether the case ran and the outcome matched the expectation
+func (r *Result) Passed() bool {
+	return r.Err == nil && len(r.Failures) == 0
+}
+
+// Load reads every case file under dir, sorted by path
+func Load(dir string) ([]*Case, error) {
+	var cases []*Case
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || !strings.HasSuffix(entry.Name(), CaseSuffix) {
+			return err
+		}
+		c, err := LoadCase(path)
+		if err != nil {
+			return err
+		}
+		cases = append(cases, c)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(cases) == 0 {
+		return nil, fmt.Errorf("no %s files in %s", CaseSuffix, dir)
+	}
+	return cases, nil
+}
+
+// LoadCase reads a single case file. A case without a name is named after its file.
+func LoadCase(path string) (*Case, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c := &Case{}
+	if err := yaml.UnmarshalStrict(data, c); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	c.Path = path
+	if c.Name == "" {
+		c.Name = strings.TrimSuffix(filepath.Base(path), CaseSuffix)
+	}
+	switch {
+	case c.Input == "":
+		return nil, fmt.Errorf("%s: input is required", path)
+	case c.Policy == "":
+		return nil, fmt.Errorf("%s: policy is required", path)
+	case c.Expect.Allowed == nil:
+		return nil, fmt.Errorf("%s: expect.allowed is required", path)
+	}
+	return c, nil
+}
+
+// RunDir loads and runs every case under dir
+func RunDir(ctx context.Context, dir string) ([]*Result, error) {
+	cases, err := Load(dir)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]*Result, 0, len(cases))
+	for _, c := range cases {
+		results = append(results, Run(ctx, c))
+	}
+	return results, nil
+}
+
+// Run sends the input of the case through the webhook handlers of every group it selects, the way
+// the API server calls the webhook, and compares the combined outcome with the expectation
+func Run(ctx context.Context, c *Case) *Result {
+	result := &Result{Case: c}
+
+	policies, err := policy.Load(c.resolve(c.Policy))
+	if err != nil {
+		result.Err = fmt.Errorf("policy %s: %w", c.Policy, err)
+		return result
+	}
+	request, err := c.request()
+	if err != nil {
+		result.Err = fmt.Errorf("input %s: %w", c.Input, err)
+		return result
+	}
+	outcome, err := offline.Review(ctx, request, offline.Options{
+		Policies:        policies,
+		Groups:          c.Groups,
+		NamespaceLabels: c.NamespaceLabels,
+	})
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	result.Allowed = outcome.Allowed
+	for _, v := range outcome.Violations {
+		result.Violations = append(result.Violations, v.Check)
+	}
+	for _, w := range outcome.Warnings {
+		result.Warnings = append(result.Warnings, w.Check)
+	}
+	result.Violations = normalize(result.Violations)
+	result.Warnings = normalize(result.Warnings)
+	result.Message = strings.Join(outcome.Messages, "; ")
+	result.Failures = c.compare(result)
+	return result
+}
+
+// compare lists every way the result differs from the expectation of the case
+func (c *Case) compare(result *Result) []string {
+	var failures []string
+	if want := *c.Expect.Allowed; result.Allowed != want {
+		failures = append(failures, fmt.Sprintf("expected %s, got %s", outcome(want), outcome(result.Allowed)))
+	}
+	if c.Expect.Violations != nil {
+		if want := normalize(c.Expect.Violations); !sameIDs(want, result.Violations) {
+			failures = append(failures, fmt.Sprintf("expected violations %v, got %v", want, result.Violations))
+		}
+	}
+	if c.Expect.Warnings != nil {
+		if want := normalize(c.Expect.Warnings); !sameIDs(want, result.Warnings) {
+			failures = append(failures, fmt.Sprintf("expected warnings %v, got %v", want, result.Warnings))
+		}
+	}
+	return failures
+}
+
+// request reads the request of an AdmissionReview input, or wraps a manifest into one
+func (c *Case) request() (*admissionv1.AdmissionRequest, error) {
+	data, err := os.ReadFile(c.resolve(c.Input))
+	if err != nil {
+		return nil, err
+	}
+	object, err := sigsyaml.YAMLToJSON(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var meta metav1.TypeMeta
+	if err := json.Unmarshal(object, &meta); err != nil {
+		return nil, err
+	}
+	if meta.Kind == "AdmissionReview" {
+		review := &admissionv1.AdmissionReview{}
+		if err := json.Unmarshal(object, review); err != nil {
+			return nil, err
+		}
+		if review.Request == nil {
+			return nil, errors.New("AdmissionReview has no request")
+		}
+		return review.Request, nil
+	}
+	return offline.ManifestRequest(object, c.Namespace, admissionv1.Operation(c.Operation))
+}
+
+// normalize sorts and deduplicates IDs
+func normalize(ids []string) []string {
+	if len(ids) == 0 {
+		return nil
+	}
+	sorted := append([]string{}, ids...)
+	sort.Strings(sorted)
+	unique := sorted[:0]
+	for i, id := range sorted {
+		if i == 0 || id != sorted[i-1] {
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
+
+// sameIDs reports whether two normalized lists of IDs are equal
+func sameIDs(a, b []string) bool {
+	return len(a) == len(b) && (len(a) == 0 || reflect.DeepEqual(a, b))
+}
+
+// resolve returns path relative to the directory of the case file
+func (c *Case) resolve(path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(filepath.Dir(c.Path), path)
+}
+
+func outcome(allowed bool) string {
+	if allowed {
+		return "allowed"
+	}
+	return "denied"
+}
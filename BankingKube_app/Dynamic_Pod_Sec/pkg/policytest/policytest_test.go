@@ -0,0 +1,70 @@
+package policytest
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestShippedPolicies(t *testing.T) {
+	Test(t, "../../policytests")
+}
+
+func TestRunReportsMismatches(t *testing.T) {
+	dir := t.TempDir()
+	fixtures, err := filepath.Abs("../../policytests")
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := "input: " + filepath.Join(fixtures, "manifests/privileged-pod.yaml") + "\n" +
+		"policy: " + filepath.Join(fixtures, "policies/capabilities-warn.yaml") + "\n" +
+		"groups: [context]\n" +
+		"expect:\n  allowed: true\n  violations: []\n  warnings: []\n"
+	if err := os.WriteFile(filepath.Join(dir, "wrong.case.yaml"), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := RunDir(context.Background(), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := results[0]
+	if r.Err != nil {
+		t.Fatal(r.Err)
+	}
+	if r.Case.Name != "wrong" {
+		t.Errorf("name = %q, want it taken from the file name", r.Case.Name)
+	}
+	want := []string{
+		"expected allowed, got denied",
+		"expected violations [], got [pod_security_context]",
+		"expected warnings [], got [capabilities]",
+	}
+	if !reflect.DeepEqual(r.Failures, want) {
+		t.Errorf("failures = %q, want %q", r.Failures, want)
+	}
+
+	var out bytes.Buffer
+	if failed := PrintTable(&out, results); failed != 1 {
+		t.Errorf("PrintTable() = %d failed, want 1", failed)
+	}
+	for _, line := range append(want, "0 passed, 1 failed") {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("table does not contain %q:\n%s", line, out.String())
+		}
+	}
+}
+
+func TestLoadCaseRequiresExpectation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "incomplete.case.yaml")
+	if err := os.WriteFile(path, []byte("input: pod.yaml\npolicy: policies.yaml\nexpect:\n  violations: [host_path]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadCase(path); err == nil || !strings.Contains(err.Error(), "expect.allowed is required") {
+		t.Errorf("LoadCase() = %v, want expect.allowed reported", err)
+	}
+}
@@ -0,0 +1,79 @@
+package policytest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"text/tabwriter"
+)
+
+// PrintTable writes one row per result and then what went wrong with every case that failed.
+// It returns the number of failed cases.
+func PrintTable(w io.Writer, results []*Result) int {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "CASE\tEXPECTED\tGOT\tVIOLATIONS\tWARNINGS\tRESULT")
+	failed := 0
+	for _, r := range results {
+		status, got := "PASS", outcome(r.Allowed)
+		if r.Err != nil {
+			status, got = "ERROR", "-"
+		} else if !r.Passed() {
+			status = "FAIL"
+		}
+		if !r.Passed() {
+			failed++
+		}
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\t%s\n", r.Case.Name, outcome(*r.Case.Expect.Allowed), got, ids(r.Violations), ids(r.Warnings), status)
+	}
+	table.Flush()
+
+	for _, r := range results {
+		if r.Passed() {
+			continue
+		}
+		fmt.Fprintf(w, "\n%s (%s):\n", r.Case.Name, r.Case.Path)
+		if r.Err != nil {
+			fmt.Fprintf(w, "  %v\n", r.Err)
+			continue
+		}
+		for _, failure := range r.Failures {
+			fmt.Fprintf(w, "  %s\n", failure)
+		}
+	}
+	fmt.Fprintf(w, "\n%d passed, %d failed\n", len(results)-failed, failed)
+	return failed
+}
+
+// Test runs every case under dir as a subtest of t, so a repository can check its fixtures with go test:
+//
+//	func TestPolicies(t *testing.T) { policytest.Test(t, "testdata/policies") }
+func Test(t *testing.T, dir string) {
+	t.Helper()
+	cases, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			r := Run(context.Background(), c)
+			if r.Err != nil {
+				t.Fatalf("%s: %v", c.Path, r.Err)
+			}
+			for _, failure := range r.Failures {
+				t.Errorf("%s: %s", c.Path, failure)
+			}
+			if !r.Passed() && r.Message != "" {
+				t.Logf("webhook message: %s", r.Message)
+			}
+		})
+	}
+}
+
+func ids(list []string) string {
+	if len(list) == 0 {
+		return "-"
+	}
+	return strings.Join(list, ",")
+}
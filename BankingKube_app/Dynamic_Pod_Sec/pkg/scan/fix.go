@@ -0,0 +1,86 @@
+package scan
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission"
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/checks"
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/jsonpatch"
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/workload"
+	"gopkg.in/yaml.v3"
+)
+
+// Fix writes the defaults the mutating webhook injects (admission.ApplyBaselineSecurity) into the pod
+// templates of a stream of manifests. The YAML nodes are edited in place, so comments and key order
+// survive; only sequences may be indented differently. It returns the rewritten stream and the
+// number of objects it changed, or a nil stream when nothing changed.
+func Fix(file string, r io.Reader) ([]byte, int, error) {
+	documents, objects, err := decode(file, r)
+	if err != nil {
+		return nil, 0, err
+	}
+	fixed := 0
+	for _, object := range objects {
+		changed, err := fixObject(object)
+		if err != nil {
+			return nil, 0, fmt.Errorf("%s:%d: %w", object.File, object.Line, err)
+		}
+		if changed {
+			fixed++
+		}
+	}
+	if fixed == 0 {
+		return nil, 0, nil
+	}
+
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	for _, document := range documents {
+		if err := encoder.Encode(document); err != nil {
+			return nil, 0, fmt.Errorf("%s: %w", file, err)
+		}
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, 0, fmt.Errorf("%s: %w", file, err)
+	}
+	return out.Bytes(), fixed, nil
+}
+
+// fixObject applies the difference between the pod spec of a workload and its mutated copy to the
+// node of the object. Objects without a pod template are left alone.
+func fixObject(object *Object) (bool, error) {
+	if !workload.Supported(object.Kind) {
+		return false, nil
+	}
+	template, err := workload.FromObject(object.Kind, object.JSON)
+	if err != nil {
+		return false, err
+	}
+	original, err := json.Marshal(template.Pod.Spec)
+	if err != nil {
+		return false, err
+	}
+	pod := template.Pod.DeepCopy()
+	admission.ApplyBaselineSecurity(pod)
+	mutated, err := json.Marshal(pod.Spec)
+	if err != nil {
+		return false, err
+	}
+	ops, err := jsonpatch.Diff(original, mutated)
+	if err != nil {
+		return false, err
+	}
+
+	specPointer := checks.FieldPointer(template.SpecPath)
+	for _, op := range ops {
+		op.Path = specPointer + op.Path
+		if err := applyOperation(object.node, op); err != nil {
+			return false, err
+		}
+	}
+	return len(ops) > 0, nil
+}
@@ -0,0 +1,134 @@
+package scan
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/jsonpatch"
+	"gopkg.in/yaml.v3"
+)
+
+var pointerUnescaper = strings.NewReplacer("~1", "/", "~0", "~")
+
+// pointerTokens splits a JSON pointer into its unescaped reference tokens
+func pointerTokens(pointer string) []string {
+	if pointer == "" {
+		return nil
+	}
+	tokens := strings.Split(strings.TrimPrefix(pointer, "/"), "/")
+	for i, token := range tokens {
+		tokens[i] = pointerUnescaper.Replace(token)
+	}
+	return tokens
+}
+
+// walk follows tokens from node as far as they resolve. It returns the last node reached, the node
+// that marks it in the source (the key of a mapping entry, the item of a sequence) and how many
+// tokens were resolved.
+func walk(node *yaml.Node, tokens []string) (value, mark *yaml.Node, resolved int) {
+	value, mark = node, node
+	for _, token := range tokens {
+		switch value.Kind {
+		case yaml.MappingNode:
+			i := mappingIndex(value, token)
+			if i < 0 {
+				return value, mark, resolved
+			}
+			value, mark = value.Content[i+1], value.Content[i]
+		case yaml.SequenceNode:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(value.Content) {
+				return value, mark, resolved
+			}
+			value, mark = value.Content[i], value.Content[i]
+		default:
+			return value, mark, resolved
+		}
+		resolved++
+	}
+	return value, mark, resolved
+}
+
+// mappingIndex returns the index of the key node of key in a mapping node, or -1
+func mappingIndex(node *yaml.Node, key string) int {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// mappingValue returns the value of key in a mapping node, or nil
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if i := mappingIndex(node, key); i >= 0 {
+		return node.Content[i+1]
+	}
+	return nil
+}
+
+// applyOperation applies a JSON patch operation to a YAML node in place. Nodes the operation does not
+// touch keep their position, style and comments.
+func applyOperation(root *yaml.Node, op jsonpatch.Operation) error {
+	tokens := pointerTokens(op.Path)
+	if len(tokens) == 0 {
+		return fmt.Errorf("%s %q: cannot patch the whole object", op.Op, op.Path)
+	}
+	parent, _, resolved := walk(root, tokens[:len(tokens)-1])
+	if resolved != len(tokens)-1 {
+		return fmt.Errorf("%s %q: parent does not exist", op.Op, op.Path)
+	}
+	token := tokens[len(tokens)-1]
+
+	var value *yaml.Node
+	if op.Op != jsonpatch.OpRemove {
+		value = &yaml.Node{}
+		if err := value.Encode(op.Value); err != nil {
+			return fmt.Errorf("%s %q: %w", op.Op, op.Path, err)
+		}
+	}
+
+	switch parent.Kind {
+	case yaml.MappingNode:
+		i := mappingIndex(parent, token)
+		switch {
+		case op.Op == jsonpatch.OpRemove && i >= 0:
+			parent.Content = append(parent.Content[:i], parent.Content[i+2:]...)
+		case op.Op == jsonpatch.OpRemove || (op.Op == jsonpatch.OpReplace && i < 0):
+			return fmt.Errorf("%s %q: no such key", op.Op, op.Path)
+		case i >= 0:
+			// A key that is present but null, e.g. "securityContext:", is filled in where it is
+			value.LineComment = parent.Content[i+1].LineComment
+			parent.Content[i+1] = value
+		default:
+			if len(parent.Content) == 0 {
+				parent.Style &^= yaml.FlowStyle
+			}
+			key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: token}
+			parent.Content = append(parent.Content, key, value)
+		}
+	case yaml.SequenceNode:
+		i := len(parent.Content)
+		if token != "-" {
+			var err error
+			if i, err = strconv.Atoi(token); err != nil {
+				return fmt.Errorf("%s %q: %w", op.Op, op.Path, err)
+			}
+		}
+		if i < 0 || i > len(parent.Content) || (op.Op != jsonpatch.OpAdd && i == len(parent.Content)) {
+			return fmt.Errorf("%s %q: index out of range", op.Op, op.Path)
+		}
+		switch op.Op {
+		case jsonpatch.OpAdd:
+			parent.Content = append(parent.Content[:i], append([]*yaml.Node{value}, parent.Content[i:]...)...)
+		case jsonpatch.OpReplace:
+			parent.Content[i] = value
+		case jsonpatch.OpRemove:
+			parent.Content = append(parent.Content[:i], parent.Content[i+1:]...)
+		}
+	default:
+		return fmt.Errorf("%s %q: parent is not a mapping or sequence", op.Op, op.Path)
+	}
+	return nil
+}
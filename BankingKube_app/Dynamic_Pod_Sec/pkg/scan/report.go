@@ -0,0 +1,165 @@
+package scan
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/offline"
+)
+
+// Writers maps the output formats to the functions that write them
+var Writers = map[string]func(io.Writer, []*Result) error{
+	"text":  WriteText,
+	"json":  WriteJSON,
+	"junit": WriteJUnit,
+	"sarif": WriteSARIF,
+}
+
+// WriteText lists the findings of every object that has any, followed by a summary line
+func WriteText(w io.Writer, results []*Result) error {
+	warned := 0
+	for _, r := range results {
+		if len(r.Warnings) > 0 {
+			warned++
+		}
+		if r.Allowed && len(r.Warnings) == 0 {
+			continue
+		}
+		verdict := "allowed with warnings"
+		if !r.Allowed {
+			verdict = "denied"
+		}
+		fmt.Fprintf(w, "%s:%d: %s %s: %s\n", r.File, r.Line, r.Kind, r.objectName(), verdict)
+		for _, v := range r.Violations {
+			fmt.Fprintf(w, "  DENY  %s\n", findingText(v))
+		}
+		for _, v := range r.Warnings {
+			fmt.Fprintf(w, "  WARN  %s\n", findingText(v))
+		}
+	}
+	_, err := fmt.Fprintf(w, "%d objects scanned: %d denied, %d with warnings\n", len(results), Denied(results), warned)
+	return err
+}
+
+// jsonReport is the document WriteJSON writes
+type jsonReport struct {
+	Objects []*Result `json:"objects"`
+	Summary struct {
+		Objects int `json:"objects"`
+		Denied  int `json:"denied"`
+	} `json:"summary"`
+}
+
+// WriteJSON writes every result and a summary as a single JSON document
+func WriteJSON(w io.Writer, results []*Result) error {
+	report := jsonReport{Objects: results}
+	if report.Objects == nil {
+		report.Objects = []*Result{}
+	}
+	report.Summary.Objects = len(results)
+	report.Summary.Denied = Denied(results)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit writes a test suite per file with a test case per object; a denied object is a failure
+// and warnings go to its system-out, so CI systems show the findings next to the build
+func WriteJUnit(w io.Writer, results []*Result) error {
+	suites := junitSuites{Tests: len(results), Failures: Denied(results)}
+	index := make(map[string]int)
+	for _, r := range results {
+		i, ok := index[r.File]
+		if !ok {
+			i = len(suites.Suites)
+			index[r.File] = i
+			suites.Suites = append(suites.Suites, junitSuite{Name: r.File})
+		}
+		suite := &suites.Suites[i]
+
+		testCase := junitCase{
+			Name:      fmt.Sprintf("%s %s", r.Kind, r.objectName()),
+			Classname: r.File,
+			SystemOut: findingLines(r.Warnings),
+		}
+		if !r.Allowed {
+			suite.Failures++
+			testCase.Failure = &junitFailure{
+				Message: fmt.Sprintf("%d policy violations found (line %d)", len(r.Violations), r.Line),
+				Type:    "PolicyViolation",
+				Text:    findingLines(r.Violations),
+			}
+		}
+		suite.Tests++
+		suite.Cases = append(suite.Cases, testCase)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(suites); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// objectName returns namespace/name, or the name of a cluster-scoped object
+func (r *Result) objectName() string {
+	name := r.Name
+	if name == "" {
+		name = "<unnamed>"
+	}
+	if r.Namespace == "" {
+		return name
+	}
+	return r.Namespace + "/" + name
+}
+
+func findingText(f offline.Finding) string {
+	text := fmt.Sprintf("[%s] %s", f.Check, f.Message)
+	if f.Field != "" {
+		text += " (field: " + f.Field + ")"
+	}
+	return text
+}
+
+func findingLines(findings []offline.Finding) string {
+	lines := make([]string, 0, len(findings))
+	for _, f := range findings {
+		lines = append(lines, findingText(f))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,135 @@
+package scan
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/admission/checks"
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/offline"
+)
+
+// SARIF 2.1.0 schema and the name the scanner reports as its tool
+const (
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifVersion = "2.1.0"
+	sarifTool    = "bankingkube-scan"
+)
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifToolInfo `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifToolInfo struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name  string      `json:"name"`
+	Rules []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	RuleIndex int             `json:"ruleIndex"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+}
+
+// WriteSARIF writes a SARIF 2.1.0 log for code scanning tools. Each finding is a result of the rule
+// named after its check, located at the line and column of the field it is about; denials are errors
+// and warnings are warnings.
+func WriteSARIF(w io.Writer, results []*Result) error {
+	run := sarifRun{
+		Tool:    sarifToolInfo{Driver: sarifDriver{Name: sarifTool, Rules: []sarifRule{}}},
+		Results: []sarifResult{},
+	}
+	rules := make(map[string]int)
+	add := func(r *Result, f offline.Finding, level string) {
+		id := f.Check
+		if id == "" {
+			id = "webhook"
+		}
+		index, ok := rules[id]
+		if !ok {
+			index = len(run.Tool.Driver.Rules)
+			rules[id] = index
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{
+				ID:               id,
+				ShortDescription: sarifMessage{Text: "BankingKube check " + strings.ReplaceAll(id, "_", " ")},
+			})
+		}
+		line, column := r.Position(f.Field)
+		run.Results = append(run.Results, sarifResult{
+			RuleID:    id,
+			RuleIndex: index,
+			Level:     level,
+			Message:   sarifMessage{Text: fmt.Sprintf("%s %s: %s", r.Kind, r.objectName(), f.Message)},
+			Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: filepath.ToSlash(r.File)},
+				Region:           sarifRegion{StartLine: line, StartColumn: column},
+			}}},
+		})
+	}
+	for _, r := range results {
+		for _, v := range r.Violations {
+			add(r, v, "error")
+		}
+		for _, v := range r.Warnings {
+			add(r, v, "warning")
+		}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(sarifLog{Schema: sarifSchema, Version: sarifVersion, Runs: []sarifRun{run}})
+}
+
+// Position returns the line and column of a field of the object, such as
+// spec.containers[0].securityContext. A field missing from the manifest is located at its closest
+// parent that is present, so a finding about a value the manifest leaves out points at where it
+// belongs; fields that are not in the object at all point at the object.
+func (r *Result) Position(field string) (line, column int) {
+	if r.node == nil {
+		return r.Line, 1
+	}
+	_, mark, _ := walk(r.node, pointerTokens(checks.FieldPointer(field)))
+	return mark.Line, mark.Column
+}
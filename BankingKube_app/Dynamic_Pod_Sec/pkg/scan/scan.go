@@ -0,0 +1,159 @@
+// Package scan checks Kubernetes manifests against a policy file before they reach a cluster. Every
+// object is wrapped in the CREATE request the API server would send for it and evaluated with
+// pkg/offline, so a manifest the scanner passes is one the webhook admits.
+package scan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/offline"
+	"gopkg.in/yaml.v3"
+
+	sigsyaml "sigs.k8s.io/yaml"
+)
+
+// Source locates an object in the scanned input
+type Source struct {
+	File     string `json:"file"`     // "-" for stdin
+	Document int    `json:"document"` // 1-based index of the YAML document in the file
+	Line     int    `json:"line"`     // Line the object starts on
+}
+
+// Object is a single manifest of the input
+type Object struct {
+	Source
+	Kind string
+	Name string
+	JSON []byte
+
+	node *yaml.Node // Mapping node of the object, to locate fields and apply fixes
+}
+
+// Result is the outcome of one object
+type Result struct {
+	Source
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	offline.Outcome
+
+	node *yaml.Node
+}
+
+// Read splits a stream of YAML or JSON documents into objects. Empty documents are skipped and the
+// items of a List (e.g. kubectl get -o yaml) are objects of their own.
+func Read(file string, r io.Reader) ([]*Object, error) {
+	_, objects, err := decode(file, r)
+	return objects, err
+}
+
+// decode returns the non-empty documents of a stream and the objects they hold
+func decode(file string, r io.Reader) ([]*yaml.Node, []*Object, error) {
+	decoder := yaml.NewDecoder(r)
+	var documents []*yaml.Node
+	var objects []*Object
+	for document := 1; ; document++ {
+		node := &yaml.Node{}
+		err := decoder.Decode(node)
+		if errors.Is(err, io.EOF) {
+			return documents, objects, nil
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: document %d: %w", file, document, err)
+		}
+		if len(node.Content) == 0 || node.Content[0].Tag == "!!null" {
+			continue
+		}
+
+		found, err := read(Source{File: file, Document: document}, node.Content[0])
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: document %d: %w", file, document, err)
+		}
+		documents = append(documents, node)
+		objects = append(objects, found...)
+	}
+}
+
+// read converts a document into its object, or into the objects of its items when it is a List
+func read(source Source, node *yaml.Node) ([]*Object, error) {
+	if node.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("line %d: not a Kubernetes object", node.Line)
+	}
+	source.Line = node.Line
+
+	var meta struct {
+		Kind     string `yaml:"kind"`
+		Metadata struct {
+			Name string `yaml:"name"`
+		} `yaml:"metadata"`
+	}
+	if err := node.Decode(&meta); err != nil {
+		return nil, fmt.Errorf("line %d: %w", node.Line, err)
+	}
+	if meta.Kind == "" {
+		return nil, fmt.Errorf("line %d: object has no kind", node.Line)
+	}
+
+	if strings.HasSuffix(meta.Kind, "List") {
+		if items := mappingValue(node, "items"); items != nil && items.Kind == yaml.SequenceNode {
+			var objects []*Object
+			for _, item := range items.Content {
+				found, err := read(source, item)
+				if err != nil {
+					return nil, err
+				}
+				objects = append(objects, found...)
+			}
+			return objects, nil
+		}
+	}
+
+	encoded, err := yaml.Marshal(node)
+	if err != nil {
+		return nil, err
+	}
+	object, err := sigsyaml.YAMLToJSON(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("line %d: %w", node.Line, err)
+	}
+	return []*Object{{Source: source, Kind: meta.Kind, Name: meta.Metadata.Name, JSON: object, node: node}}, nil
+}
+
+// Scan evaluates every object. namespace applies to objects that do not set one, like kubectl apply -n.
+func Scan(ctx context.Context, objects []*Object, namespace string, options offline.Options) ([]*Result, error) {
+	results := make([]*Result, 0, len(objects))
+	for _, object := range objects {
+		request, err := offline.ManifestRequest(object.JSON, namespace, "")
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", object.File, object.Line, err)
+		}
+		outcome, err := offline.Review(ctx, request, options)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", object.File, object.Line, err)
+		}
+		results = append(results, &Result{
+			Source:    object.Source,
+			Kind:      object.Kind,
+			Namespace: request.Namespace,
+			Name:      object.Name,
+			Outcome:   *outcome,
+			node:      object.node,
+		})
+	}
+	return results, nil
+}
+
+// Denied returns how many objects the webhook would deny
+func Denied(results []*Result) int {
+	denied := 0
+	for _, r := range results {
+		if !r.Allowed {
+			denied++
+		}
+	}
+	return denied
+}
@@ -0,0 +1,252 @@
+package scan
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/offline"
+	"github.com/Droshow/EKS-BankingKube/BankingKube_app/Dynamic_Pod_Sec/pkg/policy"
+)
+
+const manifests = `# Source: chart/templates/pod.yaml
+apiVersion: v1
+kind: Pod
+metadata:
+  name: web
+spec:
+  containers:
+    - name: app
+      image: nginx
+      securityContext:
+        allowPrivilegeEscalation: true
+---
+---
+{"apiVersion": "v1", "kind": "Pod", "metadata": {"name": "api", "namespace": "payments"},
+ "spec": {"containers": [{"name": "app", "image": "nginx", "securityContext": {"allowPrivilegeEscalation": false}}]}}
+---
+apiVersion: v1
+kind: List
+items:
+  - apiVersion: v1
+    kind: ConfigMap
+    metadata:
+      name: settings
+`
+
+func TestRead(t *testing.T) {
+	objects, err := Read("-", strings.NewReader(manifests))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		name string
+		Source
+	}{
+		{"web", Source{File: "-", Document: 1, Line: 2}},
+		{"api", Source{File: "-", Document: 3, Line: 14}},
+		{"settings", Source{File: "-", Document: 4, Line: 20}},
+	}
+	if len(objects) != len(want) {
+		t.Fatalf("got %d objects, want %d", len(objects), len(want))
+	}
+	for i, w := range want {
+		if objects[i].Name != w.name || objects[i].Source != w.Source {
+			t.Errorf("object %d = %s at %+v, want %s at %+v", i, objects[i].Name, objects[i].Source, w.name, w.Source)
+		}
+	}
+
+	if _, err := Read("bad.yaml", strings.NewReader("apiVersion: v1\nmetadata: {}\n")); err == nil || !strings.Contains(err.Error(), "bad.yaml: document 1: line 1: object has no kind") {
+		t.Errorf("Read() = %v, want the missing kind reported", err)
+	}
+}
+
+func TestScan(t *testing.T) {
+	objects, err := Read("manifests.yaml", strings.NewReader(manifests))
+	if err != nil {
+		t.Fatal(err)
+	}
+	options := offline.Options{
+		Policies: &policy.SecurityPolicies{PodSecurityContext: policy.PodSecurityContext{AllowPrivilegeEscalation: false}},
+		Groups:   []string{"context"},
+	}
+	results, err := Scan(context.Background(), objects, "team-a", options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if Denied(results) != 1 || results[0].Allowed || results[0].Namespace != "team-a" || !results[1].Allowed || results[1].Namespace != "payments" {
+		t.Fatalf("results = %+v, want only web in team-a denied", results)
+	}
+
+	var out bytes.Buffer
+	if err := WriteText(&out, results); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"manifests.yaml:2: Pod team-a/web: denied", "DENY  [pod_security_context]", "3 objects scanned: 1 denied"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("text output does not contain %q:\n%s", want, out.String())
+		}
+	}
+
+	out.Reset()
+	if err := WriteJSON(&out, results); err != nil {
+		t.Fatal(err)
+	}
+	var report struct {
+		Objects []struct {
+			Name       string `json:"name"`
+			Line       int    `json:"line"`
+			Allowed    bool   `json:"allowed"`
+			Violations []struct {
+				Check string `json:"check"`
+				Field string `json:"field"`
+			} `json:"violations"`
+		} `json:"objects"`
+		Summary struct{ Denied int } `json:"summary"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	web := report.Objects[0]
+	if report.Summary.Denied != 1 || web.Allowed || web.Line != 2 || len(web.Violations) != 1 || web.Violations[0].Field != "spec.containers[0].securityContext.allowPrivilegeEscalation" {
+		t.Errorf("JSON report = %s", out.String())
+	}
+
+	out.Reset()
+	if err := WriteJUnit(&out, results); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`<testsuites tests="3" failures="1">`, `<testcase name="Pod team-a/web" classname="manifests.yaml">`, `type="PolicyViolation"`} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("JUnit output does not contain %q:\n%s", want, out.String())
+		}
+	}
+}
+
+const deployment = `# Source: chart/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web # the frontend
+spec:
+  template:
+    spec:
+      containers:
+        - name: app
+          image: nginx:latest # pinned by the release pipeline
+          securityContext:
+            runAsUser: 1000
+            readOnlyRootFilesystem: false
+        - name: sidecar
+          image: envoy:1.0
+          securityContext: {}
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+`
+
+func TestWriteSARIF(t *testing.T) {
+	objects, err := Read("deploy/web.yaml", strings.NewReader(deployment))
+	if err != nil {
+		t.Fatal(err)
+	}
+	options := offline.Options{
+		Policies: &policy.SecurityPolicies{PodSecurityContext: policy.PodSecurityContext{AllowPrivilegeEscalation: true, ReadOnlyRootFilesystem: true}},
+		Groups:   []string{"context"},
+	}
+	results, err := Scan(context.Background(), objects, "", options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for field, want := range map[string][2]int{
+		"spec.template.spec.containers[0].image":                                  {11, 11},
+		"spec.template.spec.containers[0].securityContext.readOnlyRootFilesystem": {14, 13},
+		"spec.template.spec.containers[0].securityContext.runAsNonRoot":           {12, 11},
+		"spec.template.spec.containers[1]":                                        {15, 11},
+		"consistencyPolicy":                                                       {2, 1},
+	} {
+		if line, column := results[0].Position(field); line != want[0] || column != want[1] {
+			t.Errorf("Position(%s) = %d:%d, want %d:%d", field, line, column, want[0], want[1])
+		}
+	}
+
+	var out bytes.Buffer
+	if err := WriteSARIF(&out, results); err != nil {
+		t.Fatal(err)
+	}
+	var log struct {
+		Version string `json:"version"`
+		Runs    []struct {
+			Results []struct {
+				RuleID    string `json:"ruleId"`
+				Level     string `json:"level"`
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct{ URI string } `json:"artifactLocation"`
+						Region           struct{ StartLine, StartColumn int }
+					} `json:"physicalLocation"`
+				} `json:"locations"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &log); err != nil {
+		t.Fatal(err)
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 || len(log.Runs[0].Results) != 2 {
+		t.Fatalf("SARIF log = %s", out.String())
+	}
+	// readOnlyRootFilesystem is false on app, and unset on sidecar, whose empty securityContext is reported
+	for i, want := range [][2]int{{14, 13}, {17, 11}} {
+		result := log.Runs[0].Results[i]
+		location := result.Locations[0].PhysicalLocation
+		if result.RuleID != "pod_security_context" || result.Level != "error" || location.ArtifactLocation.URI != "deploy/web.yaml" || location.Region.StartLine != want[0] || location.Region.StartColumn != want[1] {
+			t.Errorf("unexpected result %+v", result)
+		}
+	}
+}
+
+func TestFix(t *testing.T) {
+	fixed, objects, err := Fix("web.yaml", strings.NewReader(deployment))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if objects != 1 {
+		t.Fatalf("fixed %d objects, want 1", objects)
+	}
+	for _, want := range []string{
+		"# Source: chart/templates/deployment.yaml\n",
+		"name: web # the frontend\n",
+		"image: nginx:latest # pinned by the release pipeline\n          securityContext:\n            runAsUser: 1000\n            readOnlyRootFilesystem: true\n            allowPrivilegeEscalation: false\n",
+		"kind: ConfigMap\n",
+	} {
+		if !strings.Contains(string(fixed), want) {
+			t.Errorf("fixed manifest does not contain %q:\n%s", want, fixed)
+		}
+	}
+
+	again, objects, err := Fix("web.yaml", bytes.NewReader(fixed))
+	if err != nil || again != nil || objects != 0 {
+		t.Errorf("fixing a fixed manifest changed %d objects (err %v):\n%s", objects, err, again)
+	}
+
+	parsed, err := Read("web.yaml", bytes.NewReader(fixed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	options := offline.Options{
+		Policies: &policy.SecurityPolicies{PodSecurityContext: policy.PodSecurityContext{RunAsNonRoot: true, ReadOnlyRootFilesystem: true}},
+		Groups:   []string{"context"},
+	}
+	results, err := Scan(context.Background(), parsed, "", options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if Denied(results) != 0 {
+		t.Errorf("fixed manifest is still denied: %+v", results[0].Violations)
+	}
+}